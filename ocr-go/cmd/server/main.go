@@ -2,11 +2,16 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"path"
+	"runtime"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
@@ -14,29 +19,125 @@ import (
 	chimiddleware "github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
 	"github.com/username/ocr-go/internal/handler"
+	"github.com/username/ocr-go/internal/jobstore"
 	"github.com/username/ocr-go/internal/middleware"
+	"github.com/username/ocr-go/internal/model"
 	"github.com/username/ocr-go/internal/ocr"
+	"github.com/username/ocr-go/internal/storage"
 )
 
 func main() {
 	// Ensure output directories exist
-	os.MkdirAll("outputs", 0755)
-	os.MkdirAll("uploads", 0755)
+	outputDir := getEnv("OUTPUT_DIR", "outputs")
+	uploadDir := getEnv("UPLOAD_DIR", "uploads")
+	os.MkdirAll(outputDir, 0755)
+	os.MkdirAll(uploadDir, 0755)
 
 	// Get language from environment
 	lang := getEnv("TESSERACT_LANG", "spa")
 
 	// Initialize OCR engine
-	engine, err := ocr.NewTesseractEngine(lang)
+	engine, err := newEngine(lang)
 	if err != nil {
 		log.Fatalf("Failed to initialize OCR engine: %v", err)
 	}
+	engine = newCachedEngine(engine)
 	defer engine.Close()
 
-	log.Printf("OCR engine initialized with language: %s", lang)
+	log.Printf("OCR engine initialized with language: %s (version %s)", lang, engine.Version())
+
+	// Initialize result and upload storage
+	store, err := newStorage(outputDir, "")
+	if err != nil {
+		log.Fatalf("Failed to initialize storage: %v", err)
+	}
+	uploads, err := newStorage(uploadDir, "uploads")
+	if err != nil {
+		log.Fatalf("Failed to initialize upload storage: %v", err)
+	}
+
+	// Server settings
+	port := getEnv("PORT", "8080")
+	requestTimeoutLimit := getEnvDuration("REQUEST_TIMEOUT", 60*time.Second)
+	bulkTimeoutLimit := getEnvDuration("BULK_REQUEST_TIMEOUT", 0)
+	shutdownTimeout := getEnvDuration("SHUTDOWN_TIMEOUT", 30*time.Second)
+	slowRequestThreshold := getEnvDuration("SLOW_REQUEST_THRESHOLD", 10*time.Second)
+	jobRetention := getEnvDuration("JOB_RETENTION", time.Hour)
+	idempotencyTTL := getEnvDuration("IDEMPOTENCY_TTL", 24*time.Hour)
+	idempotencyMaxBytes := int64(getEnvInt("IDEMPOTENCY_MAX_BYTES", 64<<20))
+	prettyResults := getEnv("PRETTY_RESULTS", "false") == "true"
+
+	// Directory whose image files are OCR'd automatically (disabled when
+	// WATCH_DIR is unset)
+	watchDir := os.Getenv("WATCH_DIR")
+
+	// Rate limiting (disabled when RATE_LIMIT_RPS is unset or zero)
+	rateLimitRPS := getEnvFloat("RATE_LIMIT_RPS", 0)
+	rateLimitBurst := getEnvInt("RATE_LIMIT_BURST", 5)
+
+	// API key authentication (disabled when API_KEYS is unset)
+	apiKeys := getEnvList("API_KEYS")
+	if len(apiKeys) == 0 {
+		log.Println("API_KEYS not set, API authentication disabled")
+	}
+
+	// Admin endpoints are only served when ADMIN_API_KEYS is set
+	adminKeys := getEnvList("ADMIN_API_KEYS")
+
+	// CORS origins. Credentials are only allowed for an explicit origin
+	// list, browsers reject them with a wildcard origin.
+	corsOrigins := getEnvList("CORS_ORIGINS")
+	allowCredentials := len(corsOrigins) > 0
+	if !allowCredentials {
+		corsOrigins = []string{"*"}
+	}
 
 	// Initialize handler
-	h := handler.New(engine)
+	h := handler.New(engine,
+		handler.WithJobStore(jobstore.NewMemoryStore(jobRetention)),
+		handler.WithStorage(store),
+		handler.WithOutputDir(outputDir),
+		handler.WithUploadDir(uploadDir),
+		handler.WithUploadStorage(uploads),
+		handler.WithBatchConcurrency(getEnvInt("BATCH_CONCURRENCY", runtime.NumCPU())),
+		handler.WithMaxConcurrentOCR(getEnvInt("MAX_CONCURRENT_OCR", runtime.NumCPU())),
+		handler.WithOCRQueue(getEnvInt("OCR_QUEUE_SIZE", 64), getEnvDuration("OCR_QUEUE_WAIT", 5*time.Second)),
+		handler.WithMaxBatchFiles(getEnvInt("MAX_BATCH_FILES", 100)),
+		handler.WithMaxUploadSize(int64(getEnvInt("MAX_UPLOAD_SIZE", 10<<20))),
+		handler.WithMaxImagePixels(int64(getEnvInt("MAX_IMAGE_PIXELS", 50_000_000))),
+		handler.WithThumbnailSize(getEnvInt("THUMBNAIL_SIZE", 256)),
+		handler.WithStreamMaxFPS(getEnvFloat("STREAM_MAX_FPS", 5)),
+		handler.WithIdempotencyTTL(idempotencyTTL),
+		handler.WithIdempotencyMaxBytes(idempotencyMaxBytes),
+		handler.WithBatchFileTimeout(getEnvDuration("BATCH_FILE_TIMEOUT", 30*time.Second)),
+		handler.WithAutoLanguages(getEnvList("OCR_AUTO_LANGUAGES")),
+		handler.WithDictionaryDir(getEnv("DICTIONARY_DIR", "dictionaries")),
+		handler.WithTessdataDir(os.Getenv("TESSDATA_PREFIX")),
+		handler.WithPrettyResults(prettyResults),
+		handler.WithServerConfig(model.ServerConfig{
+			Port:                 port,
+			OCREngine:            getEnv("OCR_ENGINE", "tesseract"),
+			TessdataPrefix:       os.Getenv("TESSDATA_PREFIX"),
+			FallbackEngine:       os.Getenv("OCR_FALLBACK_ENGINE"),
+			CacheSize:            getEnvInt("OCR_CACHE_SIZE", 64),
+			LanguageEngines:      getEnvInt("OCR_LANGUAGE_ENGINES", 4),
+			StorageBackend:       getEnv("STORAGE_BACKEND", "local"),
+			RequestTimeout:       requestTimeoutLimit.String(),
+			BulkRequestTimeout:   bulkTimeoutLimit.String(),
+			ShutdownTimeout:      shutdownTimeout.String(),
+			SlowRequestThreshold: slowRequestThreshold.String(),
+			JobRetention:         jobRetention.String(),
+			IdempotencyTTL:       idempotencyTTL.String(),
+			IdempotencyMaxBytes:  idempotencyMaxBytes,
+			RateLimitRPS:         rateLimitRPS,
+			RateLimitBurst:       rateLimitBurst,
+			AuthEnabled:          len(apiKeys) > 0,
+			AdminEnabled:         len(adminKeys) > 0,
+			PrettyResults:        prettyResults,
+			CORSOrigins:          corsOrigins,
+			WatchDir:             watchDir,
+		}),
+	)
 
 	// Setup router
 	r := chi.NewRouter()
@@ -44,16 +145,19 @@ func main() {
 	// Middleware stack
 	r.Use(chimiddleware.RequestID)
 	r.Use(chimiddleware.RealIP)
-	r.Use(middleware.Logger)
-	r.Use(chimiddleware.Recoverer)
-	r.Use(chimiddleware.Timeout(60 * time.Second))
+	inflight := middleware.NewInFlight()
+	r.Use(inflight.Middleware)
+	r.Use(middleware.Logger(slowRequestThreshold))
+	r.Use(middleware.Recoverer)
+	r.Use(middleware.Compress())
+	r.Use(middleware.PrettyJSON)
 
 	// CORS configuration
 	r.Use(cors.Handler(cors.Options{
-		AllowedOrigins:   []string{"*"},
+		AllowedOrigins:   corsOrigins,
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type"},
-		AllowCredentials: false,
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-API-Key", "Idempotency-Key", "X-Tenant-Id"},
+		AllowCredentials: allowCredentials,
 		MaxAge:           300,
 	}))
 
@@ -61,26 +165,86 @@ func main() {
 	r.Handle("/static/*", http.StripPrefix("/static/",
 		http.FileServer(http.Dir("web/static"))))
 
+	// Interactive routes are bounded by REQUEST_TIMEOUT so they stay
+	// snappy; bulk routes get BULK_REQUEST_TIMEOUT, unbounded by default,
+	// and rely on BATCH_FILE_TIMEOUT per file instead
+	requestTimeout := routeTimeout(requestTimeoutLimit)
+	bulkTimeout := routeTimeout(bulkTimeoutLimit)
+
 	// Routes
-	r.Get("/", h.Index)
-	r.Get("/health", h.Health)
+	r.Group(func(r chi.Router) {
+		r.Use(requestTimeout)
+
+		r.Get("/", h.Index)
+		r.Get("/health", h.Health)
+		r.Get("/healthz", h.Liveness)
+		r.Get("/readyz", h.Readiness)
+		r.Get("/metrics", h.Metrics)
+	})
 
 	// API routes
 	r.Route("/api", func(r chi.Router) {
-		r.Post("/extract", h.ExtractText)
-		r.Post("/visualize", h.VisualizeBoxes)
-		r.Post("/batch", h.BatchProcess)
-		r.Get("/results", h.ListResults)
-		r.Get("/results/{filename}", h.GetResult)
+		if rateLimitRPS > 0 {
+			r.Use(middleware.RateLimit(rateLimitRPS, rateLimitBurst))
+		}
+		if len(apiKeys) > 0 {
+			r.Use(middleware.APIKeyAuth(apiKeys))
+		}
+
+		r.Group(func(r chi.Router) {
+			r.Use(bulkTimeout)
+
+			r.Post("/batch", h.BatchProcess)
+		})
+
+		// Live streams stay open for as long as the client keeps sending
+		// frames, and job events until the job finishes, so they are not
+		// bounded by a request timeout
+		r.Get("/stream", h.LiveOCR)
+		r.Get("/jobs/{id}/events", h.JobEvents)
+
+		r.Group(func(r chi.Router) {
+			r.Use(requestTimeout)
+
+			r.Post("/extract", h.ExtractText)
+			r.Put("/extract", h.ExtractText)
+			r.Post("/extract-url", h.ExtractFromURL)
+			r.Post("/reprocess/{id}", h.Reprocess)
+			r.Post("/visualize", h.VisualizeBoxes)
+			r.Post("/crops", h.ExportCrops)
+			r.Post("/convert", h.ConvertImage)
+			r.Get("/jobs/{id}", h.GetJob)
+			r.Post("/search", h.SearchText)
+			r.Post("/table", h.ExtractTable)
+			r.Post("/key-values", h.ExtractKeyValues)
+			r.Post("/diff", h.Diff)
+			r.Post("/evaluate", h.Evaluate)
+			r.Get("/version", h.Version)
+			r.Get("/config", h.Config)
+			r.Get("/stats", h.Stats)
+			r.Get("/openapi.json", h.OpenAPI)
+			r.Get("/results", h.ListResults)
+			r.Get("/results/usage", h.ResultsUsage)
+			r.Get("/results/{filename}", h.GetResult)
+		})
 	})
 
+	// Admin routes require an admin key, independently of API_KEYS
+	if len(adminKeys) > 0 {
+		r.Route("/admin", func(r chi.Router) {
+			r.Use(middleware.APIKeyAuth(adminKeys))
+			r.Use(bulkTimeout)
+
+			r.Post("/languages", h.UploadLanguage)
+		})
+	}
+
 	// Server configuration
-	port := getEnv("PORT", "8080")
 	srv := &http.Server{
 		Addr:         fmt.Sprintf(":%s", port),
 		Handler:      r,
 		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 60 * time.Second,
+		WriteTimeout: writeTimeout(requestTimeoutLimit),
 		IdleTimeout:  60 * time.Second,
 	}
 
@@ -92,23 +256,193 @@ func main() {
 		}
 	}()
 
+	// Warm up the engine so /readyz only succeeds once OCR works
+	go func() {
+		for {
+			err := h.Warmup(context.Background())
+			if err == nil {
+				log.Println("OCR engine ready")
+				return
+			}
+			log.Printf("OCR engine warm-up failed: %v", err)
+			time.Sleep(5 * time.Second)
+		}
+	}()
+
+	// OCR files dropped into WATCH_DIR alongside the HTTP API
+	watchCtx, stopWatching := context.WithCancel(context.Background())
+	defer stopWatching()
+	if watchDir != "" {
+		go func() {
+			log.Printf("Watching %s for images", watchDir)
+			if err := h.WatchDir(watchCtx, watchDir); err != nil {
+				log.Printf("Watching %s failed: %v", watchDir, err)
+			}
+		}()
+	}
+
 	// Wait for interrupt signal
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
-	log.Println("Server shutting down...")
+	log.Printf("Server shutting down with %d requests in flight...", inflight.Count())
+	stopWatching()
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
 	defer cancel()
 
+	shutdownStart := time.Now()
 	if err := srv.Shutdown(ctx); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			log.Printf("Drain deadline of %s hit with %d requests still running: %s",
+				shutdownTimeout, inflight.Count(), strings.Join(inflight.Endpoints(), ", "))
+		}
 		log.Fatalf("Server forced to shutdown: %v", err)
 	}
+	log.Printf("Drained in-flight requests in %s", time.Since(shutdownStart))
+
+	// Finish or cancel async batch jobs before the engine is closed
+	jobsCtx, cancelJobs := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancelJobs()
+	if err := h.Shutdown(jobsCtx); err != nil {
+		log.Printf("Canceled async batch jobs still running after %s", shutdownTimeout)
+	}
 
 	log.Println("Server exited")
 }
 
+// routeTimeout returns middleware cancelling the request context after d,
+// or a pass-through when d is zero so long-running routes are unbounded
+func routeTimeout(d time.Duration) func(http.Handler) http.Handler {
+	if d <= 0 {
+		return func(next http.Handler) http.Handler { return next }
+	}
+	return chimiddleware.Timeout(d)
+}
+
+// writeTimeout leaves a margin past the request timeout so the timeout
+// response itself can still be written; zero leaves writes unbounded
+func writeTimeout(requestTimeout time.Duration) time.Duration {
+	if requestTimeout <= 0 {
+		return 0
+	}
+	return requestTimeout + 5*time.Second
+}
+
+// newEngine creates the OCR engine selected by OCR_ENGINE, run once per
+// page segmentation mode in OCR_ENSEMBLE_PSM and wrapped with the
+// OCR_FALLBACK_ENGINE when those are configured
+func newEngine(lang string) (ocr.Engine, error) {
+	primary, err := buildEngine(getEnv("OCR_ENGINE", "tesseract"), lang)
+	if err != nil {
+		return nil, err
+	}
+
+	if modes := getEnvList("OCR_ENSEMBLE_PSM"); len(modes) > 0 {
+		passes := make([]ocr.EnsemblePass, len(modes))
+		for i, mode := range modes {
+			psm, err := strconv.Atoi(mode)
+			if err != nil || psm < 1 || psm > 13 {
+				primary.Close()
+				return nil, fmt.Errorf("invalid OCR_ENSEMBLE_PSM value %q", mode)
+			}
+			passes[i] = ocr.EnsemblePass{Engine: primary, Options: ocr.Options{PageSegMode: psm}}
+		}
+		log.Printf("Merging OCR passes at page segmentation modes %v", modes)
+		primary = ocr.NewEnsembleEngine(passes...)
+	}
+
+	kind := os.Getenv("OCR_FALLBACK_ENGINE")
+	if kind == "" {
+		return primary, nil
+	}
+	secondary, err := buildEngine(kind, lang)
+	if err != nil {
+		primary.Close()
+		return nil, fmt.Errorf("fallback engine: %w", err)
+	}
+
+	threshold := getEnvFloat("OCR_FALLBACK_THRESHOLD", 0.6)
+	log.Printf("Falling back to %s engine below %.2f confidence", kind, threshold)
+	return ocr.NewFallbackEngine(primary, secondary, threshold), nil
+}
+
+// newTesseractEngine creates a Tesseract engine for lang with the retry
+// policy from the environment
+func newTesseractEngine(lang string) (ocr.Engine, error) {
+	engine, err := ocr.NewTesseractEngine(lang, os.Getenv("TESSDATA_PREFIX"))
+	if err != nil {
+		return nil, err
+	}
+	engine.SetRetryPolicy(ocr.RetryPolicy{
+		Attempts: getEnvInt("OCR_RETRY_ATTEMPTS", ocr.DefaultRetryPolicy.Attempts),
+		Backoff:  getEnvDuration("OCR_RETRY_BACKOFF", ocr.DefaultRetryPolicy.Backoff),
+	})
+	return engine, nil
+}
+
+// newCachedEngine wraps engine with an in-memory result cache of
+// OCR_CACHE_SIZE entries; zero disables the cache
+func newCachedEngine(engine ocr.Engine) ocr.Engine {
+	size := getEnvInt("OCR_CACHE_SIZE", 64)
+	if size <= 0 {
+		return engine
+	}
+	return ocr.NewCachedEngine(engine, size)
+}
+
+// buildEngine creates an engine by kind. The "fake" engine returns canned
+// text and is useful for demos without Tesseract.
+func buildEngine(kind, lang string) (ocr.Engine, error) {
+	switch kind {
+	case "tesseract":
+		engine, err := newTesseractEngine(lang)
+		if err != nil {
+			return nil, err
+		}
+		if size := getEnvInt("OCR_LANGUAGE_ENGINES", 4); size > 0 {
+			return ocr.NewLanguageEngine(engine, newTesseractEngine, size), nil
+		}
+		return engine, nil
+	case "fake":
+		log.Println("Using fake OCR engine, results are canned")
+		engine := ocr.NewFakeEngine(
+			ocr.TextBox{Text: "Demo", Confidence: 0.99, Box: ocr.BoundingBox{X: 10, Y: 10, Width: 60, Height: 20}},
+			ocr.TextBox{Text: "text", Confidence: 0.95, Box: ocr.BoundingBox{X: 80, Y: 10, Width: 40, Height: 20}},
+		)
+		engine.Lang = lang
+		return engine, nil
+	default:
+		return nil, fmt.Errorf("unknown OCR engine %q", kind)
+	}
+}
+
+// newStorage creates the storage selected by STORAGE_BACKEND: dir on local
+// storage, or the S3_PREFIX key prefix followed by prefix on S3
+func newStorage(dir, prefix string) (storage.Storage, error) {
+	switch backend := getEnv("STORAGE_BACKEND", "local"); backend {
+	case "local":
+		return storage.NewLocal(dir)
+	case "s3":
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		log.Printf("Using S3 storage bucket %s", os.Getenv("S3_BUCKET"))
+		return storage.NewS3(ctx, storage.S3Config{
+			Endpoint:  getEnv("S3_ENDPOINT", "s3.amazonaws.com"),
+			Region:    os.Getenv("S3_REGION"),
+			Bucket:    os.Getenv("S3_BUCKET"),
+			Prefix:    path.Join(os.Getenv("S3_PREFIX"), prefix),
+			AccessKey: os.Getenv("S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("S3_SECRET_KEY"),
+			UseSSL:    getEnv("S3_USE_SSL", "true") == "true",
+		})
+	default:
+		return nil, fmt.Errorf("unknown STORAGE_BACKEND %q", backend)
+	}
+}
+
 // getEnv returns environment variable value or default
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
@@ -116,3 +450,48 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// getEnvList returns a comma-separated environment variable as a list,
+// skipping empty entries
+func getEnvList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// getEnvInt returns environment variable as int or default
+func getEnvInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil {
+			return parsed
+		}
+		log.Printf("Invalid %s=%q, using default %d", key, value, defaultValue)
+	}
+	return defaultValue
+}
+
+// getEnvDuration returns environment variable as time.Duration or default
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := time.ParseDuration(value); err == nil {
+			return parsed
+		}
+		log.Printf("Invalid %s=%q, using default %s", key, value, defaultValue)
+	}
+	return defaultValue
+}
+
+// getEnvFloat returns environment variable as float64 or default
+func getEnvFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+			return parsed
+		}
+		log.Printf("Invalid %s=%q, using default %g", key, value, defaultValue)
+	}
+	return defaultValue
+}
@@ -0,0 +1,136 @@
+// Package barcode finds and decodes QR codes and common 1D barcodes in
+// images, so documents carrying both text and codes can be read in one pass.
+package barcode
+
+import (
+	"context"
+	"image"
+	"math"
+
+	"github.com/makiuchi-d/gozxing"
+	multiqr "github.com/makiuchi-d/gozxing/multi/qrcode"
+	"github.com/makiuchi-d/gozxing/oned"
+)
+
+// Code is a decoded barcode
+type Code struct {
+	// Format names the symbology, such as QR_CODE, EAN_13 or CODE_128
+	Format string
+
+	// Value is the decoded content
+	Value string
+
+	// Box encloses the whole symbol of a QR code, finder patterns
+	// included, or the ends of the scanned row of a 1D barcode
+	Box image.Rectangle
+}
+
+// hints makes the 1D readers scan more rows, trading speed for finding
+// small barcodes
+var hints = map[gozxing.DecodeHintType]interface{}{
+	gozxing.DecodeHintType_TRY_HARDER: true,
+}
+
+// linearReaders return a single 1D barcode each; the formats are the ones
+// found on invoices and shipping labels
+func linearReaders() []gozxing.Reader {
+	return []gozxing.Reader{
+		oned.NewMultiFormatUPCEANReader(hints),
+		oned.NewCode128Reader(),
+		oned.NewCode39Reader(),
+	}
+}
+
+// Detect decodes every QR code in img and the first barcode of each 1D
+// format. Images without codes return nil. The readers cannot be
+// interrupted, so ctx is checked between them and its error returned once
+// it is done.
+func Detect(ctx context.Context, img image.Image) ([]Code, error) {
+	bitmap, err := gozxing.NewBinaryBitmapFromImage(img)
+	if err != nil {
+		return nil, nil
+	}
+
+	// Reader errors only mean no code of that kind was found
+	var codes []Code
+	if results, err := multiqr.NewQRCodeMultiReader().DecodeMultiple(bitmap, hints); err == nil {
+		for _, result := range results {
+			codes = append(codes, newCode(result, qrBox(result.GetResultPoints()).Intersect(img.Bounds())))
+		}
+	}
+	for _, reader := range linearReaders() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if result, err := reader.Decode(bitmap, hints); err == nil {
+			codes = append(codes, newCode(result, pointsBox(result.GetResultPoints())))
+		}
+	}
+	return codes, ctx.Err()
+}
+
+// newCode converts a decoder result
+func newCode(result *gozxing.Result, box image.Rectangle) Code {
+	return Code{
+		Format: result.GetBarcodeFormat().String(),
+		Value:  result.GetText(),
+		Box:    box,
+	}
+}
+
+// finderModules is the distance, in modules, from the center of a QR
+// finder pattern to the outer edge of the symbol
+const finderModules = 3.5
+
+// qrBox returns the box of the QR symbol whose finder pattern centers are
+// points, ordered bottom-left, top-left, top-right. The symbol is the
+// parallelogram the centers span, grown by half a finder pattern on every
+// side along its own axes, so rotated codes are covered too.
+func qrBox(points []gozxing.ResultPoint) image.Rectangle {
+	if len(points) < 3 {
+		return pointsBox(points)
+	}
+	module, ok := points[1].(interface{ GetEstimatedModuleSize() float64 })
+	if !ok {
+		return pointsBox(points)
+	}
+	bottomLeft, topLeft, topRight := points[0], points[1], points[2]
+
+	// Unit steps along the top and left edges, scaled to the outset
+	outset := finderModules * module.GetEstimatedModuleSize()
+	ux, uy := unit(topRight.GetX()-topLeft.GetX(), topRight.GetY()-topLeft.GetY())
+	vx, vy := unit(bottomLeft.GetX()-topLeft.GetX(), bottomLeft.GetY()-topLeft.GetY())
+	ux, uy, vx, vy = ux*outset, uy*outset, vx*outset, vy*outset
+
+	bottomRightX := topRight.GetX() + bottomLeft.GetX() - topLeft.GetX()
+	bottomRightY := topRight.GetY() + bottomLeft.GetY() - topLeft.GetY()
+	return pointsBox([]gozxing.ResultPoint{
+		gozxing.NewResultPoint(topLeft.GetX()-ux-vx, topLeft.GetY()-uy-vy),
+		gozxing.NewResultPoint(topRight.GetX()+ux-vx, topRight.GetY()+uy-vy),
+		gozxing.NewResultPoint(bottomLeft.GetX()-ux+vx, bottomLeft.GetY()-uy+vy),
+		gozxing.NewResultPoint(bottomRightX+ux+vx, bottomRightY+uy+vy),
+	})
+}
+
+// unit returns the vector x, y scaled to length one
+func unit(x, y float64) (float64, float64) {
+	length := math.Hypot(x, y)
+	if length == 0 {
+		return 0, 0
+	}
+	return x / length, y / length
+}
+
+// pointsBox returns the smallest rectangle holding every point
+func pointsBox(points []gozxing.ResultPoint) image.Rectangle {
+	if len(points) == 0 {
+		return image.Rectangle{}
+	}
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, p := range points {
+		minX, maxX = math.Min(minX, p.GetX()), math.Max(maxX, p.GetX())
+		minY, maxY = math.Min(minY, p.GetY()), math.Max(maxY, p.GetY())
+	}
+	return image.Rect(int(math.Floor(minX)), int(math.Floor(minY)), int(math.Ceil(maxX))+1, int(math.Ceil(maxY))+1)
+}
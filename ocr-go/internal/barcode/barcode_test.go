@@ -0,0 +1,100 @@
+package barcode
+
+import (
+	"context"
+	"image"
+	"image/draw"
+	"testing"
+
+	"github.com/makiuchi-d/gozxing"
+	"github.com/makiuchi-d/gozxing/oned"
+	"github.com/makiuchi-d/gozxing/qrcode"
+)
+
+// page returns a white 600x300 page with code drawn at offset
+func page(code image.Image, offset image.Point) image.Image {
+	img := image.NewGray(image.Rect(0, 0, 600, 300))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(img, code.Bounds().Add(offset), code, image.Point{}, draw.Src)
+	return img
+}
+
+func TestDetectQRCode(t *testing.T) {
+	matrix, err := qrcode.NewQRCodeWriter().Encode("https://example.com/invoice/42", gozxing.BarcodeFormat_QR_CODE, 200, 200, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	offset := image.Pt(350, 50)
+
+	codes, err := Detect(context.Background(), page(matrix, offset))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(codes) != 1 {
+		t.Fatalf("Detect() = %+v, want one code", codes)
+	}
+	code := codes[0]
+	if code.Format != "QR_CODE" || code.Value != "https://example.com/invoice/42" {
+		t.Errorf("code = %+v", code)
+	}
+
+	// The box should match the dark modules, not the quiet zone around
+	// them, give or take a module
+	symbol := darkBounds(matrix).Add(offset)
+	for _, d := range []int{
+		code.Box.Min.X - symbol.Min.X, code.Box.Min.Y - symbol.Min.Y,
+		code.Box.Max.X - symbol.Max.X, code.Box.Max.Y - symbol.Max.Y,
+	} {
+		if d < -5 || d > 5 {
+			t.Errorf("box = %v, want the symbol %v", code.Box, symbol)
+			break
+		}
+	}
+}
+
+// darkBounds returns the bounds of the set bits of matrix
+func darkBounds(matrix *gozxing.BitMatrix) image.Rectangle {
+	var bounds image.Rectangle
+	for y := 0; y < matrix.GetHeight(); y++ {
+		for x := 0; x < matrix.GetWidth(); x++ {
+			if matrix.Get(x, y) {
+				bounds = bounds.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+	return bounds
+}
+
+func TestDetectCanceled(t *testing.T) {
+	matrix, err := oned.NewCode128Writer().Encode("INV-2024-0042", gozxing.BarcodeFormat_CODE_128, 300, 80, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if codes, err := Detect(ctx, page(matrix, image.Pt(20, 100))); err != context.Canceled || codes != nil {
+		t.Errorf("Detect() = %+v, %v, want context.Canceled", codes, err)
+	}
+}
+
+func TestDetectBarcode(t *testing.T) {
+	matrix, err := oned.NewCode128Writer().Encode("INV-2024-0042", gozxing.BarcodeFormat_CODE_128, 300, 80, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	codes, err := Detect(context.Background(), page(matrix, image.Pt(20, 100)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(codes) != 1 || codes[0].Format != "CODE_128" || codes[0].Value != "INV-2024-0042" {
+		t.Errorf("Detect() = %+v, want the CODE_128 barcode", codes)
+	}
+}
+
+func TestDetectNothing(t *testing.T) {
+	if codes, err := Detect(context.Background(), page(image.NewGray(image.Rectangle{}), image.Point{})); codes != nil || err != nil {
+		t.Errorf("Detect() = %+v, %v, want nil", codes, err)
+	}
+}
@@ -0,0 +1,153 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// ErrForbiddenAddress is returned when a URL resolves to an internal address
+var ErrForbiddenAddress = errors.New("destination address is not allowed")
+
+// ErrTooLarge is returned when the response body exceeds the size limit
+var ErrTooLarge = errors.New("response body exceeds size limit")
+
+// Fetcher downloads remote resources while refusing to connect to
+// loopback, private, link-local and other internal addresses
+type Fetcher struct {
+	client  *http.Client
+	maxSize int64
+}
+
+// New creates a Fetcher with the given request timeout and body size limit
+func New(timeout time.Duration, maxSize int64) *Fetcher {
+	return &Fetcher{client: NewClient(timeout), maxSize: maxSize}
+}
+
+// NewClient returns an HTTP client with the given timeout that refuses to
+// connect to internal addresses, for any request to a URL a client chose
+func NewClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 10 * time.Second,
+		// Checking the resolved IP at dial time also covers redirects and
+		// DNS rebinding, not just the host in the original URL
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || !allowedIP(ip) {
+				return ErrForbiddenAddress
+			}
+			return nil
+		},
+	}
+
+	transport := &http.Transport{
+		Proxy:                 nil,
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ResponseHeaderTimeout: timeout,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       90 * time.Second,
+	}
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 5 {
+				return errors.New("too many redirects")
+			}
+			return ValidateURL(req.URL)
+		},
+	}
+}
+
+// Get downloads the resource at rawURL and returns its body
+func (f *Fetcher) Get(ctx context.Context, rawURL string) ([]byte, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL: %w", err)
+	}
+	if err := ValidateURL(u); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL: %w", err)
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		if errors.Is(err, ErrForbiddenAddress) {
+			return nil, ErrForbiddenAddress
+		}
+		return nil, fmt.Errorf("failed to fetch URL: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch URL: status %d", resp.StatusCode)
+	}
+	if resp.ContentLength > f.maxSize {
+		return nil, ErrTooLarge
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, f.maxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if int64(len(data)) > f.maxSize {
+		return nil, ErrTooLarge
+	}
+
+	return data, nil
+}
+
+// ValidateURL checks that u is an absolute http(s) URL whose host is not a
+// literal internal address. Hostnames are checked again once resolved.
+func ValidateURL(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("unsupported URL scheme %q", u.Scheme)
+	}
+	host := u.Hostname()
+	if host == "" {
+		return errors.New("URL has no host")
+	}
+	if host == "localhost" {
+		return ErrForbiddenAddress
+	}
+	if ip := net.ParseIP(host); ip != nil && !allowedIP(ip) {
+		return ErrForbiddenAddress
+	}
+	return nil
+}
+
+// loopbackAllowed also lets connections reach loopback addresses. Only
+// tests set it, to reach their local servers; dials may still be running
+// when a test ends, so it is atomic.
+var loopbackAllowed atomic.Bool
+
+// allowedIP decides which addresses may be connected to
+func allowedIP(ip net.IP) bool {
+	return publicIP(ip) || (loopbackAllowed.Load() && ip.IsLoopback())
+}
+
+// publicIP reports whether ip is a globally routable unicast address
+func publicIP(ip net.IP) bool {
+	return ip.IsGlobalUnicast() &&
+		!ip.IsPrivate() &&
+		!ip.IsLoopback() &&
+		!ip.IsLinkLocalUnicast() &&
+		!ip.IsUnspecified()
+}
@@ -0,0 +1,107 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// allowLoopback lets the fetcher reach httptest servers for the duration
+// of a test, other internal addresses stay forbidden
+func allowLoopback(t *testing.T) {
+	loopbackAllowed.Store(true)
+	t.Cleanup(func() { loopbackAllowed.Store(false) })
+}
+
+func TestGet(t *testing.T) {
+	allowLoopback(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("image"))
+	}))
+	defer srv.Close()
+
+	data, err := New(5*time.Second, 1<<10).Get(context.Background(), srv.URL+"/scan.png")
+	if err != nil || string(data) != "image" {
+		t.Errorf("Get() = %q, %v; want the body", data, err)
+	}
+}
+
+func TestGetForbiddenAddress(t *testing.T) {
+	f := New(5*time.Second, 1<<10)
+	for _, rawURL := range []string{
+		"http://127.0.0.1/",
+		"http://localhost:8080/",
+		"http://10.0.0.1/",
+		"http://192.168.1.1/",
+		"http://169.254.169.254/latest/meta-data/",
+		"http://[::1]/",
+	} {
+		if _, err := f.Get(context.Background(), rawURL); !errors.Is(err, ErrForbiddenAddress) {
+			t.Errorf("Get(%s) error = %v, want ErrForbiddenAddress", rawURL, err)
+		}
+	}
+}
+
+func TestGetRedirectToPrivateAddress(t *testing.T) {
+	allowLoopback(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://10.1.2.3/secret", http.StatusFound)
+	}))
+	defer srv.Close()
+
+	if _, err := New(5*time.Second, 1<<10).Get(context.Background(), srv.URL); !errors.Is(err, ErrForbiddenAddress) {
+		t.Errorf("error = %v, want ErrForbiddenAddress", err)
+	}
+}
+
+func TestClientDialsOnlyAllowedAddresses(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	// Without ValidateURL in front, the resolved address is still checked
+	// when dialing, which is what catches hostnames and DNS rebinding
+	resp, err := NewClient(5 * time.Second).Get(srv.URL)
+	if err == nil {
+		resp.Body.Close()
+	}
+	if !errors.Is(err, ErrForbiddenAddress) {
+		t.Errorf("error = %v, want ErrForbiddenAddress", err)
+	}
+}
+
+func TestGetTooLarge(t *testing.T) {
+	allowLoopback(t)
+	body := strings.Repeat("x", 2048)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/chunked" {
+			// Flushing first sends the body without a Content-Length
+			w.(http.Flusher).Flush()
+		}
+		w.Write([]byte(body))
+	}))
+	defer srv.Close()
+
+	f := New(5*time.Second, 1<<10)
+	for _, path := range []string{"/sized", "/chunked"} {
+		if _, err := f.Get(context.Background(), srv.URL+path); !errors.Is(err, ErrTooLarge) {
+			t.Errorf("%s: error = %v, want ErrTooLarge", path, err)
+		}
+	}
+}
+
+func TestValidateURL(t *testing.T) {
+	for _, rawURL := range []string{"ftp://example.com/scan.png", "/scan.png", "http:///scan.png"} {
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := ValidateURL(u); err == nil || errors.Is(err, ErrForbiddenAddress) {
+			t.Errorf("ValidateURL(%s) = %v, want an invalid URL error", rawURL, err)
+		}
+	}
+}
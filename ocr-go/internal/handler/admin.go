@@ -0,0 +1,122 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/username/ocr-go/internal/model"
+	"github.com/username/ocr-go/internal/ocr"
+)
+
+// maxTraineddataSize bounds uploaded language models; the largest
+// official tessdata_best models are around 100MB
+const maxTraineddataSize = 256 << 20
+
+// modelNamePattern matches a single language name that parseOCROptions
+// accepts in lang
+var modelNamePattern = regexp.MustCompile(`^[a-z][a-z_]*$`)
+
+// UploadLanguage installs an uploaded .traineddata file into the tessdata
+// directory so its language can be used in lang right away. The language
+// is named by the "lang" field, or else by the file name.
+func (h *Handler) UploadLanguage(w http.ResponseWriter, r *http.Request) {
+	if h.tessdataDir == "" {
+		h.respondError(w, http.StatusServiceUnavailable,
+			"Custom languages require TESSDATA_PREFIX to be set")
+		return
+	}
+
+	// Models are far larger than images and may take longer to upload
+	// than the server's read timeout. The write timeout counts from the
+	// same start, so the response would be cut off too.
+	rc := http.NewResponseController(w)
+	if err := rc.SetReadDeadline(time.Time{}); err != nil {
+		log.Printf("Failed to lift read deadline for model upload: %v", err)
+	}
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		log.Printf("Failed to lift write deadline for model upload: %v", err)
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxTraineddataSize+1<<20)
+	if err := r.ParseMultipartForm(10 << 20); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			h.respondError(w, http.StatusRequestEntityTooLarge,
+				fmt.Sprintf("Model exceeds %d bytes", maxTraineddataSize))
+			return
+		}
+		h.respondError(w, http.StatusBadRequest, "Failed to parse form")
+		return
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		h.respondFieldError(w, codeMissingField, "file", "No file uploaded")
+		return
+	}
+	defer file.Close()
+
+	lang := r.FormValue("lang")
+	if lang == "" {
+		lang = strings.TrimSuffix(header.Filename, ".traineddata")
+	}
+	if !modelNamePattern.MatchString(lang) {
+		h.respondFieldError(w, codeInvalidField, "lang",
+			"lang must be lowercase letters and underscores, such as forms or eng_forms")
+		return
+	}
+
+	if err := ocr.ValidateTraineddata(file, header.Size); err != nil {
+		h.respondFieldError(w, codeInvalidField, "file", err.Error())
+		return
+	}
+
+	target := filepath.Join(h.tessdataDir, lang+".traineddata")
+	_, statErr := os.Stat(target)
+	replaced := statErr == nil
+
+	if err := installFile(h.tessdataDir, target, file); err != nil {
+		log.Printf("Failed to install language %s: %v", lang, err)
+		h.respondError(w, http.StatusInternalServerError, "Failed to save model")
+		return
+	}
+	ocr.ReloadLanguage(h.engine, lang)
+	log.Printf("Installed language %s (%d bytes)", lang, header.Size)
+
+	h.respondJSON(w, http.StatusCreated, model.LanguageUploadResponse{
+		Language: lang,
+		Size:     header.Size,
+		Replaced: replaced,
+	})
+}
+
+// installFile copies src to a temporary file in dir and renames it to
+// target, so Tesseract never reads a partly written model
+func installFile(dir, target string, src io.ReadSeeker) error {
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, src); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), target)
+}
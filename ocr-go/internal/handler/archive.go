@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"image"
+	"io"
+	"log"
+	"path/filepath"
+)
+
+// saveZip stores a ZIP archive under name. The archive is streamed into
+// storage as write adds entries, so large archives are never held in
+// memory as a whole.
+func (h *Handler) saveZip(ctx context.Context, name string, write func(zw *zip.Writer) error) error {
+	pr, pw := io.Pipe()
+	go func() {
+		zw := zip.NewWriter(pw)
+		err := write(zw)
+		if closeErr := zw.Close(); err == nil {
+			err = closeErr
+		}
+		pw.CloseWithError(err)
+	}()
+
+	err := h.storage.Put(ctx, name, pr)
+	// Unblock the writer if storage stopped reading early
+	pr.CloseWithError(err)
+	if err != nil {
+		log.Printf("Failed to save archive %s: %v", name, err)
+	}
+	return err
+}
+
+// copyToZip adds the stored object name to the archive under the same
+// name, streaming it from storage. PNG entries are stored rather than
+// deflated, like writeZipPNG.
+func (h *Handler) copyToZip(ctx context.Context, zw *zip.Writer, name string) error {
+	src, _, err := h.storage.Get(ctx, name)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	method := zip.Deflate
+	if filepath.Ext(name) == ".png" {
+		method = zip.Store
+	}
+	dst, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: method})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(dst, src)
+	return err
+}
+
+// writeZipJSON adds data to the archive as an indented JSON entry
+func writeZipJSON(zw *zip.Writer, name string, data interface{}) error {
+	f, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	return enc.Encode(data)
+}
+
+// writeZipPNG adds img to the archive as a PNG entry. PNG data is already
+// compressed, so the entry is stored rather than deflated.
+func writeZipPNG(zw *zip.Writer, name string, img image.Image) error {
+	f, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Store})
+	if err != nil {
+		return err
+	}
+	return pngEncoder.Encode(f, img)
+}
@@ -1,17 +1,18 @@
 package handler
 
 import (
+	"archive/zip"
+	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
-	"image"
 	_ "image/gif"
 	_ "image/jpeg"
 	_ "image/png"
+	"io"
+	"log"
 	"mime/multipart"
 	"net/http"
-	"os"
-	"path/filepath"
+	"runtime/debug"
 	"sync"
 	"time"
 
@@ -19,36 +20,119 @@ import (
 	"github.com/username/ocr-go/internal/model"
 )
 
+// batchFile is a single uploaded file in a batch
+type batchFile struct {
+	name string
+	size int64
+	open func() (io.ReadSeekCloser, error)
+}
+
+// batchOptions are the per-request options of a batch
+type batchOptions struct {
+	// zip bundles the result files into a single archive
+	zip bool
+	// annotate saves each image with its word boxes drawn on
+	annotate bool
+	// tenant namespaces the result files, see requestTenant
+	tenant string
+}
+
+// parseBatchOptions reads the batch options from the query string
+func parseBatchOptions(r *http.Request) batchOptions {
+	query := r.URL.Query()
+	return batchOptions{
+		zip:      query.Get("zip") == "true",
+		annotate: query.Get("annotate") == "true",
+	}
+}
+
 // BatchProcess handles batch processing of multiple files
 func (h *Handler) BatchProcess(w http.ResponseWriter, r *http.Request) {
-	startTime := time.Now()
+	// Up to 50MB of the files stay in memory while parsing
+	if !h.parseUploadForm(w, r, h.maxUploadSize*int64(h.maxBatchFiles), 50<<20) {
+		return
+	}
 
-	// Parse multipart form (50MB max for batch)
-	if err := r.ParseMultipartForm(50 << 20); err != nil {
-		h.respondError(w, http.StatusBadRequest, "Failed to parse form")
+	headers := r.MultipartForm.File["files"]
+	if len(headers) == 0 {
+		h.respondFieldError(w, codeMissingField, "files", "No files uploaded")
+		return
+	}
+	if len(headers) > h.maxBatchFiles {
+		h.respondFieldError(w, codeInvalidField, "files",
+			fmt.Sprintf("Too many files: %d (max %d)", len(headers), h.maxBatchFiles))
 		return
 	}
+	for _, header := range headers {
+		if header.Size > h.maxUploadSize {
+			h.respondError(w, http.StatusRequestEntityTooLarge,
+				fmt.Sprintf("File %q exceeds %d bytes", header.Filename, h.maxUploadSize))
+			return
+		}
+	}
 
-	files := r.MultipartForm.File["files"]
-	if len(files) == 0 {
-		h.respondError(w, http.StatusBadRequest, "No files uploaded")
+	opts := parseBatchOptions(r)
+	tenant, err := requestTenant(r)
+	if err != nil {
+		h.respondFieldError(w, codeInvalidField, "tenant", err.Error())
+		return
+	}
+	opts.tenant = tenant
+	if r.URL.Query().Get("async") == "true" {
+		h.enqueueBatch(w, r, headers, opts)
 		return
 	}
 
+	files := make([]batchFile, len(headers))
+	for i, header := range headers {
+		header := header
+		files[i] = batchFile{
+			name: header.Filename,
+			size: header.Size,
+			open: func() (io.ReadSeekCloser, error) { return header.Open() },
+		}
+	}
+
+	// A large batch can outlast the server's write timeout; each file is
+	// bounded by the batch file timeout instead
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+		log.Printf("Failed to lift write deadline for batch: %v", err)
+	}
+
+	h.respondJSON(w, http.StatusOK, h.runBatch(r.Context(), files, opts, nil))
+}
+
+// runBatch processes files concurrently and aggregates the results,
+// bundling the result files into a ZIP archive if opts.zip is set.
+// If events is non-nil a "file" event is sent on it as each file finishes;
+// the caller must drain the channel until runBatch returns.
+func (h *Handler) runBatch(ctx context.Context, files []batchFile, opts batchOptions, events chan<- model.BatchEvent) model.BatchProcessResponse {
+	startTime := h.clock.Now()
+
 	// Process files concurrently
 	results := make([]model.BatchResult, len(files))
 	var wg sync.WaitGroup
-	semaphore := make(chan struct{}, 4) // Limit to 4 concurrent processes
+	semaphore := make(chan struct{}, h.batchConcurrency)
 
-	for i, fileHeader := range files {
+	for i, file := range files {
 		wg.Add(1)
-		go func(index int, header *multipart.FileHeader) {
+		go func(index int, file batchFile) {
 			defer wg.Done()
 			semaphore <- struct{}{}
 			defer func() { <-semaphore }()
 
-			results[index] = h.processFile(r.Context(), header)
-		}(i, fileHeader)
+			results[index] = h.safeProcessFile(ctx, index, file, opts)
+			if events != nil {
+				events <- model.BatchEvent{
+					Type:     "file",
+					Index:    index,
+					Total:    len(files),
+					Filename: results[index].Filename,
+					Success:  results[index].Success,
+					Error:    results[index].Error,
+				}
+			}
+		}(i, file)
 	}
 
 	wg.Wait()
@@ -65,43 +149,103 @@ func (h *Handler) BatchProcess(w http.ResponseWriter, r *http.Request) {
 	}
 
 	response := model.BatchProcessResponse{
-		TotalFiles:     len(files),
-		SuccessCount:   successCount,
-		FailureCount:   failureCount,
-		Results:        results,
-		ProcessingTime: time.Since(startTime).String(),
+		TotalFiles:   len(files),
+		SuccessCount: successCount,
+		FailureCount: failureCount,
+		Results:      results,
+	}
+
+	if opts.zip && successCount > 0 {
+		archiveName := fmt.Sprintf("%sbatch_%s.zip", tenantPrefix(opts.tenant), uuid.Must(uuid.NewV4()).String())
+		if err := h.saveZip(ctx, archiveName, func(zw *zip.Writer) error {
+			return h.bundleResults(ctx, zw, results)
+		}); err == nil {
+			response.ArchiveFile = archiveName
+			response.DownloadURL = "/api/results/" + archiveName
+		}
+	}
+
+	response.ProcessingTime = h.clock.Now().Sub(startTime).String()
+	return response
+}
+
+// bundleResults copies the stored result JSON and annotated image of each
+// successful file into the archive, one entry at a time
+func (h *Handler) bundleResults(ctx context.Context, zw *zip.Writer, results []model.BatchResult) error {
+	for _, result := range results {
+		for _, name := range []string{result.OutputFile, result.AnnotatedFile} {
+			if name == "" {
+				continue
+			}
+			if err := h.copyToZip(ctx, zw, name); err != nil {
+				return fmt.Errorf("failed to add %s: %w", name, err)
+			}
+		}
 	}
+	return nil
+}
+
+// safeProcessFile runs processFile, turning a panic, such as a decoder
+// crash on a malformed image, into a failure result for that file only
+func (h *Handler) safeProcessFile(ctx context.Context, index int, batch batchFile, opts batchOptions) (result model.BatchResult) {
+	defer func() {
+		if p := recover(); p != nil {
+			log.Printf("Panic processing batch file %q: %v\n%s", batch.name, p, debug.Stack())
+			result = model.BatchResult{
+				Index:    index,
+				Filename: batch.name,
+				Size:     batch.size,
+				Error:    fmt.Sprintf("Processing failed: %v", p),
+			}
+		}
+	}()
 
-	h.respondJSON(w, http.StatusOK, response)
+	return h.processFile(ctx, index, batch, opts)
 }
 
 // processFile processes a single file for batch processing
-func (h *Handler) processFile(ctx context.Context, header *multipart.FileHeader) model.BatchResult {
+func (h *Handler) processFile(ctx context.Context, index int, batch batchFile, opts batchOptions) model.BatchResult {
 	result := model.BatchResult{
-		Filename: header.Filename,
+		Index:    index,
+		Filename: batch.name,
+		Size:     batch.size,
 	}
 
-	file, err := header.Open()
+	file, err := batch.open()
 	if err != nil {
 		result.Error = fmt.Sprintf("Failed to open file: %v", err)
 		return result
 	}
 	defer file.Close()
 
-	img, _, err := image.Decode(file)
+	data, err := io.ReadAll(file)
+	if err != nil {
+		result.Error = fmt.Sprintf("Failed to read file: %v", err)
+		return result
+	}
+
+	img, imageFormat, err := h.decodeImage(bytes.NewReader(data))
 	if err != nil {
 		result.Error = fmt.Sprintf("Invalid image: %v", err)
 		return result
 	}
 
-	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, h.batchFileTimeout)
 	defer cancel()
 
+	if err := h.acquireOCR(ctx); err != nil {
+		result.Error = fmt.Sprintf("OCR failed: %v", err)
+		return result
+	}
+	start := h.clock.Now()
 	ocrResult, err := h.engine.ExtractTextWithBoxes(ctx, img)
+	h.releaseOCR()
 	if err != nil {
 		result.Error = fmt.Sprintf("OCR failed: %v", err)
 		return result
 	}
+	h.stats.record(ocrResult.Language, ocrResult.MeanConfidence, h.clock.Now().Sub(start))
+	sanitizeResult(ocrResult)
 
 	result.Lines = ocrResult.TotalLines
 	result.Success = true
@@ -113,21 +257,75 @@ func (h *Handler) processFile(ctx context.Context, header *multipart.FileHeader)
 		result.Preview = ocrResult.FullText
 	}
 
-	// Save result to file
+	// Save original and result to storage
 	resultID := uuid.Must(uuid.NewV4()).String()
-	outputPath := filepath.Join("outputs", fmt.Sprintf("ocr_%s.json", resultID))
+	result.ContentHash = contentHash(data)
+	outputName := resultFileName(opts.tenant, result.ContentHash, resultID)
+	result.SourceFile = h.saveUpload(ctx, resultID, imageFormat, data)
 
-	outputFile, err := os.Create(outputPath)
+	err = h.saveResult(ctx, resultID, outputName, map[string]interface{}{
+		"id":           resultID,
+		"index":        index,
+		"filename":     batch.name,
+		"content_hash": result.ContentHash,
+		"source_file":  result.SourceFile,
+		"full_text":    ocrResult.FullText,
+		"boxes":        ocrResult.Boxes,
+		"total_lines":  ocrResult.TotalLines,
+	})
 	if err == nil {
-		defer outputFile.Close()
-		json.NewEncoder(outputFile).Encode(map[string]interface{}{
-			"filename":    header.Filename,
-			"full_text":   ocrResult.FullText,
-			"boxes":       ocrResult.Boxes,
-			"total_lines": ocrResult.TotalLines,
-		})
-		result.OutputFile = filepath.Base(outputPath)
+		result.OutputFile = outputName
+	}
+
+	if opts.annotate {
+		rgba, release := newDrawable(img)
+		drawBoxes(rgba, ocrResult.Boxes, false)
+		annotatedName := fmt.Sprintf("%sboxes_%s.png", tenantPrefix(opts.tenant), resultID)
+		if err := h.savePNG(ctx, annotatedName, rgba); err == nil {
+			result.AnnotatedFile = annotatedName
+		}
+		release()
 	}
 
 	return result
 }
+
+// bufferBatchFiles copies uploaded files into memory so they outlive the request
+func bufferBatchFiles(headers []*multipart.FileHeader) []batchFile {
+	files := make([]batchFile, len(headers))
+	for i, header := range headers {
+		data, err := readFileHeader(header)
+		files[i] = batchFile{
+			name: header.Filename,
+			size: header.Size,
+			open: func() (io.ReadSeekCloser, error) {
+				if err != nil {
+					return nil, err
+				}
+				return nopSeekCloser{bytes.NewReader(data)}, nil
+			},
+		}
+	}
+	return files
+}
+
+// readFileHeader reads the full contents of an uploaded file
+func readFileHeader(header *multipart.FileHeader) ([]byte, error) {
+	file, err := header.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return io.ReadAll(file)
+}
+
+// nopSeekCloser adds a no-op Close to an in-memory reader
+type nopSeekCloser struct {
+	*bytes.Reader
+}
+
+// Close implements io.Closer
+func (nopSeekCloser) Close() error {
+	return nil
+}
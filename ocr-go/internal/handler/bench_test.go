@@ -0,0 +1,75 @@
+package handler_test
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"testing"
+)
+
+// multipartBody encodes data as the "file" part of a multipart form
+func multipartBody(b *testing.B, data []byte) ([]byte, string) {
+	b.Helper()
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("file", "scan.png")
+	if err != nil {
+		b.Fatal(err)
+	}
+	part.Write(data)
+	writer.Close()
+
+	return body.Bytes(), writer.FormDataContentType()
+}
+
+// largePNG encodes a white page the size of an A4 scan at 300 dpi
+func largePNG(b *testing.B) []byte {
+	b.Helper()
+
+	img := image.NewGray(image.Rect(0, 0, 2480, 3508))
+	for i := range img.Pix {
+		img.Pix[i] = 255
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		b.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func benchmarkExtract(b *testing.B, data []byte) {
+	srv := newTestServer(b, testEngine())
+	body, contentType := multipartBody(b, data)
+
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		resp, err := http.Post(srv.URL+"/api/extract", contentType, bytes.NewReader(body))
+		if err != nil {
+			b.Fatal(err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			b.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+		}
+	}
+}
+
+// BenchmarkExtractText measures handler overhead on a small image; the
+// fake engine keeps OCR time out of the measurement
+func BenchmarkExtractText(b *testing.B) {
+	benchmarkExtract(b, pngImage(b))
+}
+
+// BenchmarkExtractTextLarge measures decoding and storage of a full page scan
+func BenchmarkExtractTextLarge(b *testing.B) {
+	benchmarkExtract(b, largePNG(b))
+}
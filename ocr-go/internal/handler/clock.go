@@ -0,0 +1,17 @@
+package handler
+
+import "time"
+
+// Clock provides the current time. Tests can replace it to get
+// deterministic timestamps and durations.
+type Clock interface {
+	Now() time.Time
+}
+
+// realClock is the Clock backed by the system time
+type realClock struct{}
+
+// Now returns the current system time
+func (realClock) Now() time.Time {
+	return time.Now()
+}
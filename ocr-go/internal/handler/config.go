@@ -0,0 +1,30 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/username/ocr-go/internal/model"
+)
+
+// Config reports the effective runtime configuration so misconfigured
+// deployments can be diagnosed. Secrets such as API keys are never included.
+func (h *Handler) Config(w http.ResponseWriter, r *http.Request) {
+	h.respondJSON(w, http.StatusOK, model.ConfigResponse{
+		Language:         h.engine.Language(),
+		AutoLanguages:    h.autoLanguages,
+		MaxUploadSize:    h.maxUploadSize,
+		MaxImagePixels:   h.maxImagePixels,
+		ThumbnailSize:    h.thumbnailSize,
+		StreamMaxFPS:     h.streamMaxFPS,
+		MaxBatchFiles:    h.maxBatchFiles,
+		BatchConcurrency: h.batchConcurrency,
+		MaxConcurrentOCR: h.maxConcurrentOCR,
+		OCRQueueSize:     h.ocrQueueSize,
+		OCRQueueWait:     h.ocrQueueWait.String(),
+		BatchFileTimeout: h.batchFileTimeout.String(),
+		OutputDir:        h.outputDir,
+		UploadDir:        h.uploadDir,
+		DictionaryDir:    h.dictionaryDir,
+		Server:           h.serverConfig,
+	})
+}
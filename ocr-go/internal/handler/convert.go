@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// defaultJPEGQuality is used when /api/convert gets no quality
+const defaultJPEGQuality = 90
+
+// ConvertImage decodes an uploaded image with the same decoders and size
+// guards as the OCR endpoints and re-encodes it as PNG or JPEG
+func (h *Handler) ConvertImage(w http.ResponseWriter, r *http.Request) {
+	to := strings.ToLower(r.URL.Query().Get("to"))
+	if to == "jpg" {
+		to = "jpeg"
+	}
+	if to != "png" && to != "jpeg" {
+		h.respondFieldError(w, codeInvalidField, "to", "to must be png or jpeg")
+		return
+	}
+
+	quality, ok := parseJPEGQuality(r.URL.Query().Get("quality"))
+	if !ok {
+		h.respondFieldError(w, codeInvalidField, "quality", "quality must be between 1 and 100")
+		return
+	}
+
+	if !h.parseUploadForm(w, r, h.maxUploadSize, 10<<20) {
+		return
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		h.respondFieldError(w, codeMissingField, "file", "No file uploaded")
+		return
+	}
+	defer file.Close()
+
+	img, _, err := h.decodeImage(file)
+	if err != nil {
+		h.respondDecodeError(w, err)
+		return
+	}
+
+	var buf bytes.Buffer
+	switch to {
+	case "png":
+		err = pngEncoder.Encode(&buf, img)
+	case "jpeg":
+		err = jpeg.Encode(&buf, flatten(img), &jpeg.Options{Quality: quality})
+	}
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, "Failed to encode image")
+		return
+	}
+
+	name := strings.TrimSuffix(filepath.Base(header.Filename), filepath.Ext(header.Filename)) + "." + to
+	w.Header().Set("Content-Type", "image/"+to)
+	w.Header().Set("Content-Disposition",
+		mime.FormatMediaType("attachment", map[string]string{"filename": name}))
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	w.WriteHeader(http.StatusOK)
+	buf.WriteTo(w)
+}
+
+// parseJPEGQuality parses a JPEG quality parameter (1-100), defaulting to
+// defaultJPEGQuality when it is empty
+func parseJPEGQuality(value string) (int, bool) {
+	if value == "" {
+		return defaultJPEGQuality, true
+	}
+	q, err := strconv.Atoi(value)
+	if err != nil || q < 1 || q > 100 {
+		return 0, false
+	}
+	return q, true
+}
+
+// flatten composites img onto white, since JPEG has no alpha channel and
+// transparent areas would otherwise turn black
+func flatten(img image.Image) image.Image {
+	if opaque, ok := img.(interface{ Opaque() bool }); ok && opaque.Opaque() {
+		return img
+	}
+
+	bounds := img.Bounds()
+	flat := image.NewRGBA(bounds)
+	draw.Draw(flat, bounds, image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(flat, bounds, img, bounds.Min, draw.Over)
+	return flat
+}
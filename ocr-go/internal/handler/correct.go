@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/username/ocr-go/internal/ocr"
+	"github.com/username/ocr-go/internal/textutil"
+)
+
+// Spelling correction settings
+const (
+	// correctMaxConfidence is the confidence below which words are corrected
+	correctMaxConfidence = 0.85
+
+	// correctMaxDistance is the edit distance allowed for longer words;
+	// words of up to four letters may only differ by one edit
+	correctMaxDistance = 2
+)
+
+// validLanguage matches Tesseract language names such as "eng" or "spa+eng"
+var validLanguage = regexp.MustCompile(`^[A-Za-z0-9_]+(\+[A-Za-z0-9_]+)*$`)
+
+// errNoDictionary is returned when no word list exists for a language
+var errNoDictionary = errors.New("no dictionary")
+
+// dictionary returns the word list for lang, loading <lang>.txt from the
+// dictionary directory on first use. Combined languages such as "spa+eng"
+// merge the lists of each language.
+func (h *Handler) dictionary(lang string) (*textutil.Dictionary, error) {
+	if !validLanguage.MatchString(lang) {
+		return nil, fmt.Errorf("invalid language %q", lang)
+	}
+
+	h.dictMu.Lock()
+	defer h.dictMu.Unlock()
+
+	if dict, ok := h.dictionaries[lang]; ok {
+		return dict, nil
+	}
+
+	var readers []io.Reader
+	for _, part := range strings.Split(lang, "+") {
+		file, err := os.Open(filepath.Join(h.dictionaryDir, part+".txt"))
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("%w for language %s", errNoDictionary, part)
+		}
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		readers = append(readers, file, strings.NewReader("\n"))
+	}
+
+	dict, err := textutil.LoadDictionary(io.MultiReader(readers...))
+	if err != nil {
+		return nil, err
+	}
+	h.dictionaries[lang] = dict
+	return dict, nil
+}
+
+// correctBoxes returns a copy of boxes with low-confidence words replaced
+// by their nearest dictionary word
+func correctBoxes(boxes []ocr.TextBox, dict *textutil.Dictionary) []ocr.TextBox {
+	corrected := make([]ocr.TextBox, len(boxes))
+	copy(corrected, boxes)
+
+	for i, box := range corrected {
+		if box.Confidence >= correctMaxConfidence {
+			continue
+		}
+		distance := correctMaxDistance
+		if utf8.RuneCountInString(box.Text) <= 4 {
+			distance = 1
+		}
+		corrected[i].Text = dict.Correct(box.Text, distance)
+	}
+	return corrected
+}
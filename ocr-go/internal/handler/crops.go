@@ -0,0 +1,141 @@
+package handler
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"image"
+	"image/draw"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gofrs/uuid"
+	"github.com/username/ocr-go/internal/model"
+	"github.com/username/ocr-go/internal/ocr"
+)
+
+// maxCropNameLen caps the part of a crop filename taken from its text
+const maxCropNameLen = 32
+
+// ExportCrops recognizes the words in an uploaded image and saves each
+// one as its own cropped PNG, zipped together with a manifest.json that
+// maps every crop filename to its text, confidence and bounding box
+func (h *Handler) ExportCrops(w http.ResponseWriter, r *http.Request) {
+	if !h.parseUploadForm(w, r, h.maxUploadSize, 10<<20) {
+		return
+	}
+
+	opts, err := parseOCROptions(r)
+	if err != nil {
+		h.respondOptionsError(w, err)
+		return
+	}
+	tenant, err := requestTenant(r)
+	if err != nil {
+		h.respondFieldError(w, codeInvalidField, "tenant", err.Error())
+		return
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		h.respondFieldError(w, codeMissingField, "file", "No file uploaded")
+		return
+	}
+	defer file.Close()
+
+	img, _, err := h.decodeImage(file)
+	if err != nil {
+		h.respondDecodeError(w, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	result, err := h.recognize(ctx, img, opts)
+	if err != nil {
+		h.respondOCRError(w, err)
+		return
+	}
+
+	resultID := uuid.Must(uuid.NewV4()).String()
+	outputName := fmt.Sprintf("%scrops_%s.zip", tenantPrefix(tenant), resultID)
+
+	manifest := make(map[string]model.CropEntry)
+	err = h.saveZip(r.Context(), outputName, func(zw *zip.Writer) error {
+		for i, box := range result.Boxes {
+			crop, ok := cropBox(img, box.Box)
+			if !ok {
+				continue
+			}
+			name := cropName(i, box)
+			if err := writeZipPNG(zw, name, crop); err != nil {
+				return err
+			}
+			manifest[name] = model.CropEntry{
+				Text:       box.Text,
+				Confidence: box.Confidence,
+				BBox: model.BBox{
+					X:      box.Box.X,
+					Y:      box.Box.Y,
+					Width:  box.Box.Width,
+					Height: box.Box.Height,
+				},
+			}
+		}
+		return writeZipJSON(zw, "manifest.json", manifest)
+	})
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, "Failed to save crops")
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, model.CropExportResponse{
+		Filename:    header.Filename,
+		OutputFile:  outputName,
+		TotalCrops:  len(manifest),
+		DownloadURL: fmt.Sprintf("/api/results/%s", outputName),
+	})
+}
+
+// cropBox returns the part of img inside box, clipped to the image. It
+// reports false when nothing of the box lies within the image.
+func cropBox(img image.Image, box ocr.BoundingBox) (image.Image, bool) {
+	rect := image.Rect(box.X, box.Y, box.X+box.Width, box.Y+box.Height).
+		Add(img.Bounds().Min).
+		Intersect(img.Bounds())
+	if rect.Empty() {
+		return nil, false
+	}
+
+	if sub, ok := img.(interface {
+		SubImage(image.Rectangle) image.Image
+	}); ok {
+		return sub.SubImage(rect), true
+	}
+
+	crop := image.NewRGBA(image.Rect(0, 0, rect.Dx(), rect.Dy()))
+	draw.Draw(crop, crop.Bounds(), img, rect.Min, draw.Src)
+	return crop, true
+}
+
+// cropName names the crop of the word at index i by its text and
+// confidence, such as "0003_Invoice_97.png". The index keeps names unique
+// when words repeat; characters unsafe in filenames become underscores.
+func cropName(i int, box ocr.TextBox) string {
+	text := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' {
+			return r
+		}
+		return '_'
+	}, box.Text)
+	if runes := []rune(text); len(runes) > maxCropNameLen {
+		text = string(runes[:maxCropNameLen])
+	}
+	if text == "" {
+		text = "word"
+	}
+	return fmt.Sprintf("%04d_%s_%.0f.png", i, text, box.Confidence*100)
+}
@@ -0,0 +1,205 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/gif"
+	"io"
+	"net/http"
+
+	"github.com/username/ocr-go/internal/imageinfo"
+	"github.com/username/ocr-go/internal/preprocess"
+)
+
+// imageTooLargeError reports an image whose pixel count exceeds the limit
+type imageTooLargeError struct {
+	width, height int
+	max           int64
+}
+
+// Error implements error
+func (e *imageTooLargeError) Error() string {
+	return fmt.Sprintf("image is too large: %dx%d pixels (max %d)", e.width, e.height, e.max)
+}
+
+// checkImageSize rejects dimensions whose pixel count exceeds the limit
+func (h *Handler) checkImageSize(width, height int) error {
+	if int64(width)*int64(height) > h.maxImagePixels {
+		return &imageTooLargeError{width: width, height: height, max: h.maxImagePixels}
+	}
+	return nil
+}
+
+// exifSearchLimit is how much of the file is searched for the EXIF
+// orientation. The metadata precedes the pixels and an APP1 segment is at
+// most 64KB, leaving room for a JFIF thumbnail in front of it.
+const exifSearchLimit = 192 << 10
+
+// frameRangeError reports a frame index past the end of the image
+type frameRangeError struct {
+	frame, frames int
+}
+
+// Error implements error
+func (e *frameRangeError) Error() string {
+	return fmt.Sprintf("frame %d requested but the image has %d", e.frame, e.frames)
+}
+
+// decodeImage decodes an image, checking the dimensions in its header
+// against the pixel limit before the full decode allocates the pixels.
+// Only the header is parsed before r is rewound for the full decode, so
+// decompression bombs are rejected cheaply. Images carrying an EXIF
+// orientation, such as phone photos, are turned upright. Animated GIFs
+// yield their first frame.
+func (h *Handler) decodeImage(r io.ReadSeeker) (image.Image, string, error) {
+	img, format, _, err := h.decodeImageFrame(r, 0)
+	return img, format, err
+}
+
+// decodeImageFrame is decodeImage for the given frame of an animated GIF,
+// counting from 0. It also returns the number of frames, which is 1 for
+// other images.
+func (h *Handler) decodeImageFrame(r io.ReadSeeker, frame int) (image.Image, string, int, error) {
+	_, format, err := h.decodeImageConfig(r)
+	if err != nil {
+		return nil, "", 0, err
+	}
+	if format == "gif" {
+		return h.decodeGIFFrame(r, frame)
+	}
+	if frame != 0 {
+		return nil, "", 0, &frameRangeError{frame: frame, frames: 1}
+	}
+
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, "", 0, err
+	}
+	head, err := io.ReadAll(io.LimitReader(r, exifSearchLimit))
+	if err != nil {
+		return nil, "", 0, err
+	}
+	orientation := imageinfo.Orientation(head)
+
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, "", 0, err
+	}
+	img, format, err := image.Decode(r)
+	if err != nil {
+		return nil, "", 0, err
+	}
+	size := img.Bounds().Size()
+	if err := h.checkImageSize(size.X, size.Y); err != nil {
+		return nil, "", 0, err
+	}
+
+	return preprocess.Orient(img, orientation), format, 1, nil
+}
+
+// gifDecodeBudget bounds the frame pixels decoded to render one GIF frame,
+// as a multiple of the pixel limit. Paletted frames take a byte per pixel,
+// so this is the memory of one RGBA image at the limit.
+const gifDecodeBudget = 4
+
+// decodeGIFFrame renders the requested frame of a GIF as it is displayed:
+// the frames before it are drawn in order on a white canvas the size of
+// the logical screen, honoring their disposal methods, since later frames
+// often only hold the pixels that changed. Only the frames up to the
+// requested one are decoded, and only if their total area stays within
+// gifDecodeBudget times the pixel limit.
+func (h *Handler) decodeGIFFrame(r io.ReadSeeker, frame int) (image.Image, string, int, error) {
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, "", 0, err
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, "", 0, err
+	}
+	layout, err := imageinfo.GIFFrames(data)
+	if err != nil {
+		return nil, "", 0, err
+	}
+	if frame < 0 || frame >= len(layout) {
+		return nil, "", 0, &frameRangeError{frame: frame, frames: len(layout)}
+	}
+	var area int64
+	for _, f := range layout[:frame+1] {
+		area += int64(f.Bounds.Dx()) * int64(f.Bounds.Dy())
+	}
+	if area > gifDecodeBudget*h.maxImagePixels {
+		size := layout[frame].Bounds.Size()
+		return nil, "", 0, &imageTooLargeError{width: size.X, height: size.Y, max: h.maxImagePixels}
+	}
+
+	// Cut the file after the requested frame, the full slice expression
+	// keeps the trailer from overwriting the rest of data
+	end := layout[frame].End
+	g, err := gif.DecodeAll(bytes.NewReader(append(data[:end:end], 0x3B)))
+	if err != nil {
+		return nil, "", 0, err
+	}
+	if len(g.Image) != frame+1 {
+		return nil, "", 0, imageinfo.ErrInvalidGIF
+	}
+
+	bounds := image.Rect(0, 0, g.Config.Width, g.Config.Height)
+	canvas := image.NewRGBA(bounds)
+	draw.Draw(canvas, bounds, image.White, image.Point{}, draw.Src)
+	var saved *image.RGBA
+	for i := 0; i <= frame; i++ {
+		paletted := g.Image[i]
+		var disposal byte
+		if i < len(g.Disposal) {
+			disposal = g.Disposal[i]
+		}
+		if disposal == gif.DisposalPrevious {
+			saved = image.NewRGBA(bounds)
+			copy(saved.Pix, canvas.Pix)
+		}
+
+		draw.Draw(canvas, paletted.Bounds(), paletted, paletted.Bounds().Min, draw.Over)
+		if i == frame {
+			break
+		}
+
+		switch disposal {
+		case gif.DisposalBackground:
+			draw.Draw(canvas, paletted.Bounds(), image.White, image.Point{}, draw.Src)
+		case gif.DisposalPrevious:
+			canvas = saved
+		}
+	}
+	return canvas, "gif", len(layout), nil
+}
+
+// decodeImageConfig parses only the image header and checks its
+// dimensions against the pixel limit
+func (h *Handler) decodeImageConfig(r io.Reader) (image.Config, string, error) {
+	config, format, err := image.DecodeConfig(r)
+	if err != nil {
+		return config, "", err
+	}
+	if err := h.checkImageSize(config.Width, config.Height); err != nil {
+		return config, "", err
+	}
+	return config, format, nil
+}
+
+// respondDecodeError answers 413 for images over the pixel limit and a 400
+// field error for anything that could not be decoded
+func (h *Handler) respondDecodeError(w http.ResponseWriter, err error) {
+	var tooLarge *imageTooLargeError
+	if errors.As(err, &tooLarge) {
+		h.respondError(w, http.StatusRequestEntityTooLarge, "Image is too large")
+		return
+	}
+	var frameRange *frameRangeError
+	if errors.As(err, &frameRange) {
+		h.respondFieldError(w, codeInvalidField, "frame",
+			fmt.Sprintf("frame must be below %d, the number of frames in the image", frameRange.frames))
+		return
+	}
+	h.respondFieldError(w, codeInvalidField, "file", "Invalid image file")
+}
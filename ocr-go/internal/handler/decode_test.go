@@ -0,0 +1,173 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"testing"
+)
+
+// pngBomb returns a PNG whose header claims width x height pixels but which
+// carries no image data
+func pngBomb(width, height uint32) []byte {
+	ihdr := make([]byte, 13)
+	binary.BigEndian.PutUint32(ihdr[0:], width)
+	binary.BigEndian.PutUint32(ihdr[4:], height)
+	ihdr[8] = 8 // bit depth
+	ihdr[9] = 6 // RGBA
+
+	var buf bytes.Buffer
+	buf.WriteString("\x89PNG\r\n\x1a\n")
+	binary.Write(&buf, binary.BigEndian, uint32(len(ihdr)))
+	chunk := append([]byte("IHDR"), ihdr...)
+	buf.Write(chunk)
+	binary.Write(&buf, binary.BigEndian, crc32.ChecksumIEEE(chunk))
+
+	return buf.Bytes()
+}
+
+func TestDecodeImageRejectsHugeHeader(t *testing.T) {
+	h := &Handler{maxImagePixels: defaultMaxImagePixels}
+
+	_, _, err := h.decodeImage(bytes.NewReader(pngBomb(100000, 100000)))
+
+	var tooLarge *imageTooLargeError
+	if !errors.As(err, &tooLarge) {
+		t.Fatalf("err = %v, want an image too large error", err)
+	}
+	if tooLarge.width != 100000 || tooLarge.height != 100000 {
+		t.Errorf("reported %dx%d, want 100000x100000", tooLarge.width, tooLarge.height)
+	}
+}
+
+// portraitPhoto returns a 64x32 JPEG as a phone stores a portrait photo:
+// sideways, with an EXIF orientation of 6. The left half is black, so the
+// top half is black once the photo is upright.
+func portraitPhoto(t *testing.T) []byte {
+	t.Helper()
+
+	img := image.NewGray(image.Rect(0, 0, 64, 32))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(0, 0, 32, 32), image.Black, image.Point{}, draw.Src)
+	var encoded bytes.Buffer
+	if err := jpeg.Encode(&encoded, img, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// Little-endian TIFF with a single IFD entry: orientation (SHORT) = 6
+	tiff := []byte{'I', 'I', 42, 0, 8, 0, 0, 0, 1, 0, 0x12, 0x01, 3, 0, 1, 0, 0, 0, 6, 0, 0, 0, 0, 0, 0, 0}
+	segment := append([]byte("Exif\x00\x00"), tiff...)
+
+	var buf bytes.Buffer
+	buf.Write([]byte{0xFF, 0xD8, 0xFF, 0xE1})
+	binary.Write(&buf, binary.BigEndian, uint16(len(segment)+2))
+	buf.Write(segment)
+	buf.Write(encoded.Bytes()[2:])
+	return buf.Bytes()
+}
+
+func TestDecodeImageAppliesOrientation(t *testing.T) {
+	h := &Handler{maxImagePixels: defaultMaxImagePixels}
+
+	img, format, err := h.decodeImage(bytes.NewReader(portraitPhoto(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if format != "jpeg" {
+		t.Errorf("format = %q, want jpeg", format)
+	}
+	if size := img.Bounds().Size(); size != image.Pt(32, 64) {
+		t.Fatalf("size = %v, want the upright 32x64", size)
+	}
+
+	gray := func(x, y int) uint8 { return color.GrayModel.Convert(img.At(x, y)).(color.Gray).Y }
+	if top, bottom := gray(16, 16), gray(16, 48); top > 64 || bottom < 192 {
+		t.Errorf("top = %d, bottom = %d; want the black half on top", top, bottom)
+	}
+}
+
+// animatedGIF returns a 16x16 GIF whose first frame is black and whose
+// second frame only redraws the left half in white
+func animatedGIF(t *testing.T) []byte {
+	t.Helper()
+
+	palette := color.Palette{color.Black, color.White}
+	first := image.NewPaletted(image.Rect(0, 0, 16, 16), palette)
+	second := image.NewPaletted(image.Rect(0, 0, 8, 16), palette)
+	draw.Draw(second, second.Bounds(), image.White, image.Point{}, draw.Src)
+
+	var buf bytes.Buffer
+	err := gif.EncodeAll(&buf, &gif.GIF{
+		Image:    []*image.Paletted{first, second},
+		Delay:    []int{10, 10},
+		Disposal: []byte{gif.DisposalNone, gif.DisposalNone},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDecodeImageFrameGIF(t *testing.T) {
+	h := &Handler{maxImagePixels: defaultMaxImagePixels}
+	data := animatedGIF(t)
+
+	tests := []struct {
+		frame       int
+		left, right uint8
+	}{
+		{frame: 0, left: 0, right: 0},
+		{frame: 1, left: 255, right: 0},
+	}
+	for _, tt := range tests {
+		img, format, frames, err := h.decodeImageFrame(bytes.NewReader(data), tt.frame)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if format != "gif" || frames != 2 {
+			t.Errorf("frame %d: format = %q, frames = %d; want gif, 2", tt.frame, format, frames)
+		}
+		if size := img.Bounds().Size(); size != image.Pt(16, 16) {
+			t.Errorf("frame %d: size = %v, want the full 16x16 canvas", tt.frame, size)
+		}
+		gray := func(x, y int) uint8 { return color.GrayModel.Convert(img.At(x, y)).(color.Gray).Y }
+		if left, right := gray(4, 8), gray(12, 8); left != tt.left || right != tt.right {
+			t.Errorf("frame %d: left = %d, right = %d; want %d, %d", tt.frame, left, right, tt.left, tt.right)
+		}
+	}
+
+	var frameRange *frameRangeError
+	if _, _, _, err := h.decodeImageFrame(bytes.NewReader(data), 2); !errors.As(err, &frameRange) {
+		t.Errorf("frame 2 error = %v, want a frameRangeError", err)
+	}
+}
+
+func TestDecodeImageFrameGIFBudget(t *testing.T) {
+	// Six full 16x16 frames; at a limit of one frame the budget covers four
+	palette := color.Palette{color.Black, color.White}
+	anim := &gif.GIF{}
+	for i := 0; i < 6; i++ {
+		anim.Image = append(anim.Image, image.NewPaletted(image.Rect(0, 0, 16, 16), palette))
+		anim.Delay = append(anim.Delay, 10)
+	}
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, anim); err != nil {
+		t.Fatal(err)
+	}
+	h := &Handler{maxImagePixels: 16 * 16}
+
+	_, _, frames, err := h.decodeImageFrame(bytes.NewReader(buf.Bytes()), 3)
+	if err != nil || frames != 6 {
+		t.Fatalf("frame 3: frames = %d, err = %v; want 6 frames", frames, err)
+	}
+	var tooLarge *imageTooLargeError
+	if _, _, _, err := h.decodeImageFrame(bytes.NewReader(buf.Bytes()), 4); !errors.As(err, &tooLarge) {
+		t.Errorf("frame 4 error = %v, want an imageTooLargeError", err)
+	}
+}
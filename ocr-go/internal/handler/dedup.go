@@ -0,0 +1,131 @@
+package handler
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/username/ocr-go/internal/model"
+	"github.com/username/ocr-go/internal/storage"
+)
+
+// dedupParams are the request parameters, besides the engine options, that
+// change the extract result and so are part of the dedup key
+var dedupParams = []string{"upscale", "preprocess", "layout", "coords", "correct", "thumbnail", "raw", "group", "frame", "mask", "detect_codes", "sort", "join"}
+
+// dedupKey hashes the image bytes together with everything that affects the
+// result, so the same upload with the same settings maps to the same key
+func dedupKey(r *http.Request, req extractRequest) string {
+	hash := sha256.New()
+	hash.Write(req.data)
+
+	// encoding/json sorts map keys, so the options encode deterministically
+	options, _ := json.Marshal(req.options)
+	hash.Write(options)
+	for _, param := range dedupParams {
+		fmt.Fprintf(hash, "\x00%s=%s", param, r.FormValue(param))
+	}
+	if req.tenant != "" {
+		fmt.Fprintf(hash, "\x00tenant=%s", req.tenant)
+	}
+	if len(req.masks) > 0 {
+		masks, _ := json.Marshal(req.masks)
+		hash.Write(masks)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// contentHashPrefix is how many hex digits of the content hash prefix the
+// result file names, enough to tell uploads apart at a glance
+const contentHashPrefix = 16
+
+// contentHash returns the hex SHA-256 of an upload
+func contentHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// resultFileName names the saved result of an upload: the tenant, if any,
+// namespaces it, the prefix of its content hash groups results of the same
+// file and the ID keeps every run apart
+func resultFileName(tenant, hash, id string) string {
+	return fmt.Sprintf("%socr_%s_%s.json", tenantPrefix(tenant), hash[:contentHashPrefix], id)
+}
+
+// resultIndexName is the hidden storage object naming the result file of
+// an ID, so results are found by ID without listing the storage
+func resultIndexName(id string) string {
+	return fmt.Sprintf(".result_%s.json", id)
+}
+
+// resultIndexEntry is the content of a result index object
+type resultIndexEntry struct {
+	File string `json:"file"`
+}
+
+// saveResult saves a result as name and indexes it under its ID
+func (h *Handler) saveResult(ctx context.Context, id, name string, result interface{}) error {
+	if err := h.saveJSON(ctx, name, result); err != nil {
+		return err
+	}
+	return h.saveJSON(ctx, resultIndexName(id), resultIndexEntry{File: name})
+}
+
+// findResultFile returns the name of the saved result with the given ID,
+// if tenant may see it
+func (h *Handler) findResultFile(ctx context.Context, tenant, id string) (string, error) {
+	var entry resultIndexEntry
+	if err := h.loadJSON(ctx, resultIndexName(id), &entry); err != nil {
+		return "", err
+	}
+	if entry.File == "" || !tenantOwns(tenant, entry.File) {
+		return "", storage.ErrNotFound
+	}
+	return entry.File, nil
+}
+
+// dedupIndexName is the hidden storage object mapping a key to a result ID
+func dedupIndexName(key string) string {
+	return fmt.Sprintf(".dedup_%s.json", key)
+}
+
+// dedupEntry is the content of a dedup index object
+type dedupEntry struct {
+	ID   string `json:"id"`
+	File string `json:"file"`
+}
+
+// lookupResult returns the stored result previously indexed under key
+func (h *Handler) lookupResult(ctx context.Context, key string) (*model.ExtractTextResponse, bool) {
+	var entry dedupEntry
+	if err := h.loadJSON(ctx, dedupIndexName(key), &entry); err != nil {
+		return nil, false
+	}
+
+	var response model.ExtractTextResponse
+	if err := h.loadJSON(ctx, entry.File, &response); err != nil {
+		return nil, false
+	}
+	return &response, true
+}
+
+// indexResult records that key produced the result with the given ID,
+// saved as file
+func (h *Handler) indexResult(ctx context.Context, key, id, file string) {
+	h.saveJSON(ctx, dedupIndexName(key), dedupEntry{ID: id, File: file})
+}
+
+// loadJSON decodes the named storage object into v
+func (h *Handler) loadJSON(ctx context.Context, name string, v interface{}) error {
+	file, _, err := h.storage.Get(ctx, name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return json.NewDecoder(file).Decode(v)
+}
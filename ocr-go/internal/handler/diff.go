@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gofrs/uuid"
+	"github.com/username/ocr-go/internal/model"
+	"github.com/username/ocr-go/internal/storage"
+	"github.com/username/ocr-go/internal/textutil"
+)
+
+// Diff limits. The character error rate is quadratic in the length of
+// the texts, so they are bounded in characters as well as words.
+const (
+	maxDiffBody  = 1 << 20
+	maxDiffWords = 2000
+	maxDiffChars = 10000
+)
+
+// Diff compares an OCR result against a reference transcript word by word
+func (h *Handler) Diff(w http.ResponseWriter, r *http.Request) {
+	var req model.DiffRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxDiffBody)).Decode(&req); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			h.respondError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("Body exceeds %d bytes", maxDiffBody))
+			return
+		}
+		h.respondFieldError(w, codeInvalidField, "body", "Invalid JSON body")
+		return
+	}
+	if err := checkDiffSize(req.Reference); err != nil {
+		h.respondFieldError(w, codeInvalidField, "reference", "reference is "+err.Error())
+		return
+	}
+
+	hypothesis := req.Hypothesis
+	if req.ResultID == "" {
+		if err := checkDiffSize(hypothesis); err != nil {
+			h.respondFieldError(w, codeInvalidField, "hypothesis", "hypothesis is "+err.Error())
+			return
+		}
+	} else {
+		tenant, err := requestTenant(r)
+		if err != nil {
+			h.respondFieldError(w, codeInvalidField, "tenant", err.Error())
+			return
+		}
+		if _, err := uuid.FromString(req.ResultID); err != nil {
+			h.respondFieldError(w, codeInvalidField, "result_id", "Invalid result ID")
+			return
+		}
+		text, err := h.loadResultText(r, tenant, req.ResultID)
+		if errors.Is(err, storage.ErrNotFound) {
+			h.respondError(w, http.StatusNotFound, "Result not found")
+			return
+		}
+		if err != nil {
+			h.respondError(w, http.StatusInternalServerError, "Failed to read result")
+			return
+		}
+
+		// The request is valid, the saved text is just too long to compare
+		if err := checkDiffSize(text); err != nil {
+			message := "hypothesis of the saved result is " + err.Error()
+			h.respondJSON(w, http.StatusUnprocessableEntity, model.ErrorResponse{
+				Error:  message,
+				Code:   codeInvalidField,
+				Fields: map[string]string{"hypothesis": message},
+			})
+			return
+		}
+		hypothesis = text
+	}
+
+	h.respondJSON(w, http.StatusOK, diffTexts(req.Reference, hypothesis))
+}
+
+// checkDiffSize rejects a text too long to compare
+func checkDiffSize(text string) error {
+	if utf8.RuneCountInString(text) > maxDiffChars {
+		return fmt.Errorf("limited to %d characters", maxDiffChars)
+	}
+	if len(strings.Fields(text)) > maxDiffWords {
+		return fmt.Errorf("limited to %d words", maxDiffWords)
+	}
+	return nil
+}
+
+// diffTexts aligns the words of hypothesis against reference and computes
+// the error rates
+func diffTexts(reference, hypothesis string) model.DiffResponse {
+	refWords, hypWords := strings.Fields(reference), strings.Fields(hypothesis)
+
+	response := model.DiffResponse{
+		Edits:           []model.DiffEdit{},
+		ReferenceWords:  len(refWords),
+		HypothesisWords: len(hypWords),
+		CER:             textutil.CER(reference, hypothesis),
+		WER:             textutil.WER(reference, hypothesis),
+	}
+	for _, edit := range textutil.AlignWords(refWords, hypWords) {
+		switch edit.Op {
+		case textutil.OpSubstitute:
+			response.Substitutions++
+		case textutil.OpDelete:
+			response.Deletions++
+		case textutil.OpInsert:
+			response.Insertions++
+		}
+		response.Edits = append(response.Edits, model.DiffEdit{
+			Op:         string(edit.Op),
+			RefIndex:   edit.RefIndex,
+			HypIndex:   edit.HypIndex,
+			Reference:  edit.Reference,
+			Hypothesis: edit.Hypothesis,
+		})
+	}
+	return response
+}
+
+// loadResultText reads the full text of a saved extract result of tenant
+func (h *Handler) loadResultText(r *http.Request, tenant, id string) (string, error) {
+	name, err := h.findResultFile(r.Context(), tenant, id)
+	if err != nil {
+		return "", err
+	}
+	file, _, err := h.storage.Get(r.Context(), name)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	var result struct {
+		FullText string `json:"full_text"`
+	}
+	if err := json.NewDecoder(file).Decode(&result); err != nil {
+		return "", err
+	}
+	return result.FullText, nil
+}
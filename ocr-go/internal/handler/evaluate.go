@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/username/ocr-go/internal/model"
+)
+
+// Evaluate runs OCR on an uploaded image and scores the text against an
+// uploaded ground-truth transcript
+func (h *Handler) Evaluate(w http.ResponseWriter, r *http.Request) {
+	if !h.parseUploadForm(w, r, h.maxUploadSize, 10<<20) {
+		return
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		h.respondFieldError(w, codeMissingField, "file", "No file uploaded")
+		return
+	}
+	defer file.Close()
+
+	groundTruth, err := formText(r, "ground_truth")
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "Failed to read ground truth")
+		return
+	}
+	if strings.TrimSpace(groundTruth) == "" {
+		h.respondFieldError(w, codeMissingField, "ground_truth", "Missing ground_truth")
+		return
+	}
+	if err := checkDiffSize(groundTruth); err != nil {
+		h.respondError(w, http.StatusRequestEntityTooLarge, "Ground truth is "+err.Error())
+		return
+	}
+
+	opts, err := parseOCROptions(r)
+	if err != nil {
+		h.respondOptionsError(w, err)
+		return
+	}
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "Failed to read file")
+		return
+	}
+	img, _, err := h.decodeImage(bytes.NewReader(data))
+	if err != nil {
+		h.respondDecodeError(w, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	if err := h.queueOCR(ctx); err != nil {
+		h.respondOCRError(w, err)
+		return
+	}
+	result, err := h.engine.ExtractWithOptions(ctx, img, opts)
+	h.releaseOCR()
+	if err != nil {
+		h.respondOCRError(w, err)
+		return
+	}
+	sanitizeResult(result)
+
+	// The recognized text is bounded like the ground truth before scoring
+	if err := checkDiffSize(result.FullText); err != nil {
+		h.respondError(w, http.StatusUnprocessableEntity, "Recognized text is too long to score: "+err.Error())
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, model.EvaluateResponse{
+		Filename:       header.Filename,
+		Language:       result.Language,
+		PageSegMode:    opts.PageSegMode,
+		FullText:       result.FullText,
+		MeanConfidence: result.MeanConfidence,
+		DiffResponse:   diffTexts(groundTruth, result.FullText),
+	})
+}
+
+// formText returns a multipart field given either as an uploaded file or
+// as a plain value
+func formText(r *http.Request, field string) (string, error) {
+	file, _, err := r.FormFile(field)
+	if err == http.ErrMissingFile {
+		return r.FormValue(field), nil
+	}
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	return string(data), err
+}
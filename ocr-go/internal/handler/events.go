@@ -0,0 +1,145 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/username/ocr-go/internal/model"
+)
+
+// subscriberBuffer is how many events a slow SSE client may lag behind
+// before further progress events are dropped for it
+const subscriberBuffer = 64
+
+// eventBroker fans out batch job events to SSE subscribers
+type eventBroker struct {
+	mu   sync.Mutex
+	subs map[string]map[chan model.BatchEvent]struct{}
+}
+
+// newEventBroker creates an empty broker
+func newEventBroker() *eventBroker {
+	return &eventBroker{
+		subs: make(map[string]map[chan model.BatchEvent]struct{}),
+	}
+}
+
+// subscribe registers for events of a job. The returned channel is closed
+// when the job finishes; call the returned func to unsubscribe early.
+func (b *eventBroker) subscribe(jobID string) (<-chan model.BatchEvent, func()) {
+	ch := make(chan model.BatchEvent, subscriberBuffer)
+
+	b.mu.Lock()
+	if b.subs[jobID] == nil {
+		b.subs[jobID] = make(map[chan model.BatchEvent]struct{})
+	}
+	b.subs[jobID][ch] = struct{}{}
+	b.mu.Unlock()
+
+	return ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subs[jobID][ch]; ok {
+			delete(b.subs[jobID], ch)
+			close(ch)
+		}
+		// Jobs that are never watched again would otherwise keep an
+		// empty entry for good
+		if len(b.subs[jobID]) == 0 {
+			delete(b.subs, jobID)
+		}
+	}
+}
+
+// publish sends an event to every subscriber of the job without blocking
+func (b *eventBroker) publish(jobID string, event model.BatchEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subs[jobID] {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// finish closes all subscriber channels of the job
+func (b *eventBroker) finish(jobID string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subs[jobID] {
+		close(ch)
+	}
+	delete(b.subs, jobID)
+}
+
+// JobEvents streams async batch job progress as Server-Sent Events
+func (h *Handler) JobEvents(w http.ResponseWriter, r *http.Request) {
+	jobID := chi.URLParam(r, "id")
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		h.respondError(w, http.StatusInternalServerError, "Streaming unsupported")
+		return
+	}
+
+	// Subscribe before reading the status so no event is missed in between
+	events, unsubscribe := h.events.subscribe(jobID)
+	defer unsubscribe()
+
+	job, ok := h.jobStore.Get(jobID)
+	if !ok {
+		h.respondError(w, http.StatusNotFound, "Job not found")
+		return
+	}
+
+	// The stream lasts as long as the job, past the server's write timeout
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+		log.Printf("Failed to lift write deadline for job events: %v", err)
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	writeSSE(w, "status", job)
+	flusher.Flush()
+	if job.FinishedAt != nil {
+		return
+	}
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				// Job finished, send the final status with results
+				if job, ok := h.jobStore.Get(jobID); ok {
+					writeSSE(w, "done", job)
+					flusher.Flush()
+				}
+				return
+			}
+			writeSSE(w, event.Type, event)
+			flusher.Flush()
+		}
+	}
+}
+
+// writeSSE writes a single Server-Sent Event with a JSON payload
+func writeSSE(w http.ResponseWriter, event string, data interface{}) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload)
+}
@@ -0,0 +1,19 @@
+package handler
+
+import "testing"
+
+func TestEventBrokerUnsubscribe(t *testing.T) {
+	b := newEventBroker()
+	_, first := b.subscribe("job")
+	_, second := b.subscribe("job")
+
+	first()
+	if len(b.subs["job"]) != 1 {
+		t.Fatalf("subscribers = %d, want 1", len(b.subs["job"]))
+	}
+	second()
+	second()
+	if _, ok := b.subs["job"]; ok {
+		t.Error("job entry kept after its last subscriber left")
+	}
+}
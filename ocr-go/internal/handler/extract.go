@@ -1,55 +1,379 @@
 package handler
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"image"
 	_ "image/gif"
 	_ "image/jpeg"
 	_ "image/png"
+	"io"
 	"net/http"
-	"os"
-	"path/filepath"
+	"strconv"
 	"time"
 
 	"github.com/gofrs/uuid"
+	"github.com/username/ocr-go/internal/barcode"
+	"github.com/username/ocr-go/internal/imageinfo"
 	"github.com/username/ocr-go/internal/model"
+	"github.com/username/ocr-go/internal/ocr"
+	"github.com/username/ocr-go/internal/preprocess"
+	"github.com/username/ocr-go/internal/textutil"
 )
 
 // ExtractText handles text extraction from uploaded image
 func (h *Handler) ExtractText(w http.ResponseWriter, r *http.Request) {
-	// Parse multipart form (10MB max)
-	if err := r.ParseMultipartForm(10 << 20); err != nil {
-		h.respondError(w, http.StatusBadRequest, "Failed to parse form")
+	format := responseFormat(r)
+	if !validExtractFormat(format) {
+		h.respondFieldError(w, codeInvalidField, "format", "Unsupported format")
+		return
+	}
+
+	if isJSONRequest(r) {
+		h.extractBase64(w, r, format)
+		return
+	}
+	if isImageRequest(r) {
+		h.extractBody(w, r, format)
+		return
+	}
+
+	if !h.parseUploadForm(w, r, h.maxUploadSize, 10<<20) {
 		return
 	}
 
 	// Get uploaded file
 	file, header, err := r.FormFile("file")
 	if err != nil {
-		h.respondError(w, http.StatusBadRequest, "No file uploaded")
+		h.respondFieldError(w, codeMissingField, "file", "No file uploaded")
 		return
 	}
 	defer file.Close()
 
+	data, err := io.ReadAll(file)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "Failed to read file")
+		return
+	}
+
+	opts, err := parseOCROptions(r)
+	if err != nil {
+		h.respondOptionsError(w, err)
+		return
+	}
+
+	h.extractAndRespond(w, r, extractRequest{
+		format:   format,
+		filename: header.Filename,
+		data:     data,
+		options:  opts,
+	})
+}
+
+// extractRequest describes an image to run through the extract flow
+type extractRequest struct {
+	format   string
+	filename string
+	data     []byte
+	options  ocr.Options
+
+	// masks are regions from a JSON body to blank out before OCR; when
+	// nil they are read from the mask parameter
+	masks []model.BBox
+
+	// tenant namespaces the saved result, see requestTenant
+	tenant string
+
+	// sourceFile names an already stored original; when empty the
+	// original is saved under the new result ID
+	sourceFile string
+}
+
+// extractAndRespond runs the extract flow for req, replaying the earlier
+// response when the request repeats a completed Idempotency-Key
+func (h *Handler) extractAndRespond(w http.ResponseWriter, r *http.Request, req extractRequest) {
+	tenant, err := requestTenant(r)
+	if err != nil {
+		h.respondFieldError(w, codeInvalidField, "tenant", err.Error())
+		return
+	}
+	req.tenant = tenant
+
+	// A dry run creates nothing, so there is nothing to replay
+	if r.FormValue("validate_only") == "true" {
+		h.extractResult(w, r, req)
+		return
+	}
+	fingerprint := func() string { return dedupKey(r, req) + req.format }
+	h.withIdempotency(w, r, fingerprint, func(w http.ResponseWriter) {
+		h.extractResult(w, r, req)
+	})
+}
+
+// extractResult decodes the image, runs OCR, saves the original and the
+// result and writes the result in the requested format
+func (h *Handler) extractResult(w http.ResponseWriter, r *http.Request, req extractRequest) {
+	// Return the stored result of an identical earlier request. CSV needs
+	// the raw boxes, which are not stored, and debug images must be
+	// regenerated, so those always run OCR.
+	key := dedupKey(r, req)
+	validateOnly := r.FormValue("validate_only") == "true"
+	requireText := r.FormValue("require_text") == "true"
+	if !validateOnly && r.FormValue("force") != "true" && req.format != formatCSV && r.FormValue("debug_image") != "true" {
+		if cached, ok := h.lookupResult(r.Context(), key); ok {
+			cached.Cached = true
+			// Results stored before text_found existed lack the field
+			cached.TextFound = len(cached.Boxes) > 0
+			if requireText && !cached.TextFound {
+				h.respondNoText(w)
+				return
+			}
+			if req.format == formatText {
+				h.respondText(w, http.StatusOK, cached.FullText)
+			} else {
+				h.respondJSON(w, http.StatusOK, cached)
+			}
+			return
+		}
+	}
+
+	// With raw=true the file goes to the engine undecoded; only its header
+	// is read here, for the size limit and the image dimensions
+	raw := r.FormValue("raw") == "true"
+	if field := rawConflict(r, req.options); raw && field != "" {
+		h.respondFieldError(w, codeInvalidField, field, field+" cannot be combined with raw=true")
+		return
+	}
+
+	masks := req.masks
+	if value := r.FormValue("mask"); value != "" && masks == nil {
+		if err := json.Unmarshal([]byte(value), &masks); err != nil {
+			h.respondFieldError(w, codeInvalidField, "mask", "mask must be a JSON array of {x, y, width, height} regions")
+			return
+		}
+	}
+	if raw && len(masks) > 0 {
+		h.respondFieldError(w, codeInvalidField, "mask", "mask cannot be combined with raw=true")
+		return
+	}
+
+	// Animated GIFs are read frame by frame, frame picks the one to OCR
+	frame := 0
+	if value := r.FormValue("frame"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			h.respondFieldError(w, codeInvalidField, "frame", "frame must be a non-negative integer")
+			return
+		}
+		frame = n
+	}
+
 	// Decode image
-	img, _, err := image.Decode(file)
+	var img image.Image
+	var imageFormat string
+	var size image.Point
+	var frames int
+	if raw {
+		config, format, err := h.decodeImageConfig(bytes.NewReader(req.data))
+		if err != nil {
+			h.respondDecodeError(w, err)
+			return
+		}
+		imageFormat = format
+		size = image.Pt(config.Width, config.Height)
+	} else {
+		decoded, format, count, err := h.decodeImageFrame(bytes.NewReader(req.data), frame)
+		if err != nil {
+			h.respondDecodeError(w, err)
+			return
+		}
+		img, imageFormat = decoded, format
+		if count > 1 {
+			frames = count
+		}
+		size = img.Bounds().Size()
+	}
+
+	coords := r.FormValue("coords")
+	if coords != "" && coords != "absolute" && coords != "normalized" {
+		h.respondFieldError(w, codeInvalidField, "coords", "Unsupported coords")
+		return
+	}
+
+	layout := r.FormValue("layout")
+	if layout != "" && layout != "none" && layout != "columns" {
+		h.respondFieldError(w, codeInvalidField, "layout", "Unsupported layout")
+		return
+	}
+
+	// join=newline is a cheap alternative to the layouts that keeps the
+	// engine order and only breaks lines
+	join := r.FormValue("join")
+	if join != "" && join != "newline" {
+		h.respondFieldError(w, codeInvalidField, "join", "Unsupported join")
+		return
+	}
+	if join != "" && layout == "columns" {
+		h.respondFieldError(w, codeInvalidField, "join", "join does not combine with layout=columns")
+		return
+	}
+
+	group := r.FormValue("group")
+	if group != "" && group != "word" && group != "phrase" {
+		h.respondFieldError(w, codeInvalidField, "group", "Unsupported group")
+		return
+	}
+
+	order := r.FormValue("sort")
+	if order != "" && order != "engine" && order != "reading" {
+		h.respondFieldError(w, codeInvalidField, "sort", "Unsupported sort")
+		return
+	}
+
+	pipeline, err := preprocess.Parse(r.FormValue("preprocess"))
 	if err != nil {
-		h.respondError(w, http.StatusBadRequest, "Invalid image file")
+		h.respondFieldError(w, codeInvalidField, "preprocess", "Invalid preprocess: "+err.Error())
+		return
+	}
+
+	maskRects, err := maskRegions(masks, size)
+	if err != nil {
+		h.respondFieldError(w, codeInvalidField, "mask", err.Error())
+		return
+	}
+
+	// The image and every option passed the checks a real run makes; a
+	// dry run stops before the slow part
+	if validateOnly {
+		h.respondJSON(w, http.StatusOK, model.ImageValidationResponse{
+			Valid:  true,
+			Width:  size.X,
+			Height: size.Y,
+			Format: imageFormat,
+		})
 		return
 	}
+	original := img
+	img = preprocess.Mask(img, maskRects)
+	img = pipeline.Apply(img)
+
+	// Small images are enlarged so strokes are thick enough to recognize
+	scale := 1
+	if r.FormValue("upscale") == "true" {
+		img, scale = preprocess.Upscale(img, h.maxImagePixels)
+	}
+
+	// Keep what the engine will actually see when asked to
+	resultID := uuid.Must(uuid.NewV4()).String()
+	var debugImageURL string
+	if r.FormValue("debug_image") == "true" {
+		debugName := fmt.Sprintf("%sdebug_%s.png", tenantPrefix(req.tenant), resultID)
+		if err := h.savePNG(r.Context(), debugName, img); err == nil {
+			debugImageURL = "/api/results/" + debugName
+		}
+	}
+
+	// Save a preview of the upload, as sent, for results galleries
+	var thumbnailURL string
+	if r.FormValue("thumbnail") == "true" {
+		thumbName := fmt.Sprintf("%sthumb_%s.png", tenantPrefix(req.tenant), resultID)
+		if err := h.savePNG(r.Context(), thumbName, preprocess.Thumbnail(original, h.thumbnailSize)); err == nil {
+			thumbnailURL = "/api/results/" + thumbName
+		}
+	}
+
+	// Fall back to the resolution embedded in the file, ignoring bogus values
+	if req.options.DPI == 0 {
+		if dpi := imageinfo.DPI(req.data); dpi >= ocr.MinDPI && dpi <= ocr.MaxDPI {
+			req.options.DPI = dpi
+		}
+	}
+	if scale > 1 && req.options.DPI != 0 {
+		req.options.DPI = min(req.options.DPI*scale, ocr.MaxDPI)
+	}
 
 	// Extract text with boxes
 	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
 	defer cancel()
 
-	result, err := h.engine.ExtractTextWithBoxes(ctx, img)
+	// Codes are read from the upright upload, before preprocessing can
+	// blur their modules
+	var codes []model.DetectedCode
+	if r.FormValue("detect_codes") == "true" {
+		codes, err = h.detectCodes(ctx, original)
+		if err != nil {
+			h.respondOCRError(w, err)
+			return
+		}
+	}
+
+	start := h.clock.Now()
+	var result *ocr.DetailedResult
+	if raw {
+		result, err = h.recognizeBytes(ctx, req.data)
+	} else {
+		result, err = h.recognize(ctx, img, req.options)
+	}
 	if err != nil {
-		h.respondError(w, http.StatusInternalServerError,
-			fmt.Sprintf("OCR failed: %v", err))
+		h.respondOCRError(w, err)
 		return
 	}
+	h.stats.record(result.Language, result.MeanConfidence, h.clock.Now().Sub(start))
+
+	sanitizeResult(result)
+
+	// Report boxes in the coordinates of the original image
+	if scale > 1 {
+		unscaleBoxes(result.Boxes, scale)
+	}
+
+	// The engine reconstructs lines and paragraphs; "none" keeps the flat
+	// word join and "columns" reads multi-column pages column by column
+	var columns int
+	switch {
+	case join == "newline":
+		result.FullText = ocr.JoinLines(result.Boxes)
+	case layout == "none":
+		result.FullText = ocr.JoinWords(result.Boxes)
+	case layout == "columns":
+		result.FullText, columns = ocr.ColumnLayout(result.Boxes)
+	}
+
+	// Tesseract emits words block by block, which is not always the order
+	// they appear in; overlays drawn in reading order ask for it
+	if order == "reading" {
+		result.Boxes = ocr.SortReadingOrder(result.Boxes)
+	}
+
+	// Optionally correct unreliable words against the language word list
+	var corrected []ocr.TextBox
+	var correctedText string
+	if r.FormValue("correct") == "true" {
+		dict, err := h.dictionary(result.Language)
+		if errors.Is(err, errNoDictionary) {
+			h.respondError(w, http.StatusBadRequest, "No dictionary for language "+result.Language)
+			return
+		}
+		if err != nil {
+			h.respondError(w, http.StatusInternalServerError, "Failed to load dictionary")
+			return
+		}
+		corrected = correctBoxes(result.Boxes, dict)
+		correctedText = layoutText(layout, join, corrected)
+	}
+
+	// Merge adjacent words into phrases such as "Invoice Number". The
+	// grouping only depends on the geometry, so corrected boxes still line
+	// up with the recognized ones.
+	if group == "phrase" {
+		result.Boxes = ocr.GroupPhrases(result.Boxes, ocr.PhraseGap)
+		if corrected != nil {
+			corrected = ocr.GroupPhrases(corrected, ocr.PhraseGap)
+		}
+	}
 
 	// Convert boxes to map format
 	boxes := make([]map[string]interface{}, len(result.Boxes))
@@ -64,27 +388,251 @@ func (h *Handler) ExtractText(w http.ResponseWriter, r *http.Request) {
 				"height": box.Box.Height,
 			},
 		}
+		if len(box.Polygon) > 0 {
+			boxes[i]["polygon"] = box.Polygon
+		}
+		if corrected != nil && corrected[i].Text != box.Text {
+			boxes[i]["corrected"] = corrected[i].Text
+		}
+		if coords == "normalized" {
+			boxes[i]["bbox"] = normalizeBox(box.Box, size)
+			if len(box.Polygon) > 0 {
+				boxes[i]["polygon"] = normalizePolygon(box.Polygon, size)
+			}
+		}
+	}
+
+	// Keep the original so it can be reprocessed later
+	sourceFile := req.sourceFile
+	if sourceFile == "" {
+		sourceFile = h.saveUpload(r.Context(), resultID, imageFormat, req.data)
 	}
 
 	// Build response
+	hash := contentHash(req.data)
 	response := model.ExtractTextResponse{
-		Filename:    header.Filename,
-		FullText:    result.FullText,
-		Boxes:       boxes,
-		TotalLines:  result.TotalLines,
-		ProcessedAt: time.Now(),
+		ID:             resultID,
+		Filename:       req.filename,
+		ContentHash:    hash,
+		ResultFile:     resultFileName(req.tenant, hash, resultID),
+		ImageWidth:     size.X,
+		ImageHeight:    size.Y,
+		Frames:         frames,
+		MaskedRegions:  len(maskRects),
+		Codes:          codes,
+		SourceFile:     sourceFile,
+		Language:       result.Language,
+		FullText:       result.FullText,
+		TextFound:      len(result.Boxes) > 0,
+		CorrectedText:  correctedText,
+		Boxes:          boxes,
+		TotalLines:     result.TotalLines,
+		MeanConfidence: result.MeanConfidence,
+		Histogram:      result.ConfidenceHistogram,
+		DPI:            req.options.DPI,
+		Scale:          scale,
+		DebugImageURL:  debugImageURL,
+		ThumbnailURL:   thumbnailURL,
+		Columns:        columns,
+		AutoDetected:   req.options.Language == ocr.AutoLanguage,
+		ProcessedAt:    h.clock.Now(),
 	}
 
-	// Save result to file
-	resultID := uuid.Must(uuid.NewV4()).String()
-	outputPath := filepath.Join("outputs", fmt.Sprintf("ocr_%s.json", resultID))
+	// Save result to storage
+	if err := h.saveResult(r.Context(), resultID, response.ResultFile, response); err == nil {
+		h.indexResult(r.Context(), key, resultID, response.ResultFile)
+	}
 
-	outputFile, err := os.Create(outputPath)
-	if err == nil {
-		defer outputFile.Close()
-		json.NewEncoder(outputFile).Encode(response)
+	// A blank page is still a successful run; clients that want to treat
+	// it as a failure opt in to a 422
+	if requireText && !response.TextFound {
+		h.respondNoText(w)
+		return
 	}
 
 	// Send response
-	h.respondJSON(w, http.StatusOK, response)
+	switch req.format {
+	case formatText:
+		h.respondText(w, http.StatusOK, result.FullText)
+	case formatCSV:
+		h.respondCSV(w, req.filename, result.Boxes)
+	default:
+		h.respondJSON(w, http.StatusOK, response)
+	}
+}
+
+// recognize runs OCR with opts, probing the candidate languages when the
+// language is "auto". It fails with errOCRBusy when no engine slot frees
+// up in time.
+func (h *Handler) recognize(ctx context.Context, img image.Image, opts ocr.Options) (*ocr.DetailedResult, error) {
+	if err := h.queueOCR(ctx); err != nil {
+		return nil, err
+	}
+	defer h.releaseOCR()
+
+	if opts.Language == ocr.AutoLanguage {
+		return ocr.DetectLanguage(ctx, h.engine, img, opts, h.autoLanguages)
+	}
+	return h.engine.ExtractWithOptions(ctx, img, opts)
+}
+
+// recognizeBytes runs OCR on an undecoded image, holding an engine slot
+// like recognize
+func (h *Handler) recognizeBytes(ctx context.Context, data []byte) (*ocr.DetailedResult, error) {
+	if err := h.queueOCR(ctx); err != nil {
+		return nil, err
+	}
+	defer h.releaseOCR()
+
+	return ocr.ExtractFromBytes(ctx, h.engine, data)
+}
+
+// detectCodes decodes the QR codes and barcodes of img. Decoding scans the
+// whole image, so it holds an engine slot like recognize.
+func (h *Handler) detectCodes(ctx context.Context, img image.Image) ([]model.DetectedCode, error) {
+	if err := h.queueOCR(ctx); err != nil {
+		return nil, err
+	}
+	defer h.releaseOCR()
+
+	found, err := barcode.Detect(ctx, img)
+	if err != nil {
+		return nil, err
+	}
+	var codes []model.DetectedCode
+	for _, code := range found {
+		codes = append(codes, model.DetectedCode{
+			Format: code.Format,
+			Value:  code.Value,
+			BBox: model.BBox{
+				X:      code.Box.Min.X,
+				Y:      code.Box.Min.Y,
+				Width:  code.Box.Dx(),
+				Height: code.Box.Dy(),
+			},
+		})
+	}
+	return codes, nil
+}
+
+// maxMaskRegions bounds the regions blanked out per request
+const maxMaskRegions = 100
+
+// maskRegions checks that every region lies within an image of the given
+// size and returns the regions as rectangles
+func maskRegions(regions []model.BBox, size image.Point) ([]image.Rectangle, error) {
+	if len(regions) > maxMaskRegions {
+		return nil, fmt.Errorf("mask is limited to %d regions", maxMaskRegions)
+	}
+	bounds := image.Rectangle{Max: size}
+	rects := make([]image.Rectangle, len(regions))
+	for i, region := range regions {
+		rect := image.Rect(region.X, region.Y, region.X+region.Width, region.Y+region.Height)
+		if region.Width <= 0 || region.Height <= 0 || !rect.In(bounds) {
+			return nil, fmt.Errorf("mask region %d is empty or outside the %dx%d image", i, size.X, size.Y)
+		}
+		rects[i] = rect
+	}
+	return rects, nil
+}
+
+// rawConflict returns the first request setting that raw=true cannot
+// honor, either because it works on the decoded image or because it is a
+// per-call engine option, or "" if there is none
+func rawConflict(r *http.Request, opts ocr.Options) string {
+	for _, field := range []string{"preprocess", "upscale", "debug_image", "thumbnail", "detect_codes"} {
+		if value := r.FormValue(field); value != "" && value != "false" {
+			return field
+		}
+	}
+	if value := r.FormValue("frame"); value != "" && value != "0" {
+		return "frame"
+	}
+	switch {
+	case opts.Language != "":
+		return "lang"
+	case opts.PageSegMode != 0:
+		return "psm"
+	case opts.Whitelist != "":
+		return "whitelist"
+	case opts.Blacklist != "":
+		return "blacklist"
+	case opts.DPI != 0:
+		return "dpi"
+	case len(opts.Variables) > 0:
+		return "variables"
+	}
+	return ""
+}
+
+// validExtractFormat reports whether format is supported by the extract endpoints
+func validExtractFormat(format string) bool {
+	switch format {
+	case formatJSON, formatText, formatCSV:
+		return true
+	}
+	return false
+}
+
+// sanitizeResult strips control characters and normalizes the Unicode of
+// the full text and every box so the output is safe for JSON consumers
+func sanitizeResult(result *ocr.DetailedResult) {
+	result.FullText = textutil.Sanitize(result.FullText)
+	for i := range result.Boxes {
+		result.Boxes[i].Text = textutil.Sanitize(result.Boxes[i].Text)
+	}
+}
+
+// layoutText joins the text of boxes using the requested layout and join
+func layoutText(layout, join string, boxes []ocr.TextBox) string {
+	if join == "newline" {
+		return ocr.JoinLines(boxes)
+	}
+	switch layout {
+	case "none":
+		return ocr.JoinWords(boxes)
+	case "columns":
+		text, _ := ocr.ColumnLayout(boxes)
+		return text
+	}
+	return ocr.Layout(boxes)
+}
+
+// unscaleBoxes maps boxes found on an image upscaled by factor back to the
+// original image coordinates
+func unscaleBoxes(boxes []ocr.TextBox, factor int) {
+	for i := range boxes {
+		b := &boxes[i].Box
+		b.X /= factor
+		b.Y /= factor
+		b.Width /= factor
+		b.Height /= factor
+		for j := range boxes[i].Polygon {
+			boxes[i].Polygon[j].X /= factor
+			boxes[i].Polygon[j].Y /= factor
+		}
+	}
+}
+
+// normalizeBox expresses box as fractions (0-1) of an image of the given size
+func normalizeBox(box ocr.BoundingBox, size image.Point) map[string]float64 {
+	return map[string]float64{
+		"x":      float64(box.X) / float64(size.X),
+		"y":      float64(box.Y) / float64(size.Y),
+		"width":  float64(box.Width) / float64(size.X),
+		"height": float64(box.Height) / float64(size.Y),
+	}
+}
+
+// normalizePolygon expresses points as fractions (0-1) of an image of the
+// given size
+func normalizePolygon(points []ocr.Point, size image.Point) []map[string]float64 {
+	normalized := make([]map[string]float64, len(points))
+	for i, p := range points {
+		normalized[i] = map[string]float64{
+			"x": float64(p.X) / float64(size.X),
+			"y": float64(p.Y) / float64(size.Y),
+		}
+	}
+	return normalized
 }
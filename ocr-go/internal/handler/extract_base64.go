@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/username/ocr-go/internal/model"
+	"github.com/username/ocr-go/internal/ocr"
+)
+
+// extractBase64 handles text extraction from a base64 image in a JSON body
+func (h *Handler) extractBase64(w http.ResponseWriter, r *http.Request, format string) {
+	// Base64 inflates the payload by 4/3, leave some room for the JSON itself
+	limit := h.maxUploadSize/3*4 + 1<<10
+
+	var req model.ExtractBase64Request
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, limit)).Decode(&req); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			h.respondError(w, http.StatusRequestEntityTooLarge, "Image is too large")
+			return
+		}
+		h.respondError(w, http.StatusBadRequest, "Invalid JSON body")
+		return
+	}
+	if req.ImageBase64 == "" {
+		h.respondFieldError(w, codeMissingField, "image_base64", "Missing image_base64")
+		return
+	}
+	opts, err := parseOCROptions(r)
+	if err != nil {
+		h.respondOptionsError(w, err)
+		return
+	}
+	if req.Lang != "" {
+		if !languagePattern.MatchString(req.Lang) {
+			h.respondFieldError(w, codeInvalidField, "lang", "Unsupported language")
+			return
+		}
+		opts.Language = req.Lang
+	}
+	if len(req.Variables) > 0 {
+		if err := ocr.ValidateVariables(req.Variables); err != nil {
+			h.respondOptionsError(w, &fieldError{"variables", err})
+			return
+		}
+		opts.Variables = req.Variables
+	}
+
+	data, err := decodeBase64Image(req.ImageBase64)
+	if err != nil {
+		h.respondFieldError(w, codeInvalidField, "image_base64", "Invalid base64 image")
+		return
+	}
+	if int64(len(data)) > h.maxUploadSize {
+		h.respondError(w, http.StatusRequestEntityTooLarge, "Image is too large")
+		return
+	}
+
+	filename := req.Filename
+	if filename == "" {
+		filename = "image"
+	}
+	h.extractAndRespond(w, r, extractRequest{
+		format:   format,
+		filename: filename,
+		data:     data,
+		options:  opts,
+		masks:    req.Mask,
+	})
+}
+
+// decodeBase64Image decodes standard base64, tolerating a data URI prefix
+// such as "data:image/png;base64," and missing padding
+func decodeBase64Image(encoded string) ([]byte, error) {
+	if strings.HasPrefix(encoded, "data:") {
+		if i := strings.Index(encoded, ","); i >= 0 {
+			encoded = encoded[i+1:]
+		}
+	}
+	encoded = strings.TrimRight(strings.TrimSpace(encoded), "=")
+
+	return base64.RawStdEncoding.DecodeString(encoded)
+}
+
+// isJSONRequest reports whether the request body is JSON
+func isJSONRequest(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && mediaType == "application/json"
+}
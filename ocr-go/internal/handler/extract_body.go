@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// extractBody handles text extraction from an image sent as the raw
+// request body, e.g. with curl --data-binary
+func (h *Handler) extractBody(w http.ResponseWriter, r *http.Request, format string) {
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, h.maxUploadSize))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			h.respondError(w, http.StatusRequestEntityTooLarge, "Image is too large")
+			return
+		}
+		h.respondError(w, http.StatusBadRequest, "Failed to read body")
+		return
+	}
+	if len(data) == 0 {
+		h.respondFieldError(w, codeMissingField, "body", "Empty request body")
+		return
+	}
+
+	opts, err := parseOCROptions(r)
+	if err != nil {
+		h.respondOptionsError(w, err)
+		return
+	}
+
+	h.extractAndRespond(w, r, extractRequest{
+		format:   format,
+		filename: bodyFilename(r),
+		data:     data,
+		options:  opts,
+	})
+}
+
+// isImageRequest reports whether the request body is an image
+func isImageRequest(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && strings.HasPrefix(mediaType, "image/")
+}
+
+// bodyFilename returns the filename from the Content-Disposition header,
+// or "image" if the client did not send one
+func bodyFilename(r *http.Request) string {
+	_, params, err := mime.ParseMediaType(r.Header.Get("Content-Disposition"))
+	if err == nil && params["filename"] != "" {
+		return params["filename"]
+	}
+	return "image"
+}
@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"path"
+
+	"github.com/username/ocr-go/internal/fetch"
+	"github.com/username/ocr-go/internal/model"
+)
+
+// ExtractFromURL handles text extraction from an image fetched by URL
+func (h *Handler) ExtractFromURL(w http.ResponseWriter, r *http.Request) {
+	format := responseFormat(r)
+	if !validExtractFormat(format) {
+		h.respondFieldError(w, codeInvalidField, "format", "Unsupported format")
+		return
+	}
+
+	var req model.ExtractURLRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "Invalid JSON body")
+		return
+	}
+	if req.URL == "" {
+		h.respondFieldError(w, codeMissingField, "url", "Missing url")
+		return
+	}
+
+	opts, err := parseOCROptions(r)
+	if err != nil {
+		h.respondOptionsError(w, err)
+		return
+	}
+
+	// A URL that cannot be fetched is the client's mistake, only failures
+	// of the remote server are reported as 502
+	u, err := url.Parse(req.URL)
+	if err == nil {
+		err = fetch.ValidateURL(u)
+	}
+	switch {
+	case errors.Is(err, fetch.ErrForbiddenAddress):
+		h.respondFieldError(w, codeInvalidField, "url", "URL points to a forbidden address")
+		return
+	case err != nil:
+		h.respondFieldError(w, codeInvalidField, "url", "url must be an absolute http or https URL")
+		return
+	}
+
+	data, err := h.fetcher.Get(r.Context(), req.URL)
+	switch {
+	case errors.Is(err, fetch.ErrForbiddenAddress):
+		// The host resolved, or redirected, to an internal address
+		h.respondFieldError(w, codeInvalidField, "url", "URL points to a forbidden address")
+		return
+	case errors.Is(err, fetch.ErrTooLarge):
+		h.respondError(w, http.StatusRequestEntityTooLarge, "Image is too large")
+		return
+	case err != nil:
+		log.Printf("Fetching %s: %v", u.Redacted(), err)
+		h.respondError(w, http.StatusBadGateway, "Failed to fetch the image")
+		return
+	}
+
+	h.extractAndRespond(w, r, extractRequest{
+		format:   format,
+		filename: urlFilename(req.URL),
+		data:     data,
+		options:  opts,
+	})
+}
+
+// urlFilename derives a display filename from the last URL path segment
+func urlFilename(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "remote"
+	}
+	name := path.Base(u.Path)
+	if name == "/" || name == "." {
+		return u.Hostname()
+	}
+	return name
+}
@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"encoding/csv"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/username/ocr-go/internal/ocr"
+)
+
+// Response formats supported by the extract endpoint
+const (
+	formatJSON = "json"
+	formatText = "text"
+	formatCSV  = "csv"
+)
+
+// responseFormat picks the response format from ?format= or, failing that,
+// from the most preferred type in the Accept header. JSON is the default.
+func responseFormat(r *http.Request) string {
+	if format := r.URL.Query().Get("format"); format != "" {
+		return strings.ToLower(format)
+	}
+
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accept))
+		if err != nil {
+			continue
+		}
+		switch mediaType {
+		case "text/plain":
+			return formatText
+		case "text/csv":
+			return formatCSV
+		case "application/json", "*/*":
+			return formatJSON
+		}
+	}
+
+	return formatJSON
+}
+
+// respondText sends a plain-text response
+func (h *Handler) respondText(w http.ResponseWriter, status int, text string) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(status)
+	io.WriteString(w, text)
+}
+
+// respondCSV sends the boxes as a CSV attachment named after the upload
+func (h *Handler) respondCSV(w http.ResponseWriter, uploadName string, boxes []ocr.TextBox) {
+	name := strings.TrimSuffix(filepath.Base(uploadName), filepath.Ext(uploadName)) + ".csv"
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition",
+		mime.FormatMediaType("attachment", map[string]string{"filename": name}))
+	w.WriteHeader(http.StatusOK)
+
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"text", "confidence", "x", "y", "width", "height"})
+	for _, box := range boxes {
+		writer.Write([]string{
+			box.Text,
+			strconv.FormatFloat(box.Confidence, 'f', 4, 64),
+			strconv.Itoa(box.Box.X),
+			strconv.Itoa(box.Box.Y),
+			strconv.Itoa(box.Box.Width),
+			strconv.Itoa(box.Box.Height),
+		})
+	}
+	writer.Flush()
+}
@@ -1,27 +1,150 @@
 package handler
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"html/template"
+	"image"
+	"image/png"
+	"log"
 	"net/http"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/username/ocr-go/internal/fetch"
+	"github.com/username/ocr-go/internal/jobstore"
+	"github.com/username/ocr-go/internal/model"
 	"github.com/username/ocr-go/internal/ocr"
+	"github.com/username/ocr-go/internal/storage"
+	"github.com/username/ocr-go/internal/textutil"
+)
+
+// Default request limits
+const (
+	defaultMaxBatchFiles    = 100
+	defaultMaxUploadSize    = 10 << 20
+	defaultBatchFileTimeout = 30 * time.Second
+	defaultMaxImagePixels   = 50_000_000
+	defaultThumbnailSize    = 256
+	defaultStreamMaxFPS     = 5
+	defaultIdempotencyTTL   = 24 * time.Hour
+	defaultIdempotencyBytes = 64 << 20
+	defaultOCRQueueSize     = 64
+	defaultOCRQueueWait     = 5 * time.Second
 )
 
 // Handler contains dependencies for HTTP handlers
 type Handler struct {
-	engine    ocr.Engine
-	templates *template.Template
+	engine      ocr.Engine
+	templates   *template.Template
+	ready       atomic.Bool
+	probeFailed atomic.Bool
+	queue       chan batchJob
+	queueMu     sync.RWMutex
+	queueClosed bool
+	jobsCtx     context.Context
+	cancelJobs  context.CancelFunc
+	jobsDone    chan struct{}
+	jobStore    jobstore.Store
+	events      *eventBroker
+	stats       *statsCollector
+	idempotency *idempotencyStore
+	clock       Clock
+	fetcher     *fetch.Fetcher
+	storage     storage.Storage
+	uploads     storage.Storage
+	ocrSlots    chan struct{}
+	ocrWaiting  atomic.Int64
+	ocrRejected atomic.Int64
+
+	batchConcurrency int
+	maxConcurrentOCR int
+	ocrQueueSize     int
+	ocrQueueWait     time.Duration
+	maxBatchFiles    int
+	maxUploadSize    int64
+	maxImagePixels   int64
+	thumbnailSize    int
+	streamMaxFPS     float64
+	batchFileTimeout time.Duration
+	idempotencyTTL   time.Duration
+	idempotencyBytes int64
+	outputDir        string
+	uploadDir        string
+	autoLanguages    []string
+	dictionaryDir    string
+	tessdataDir      string
+	prettyResults    bool
+	serverConfig     model.ServerConfig
+
+	dictMu       sync.Mutex
+	dictionaries map[string]*textutil.Dictionary
 }
 
 // New creates a new handler with the OCR engine
-func New(engine ocr.Engine) *Handler {
+func New(engine ocr.Engine, opts ...Option) *Handler {
 	tmpl := template.Must(template.ParseGlob("web/templates/*.html"))
 
-	return &Handler{
+	h := &Handler{
 		engine:    engine,
 		templates: tmpl,
+		queue:     make(chan batchJob, jobQueueSize),
+		jobsDone:  make(chan struct{}),
+		events:    newEventBroker(),
+		clock:     realClock{},
+
+		batchConcurrency: runtime.NumCPU(),
+		maxConcurrentOCR: runtime.NumCPU(),
+		ocrQueueSize:     defaultOCRQueueSize,
+		ocrQueueWait:     defaultOCRQueueWait,
+		maxBatchFiles:    defaultMaxBatchFiles,
+		maxUploadSize:    defaultMaxUploadSize,
+		maxImagePixels:   defaultMaxImagePixels,
+		thumbnailSize:    defaultThumbnailSize,
+		streamMaxFPS:     defaultStreamMaxFPS,
+		batchFileTimeout: defaultBatchFileTimeout,
+		idempotencyTTL:   defaultIdempotencyTTL,
+		idempotencyBytes: defaultIdempotencyBytes,
+		outputDir:        "outputs",
+		uploadDir:        "uploads",
+		dictionaryDir:    "dictionaries",
+		dictionaries:     make(map[string]*textutil.Dictionary),
+	}
+	for _, opt := range opts {
+		opt(h)
 	}
+	h.stats = newStatsCollector(h.clock.Now())
+	h.ocrSlots = make(chan struct{}, h.maxConcurrentOCR)
+	h.idempotency = newIdempotencyStore(h.clock, h.idempotencyTTL, h.idempotencyBytes)
+	if h.jobStore == nil {
+		h.jobStore = jobstore.NewMemoryStore(time.Hour)
+	}
+	if len(h.autoLanguages) == 0 {
+		h.autoLanguages = ocr.DefaultAutoLanguages
+	}
+	if h.storage == nil {
+		local, err := storage.NewLocal(h.outputDir)
+		if err != nil {
+			log.Fatalf("Failed to initialize storage: %v", err)
+		}
+		h.storage = local
+	}
+	if h.uploads == nil {
+		uploads, err := storage.NewLocal(h.uploadDir)
+		if err != nil {
+			log.Fatalf("Failed to initialize upload storage: %v", err)
+		}
+		h.uploads = uploads
+	}
+	h.fetcher = fetch.New(15*time.Second, h.maxUploadSize)
+	h.jobsCtx, h.cancelJobs = context.WithCancel(context.Background())
+	go h.runJobs()
+
+	return h
 }
 
 // Index renders the main page
@@ -32,14 +155,6 @@ func (h *Handler) Index(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// Health check endpoint
-func (h *Handler) Health(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{
-		"status": "healthy",
-	})
-}
-
 // respondJSON sends JSON response
 func (h *Handler) respondJSON(w http.ResponseWriter, status int, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
@@ -47,9 +162,96 @@ func (h *Handler) respondJSON(w http.ResponseWriter, status int, data interface{
 	json.NewEncoder(w).Encode(data)
 }
 
+// Validation error codes reported with field errors
+const (
+	codeMissingField = "missing_field"
+	codeInvalidField = "invalid_field"
+
+	// codeNoText is reported with the 422 of require_text=true
+	codeNoText = "no_text"
+)
+
 // respondError sends error response
 func (h *Handler) respondError(w http.ResponseWriter, status int, message string) {
-	h.respondJSON(w, status, map[string]string{
-		"error": message,
+	h.respondJSON(w, status, model.ErrorResponse{
+		Error: message,
 	})
 }
+
+// respondFieldError sends a 400 naming the form field or parameter that
+// failed validation so clients can show it next to that field
+func (h *Handler) respondFieldError(w http.ResponseWriter, code, field, message string) {
+	h.respondJSON(w, http.StatusBadRequest, model.ErrorResponse{
+		Error:  message,
+		Code:   code,
+		Fields: map[string]string{field: message},
+	})
+}
+
+// respondNoText sends the 422 of a require_text=true request whose image
+// holds no recognizable text
+func (h *Handler) respondNoText(w http.ResponseWriter) {
+	h.respondJSON(w, http.StatusUnprocessableEntity, model.ErrorResponse{
+		Error: "No text found in the image",
+		Code:  codeNoText,
+	})
+}
+
+// respondOptionsError sends a 400 for an error from parseOCROptions
+func (h *Handler) respondOptionsError(w http.ResponseWriter, err error) {
+	field := "options"
+	var fe *fieldError
+	if errors.As(err, &fe) {
+		field = fe.field
+	}
+	h.respondFieldError(w, codeInvalidField, field, "Invalid options: "+err.Error())
+}
+
+// saveJSON encodes data as JSON and stores it under name
+func (h *Handler) saveJSON(ctx context.Context, name string, data interface{}) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	if h.prettyResults {
+		enc.SetIndent("", "  ")
+	}
+	if err := enc.Encode(data); err != nil {
+		return err
+	}
+
+	if err := h.storage.Put(ctx, name, &buf); err != nil {
+		log.Printf("Failed to save result %s: %v", name, err)
+		return err
+	}
+	return nil
+}
+
+// pngEncoder reuses its compression buffers across encodes
+var pngEncoder = &png.Encoder{BufferPool: &pngBufferPool{}}
+
+// pngBufferPool implements png.EncoderBufferPool with a sync.Pool
+type pngBufferPool struct {
+	pool sync.Pool
+}
+
+func (p *pngBufferPool) Get() *png.EncoderBuffer {
+	buf, _ := p.pool.Get().(*png.EncoderBuffer)
+	return buf
+}
+
+func (p *pngBufferPool) Put(buf *png.EncoderBuffer) {
+	p.pool.Put(buf)
+}
+
+// savePNG encodes img as PNG and stores it under name
+func (h *Handler) savePNG(ctx context.Context, name string, img image.Image) error {
+	var buf bytes.Buffer
+	if err := pngEncoder.Encode(&buf, img); err != nil {
+		return err
+	}
+
+	if err := h.storage.Put(ctx, name, &buf); err != nil {
+		log.Printf("Failed to save image %s: %v", name, err)
+		return err
+	}
+	return nil
+}
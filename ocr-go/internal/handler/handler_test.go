@@ -0,0 +1,2373 @@
+package handler_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"math"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/gorilla/websocket"
+	"github.com/makiuchi-d/gozxing"
+	"github.com/makiuchi-d/gozxing/qrcode"
+	"github.com/username/ocr-go/internal/handler"
+	"github.com/username/ocr-go/internal/model"
+	"github.com/username/ocr-go/internal/ocr"
+	"github.com/username/ocr-go/internal/storage"
+)
+
+// TestMain runs the tests from the module root so templates can be found
+func TestMain(m *testing.M) {
+	if err := os.Chdir("../.."); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+// uploadFile is a file part of a multipart test request
+type uploadFile struct {
+	field string
+	name  string
+	data  []byte
+}
+
+// newTestServer starts a server routing the API to a handler backed by engine
+func newTestServer(t testing.TB, engine ocr.Engine, opts ...handler.Option) *httptest.Server {
+	t.Helper()
+
+	opts = append([]handler.Option{
+		handler.WithOutputDir(t.TempDir()),
+		handler.WithUploadDir(t.TempDir()),
+	}, opts...)
+	h := handler.New(engine, opts...)
+
+	r := chi.NewRouter()
+	r.Route("/api", func(r chi.Router) {
+		r.Post("/extract", h.ExtractText)
+		r.Put("/extract", h.ExtractText)
+		r.Post("/extract-url", h.ExtractFromURL)
+		r.Post("/reprocess/{id}", h.Reprocess)
+		r.Post("/search", h.SearchText)
+		r.Post("/batch", h.BatchProcess)
+		r.Get("/jobs/{id}", h.GetJob)
+		r.Post("/convert", h.ConvertImage)
+		r.Post("/crops", h.ExportCrops)
+		r.Post("/visualize", h.VisualizeBoxes)
+		r.Post("/table", h.ExtractTable)
+		r.Post("/key-values", h.ExtractKeyValues)
+		r.Post("/diff", h.Diff)
+		r.Post("/evaluate", h.Evaluate)
+		r.Get("/stream", h.LiveOCR)
+		r.Get("/results", h.ListResults)
+		r.Get("/results/usage", h.ResultsUsage)
+		r.Get("/results/{filename}", h.GetResult)
+		r.Get("/openapi.json", h.OpenAPI)
+		r.Get("/config", h.Config)
+		r.Get("/stats", h.Stats)
+	})
+	r.Get("/metrics", h.Metrics)
+	r.Post("/admin/languages", h.UploadLanguage)
+
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+// testEngine returns a fake engine with two recognized words
+func testEngine() *ocr.FakeEngine {
+	return ocr.NewFakeEngine(
+		ocr.TextBox{Text: "Hello", Confidence: 0.9, Box: ocr.BoundingBox{X: 1, Y: 2, Width: 30, Height: 10}},
+		ocr.TextBox{Text: "World", Confidence: 0.8, Box: ocr.BoundingBox{X: 40, Y: 2, Width: 30, Height: 10}},
+	)
+}
+
+// pngImage encodes a small white PNG
+func pngImage(t testing.TB) []byte {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			img.Set(x, y, color.White)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// postMultipart sends the files as a multipart form to url
+func postMultipart(t *testing.T, url string, files ...uploadFile) *http.Response {
+	t.Helper()
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for _, f := range files {
+		part, err := writer.CreateFormFile(f.field, f.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		part.Write(f.data)
+	}
+	writer.Close()
+
+	resp, err := http.Post(url, writer.FormDataContentType(), &body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+
+	return resp
+}
+
+// fetchZip downloads the ZIP archive at url and returns its entries by name
+func fetchZip(t *testing.T, url string) map[string]*zip.File {
+	t.Helper()
+
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := make(map[string]*zip.File)
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+	return files
+}
+
+// decodeJSON decodes the response body into v
+func decodeJSON(t *testing.T, resp *http.Response, v interface{}) {
+	t.Helper()
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+}
+
+func TestExtractText(t *testing.T) {
+	srv := newTestServer(t, testEngine())
+
+	resp := postMultipart(t, srv.URL+"/api/extract",
+		uploadFile{field: "file", name: "scan.png", data: pngImage(t)})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+
+	var got struct {
+		Filename       string  `json:"filename"`
+		FullText       string  `json:"full_text"`
+		TotalLines     int     `json:"total_lines"`
+		MeanConfidence float64 `json:"mean_confidence"`
+		Boxes          []struct {
+			Text       string         `json:"text"`
+			Confidence float64        `json:"confidence"`
+			BBox       map[string]int `json:"bbox"`
+		} `json:"boxes"`
+	}
+	decodeJSON(t, resp, &got)
+
+	if got.Filename != "scan.png" {
+		t.Errorf("filename = %q, want %q", got.Filename, "scan.png")
+	}
+	if got.FullText != "Hello World" {
+		t.Errorf("full_text = %q, want %q", got.FullText, "Hello World")
+	}
+	if got.TotalLines != 2 || len(got.Boxes) != 2 {
+		t.Fatalf("total_lines = %d, boxes = %d, want 2", got.TotalLines, len(got.Boxes))
+	}
+	if got.MeanConfidence <= 0.8 || got.MeanConfidence >= 0.9 {
+		t.Errorf("mean_confidence = %v, want between 0.8 and 0.9", got.MeanConfidence)
+	}
+
+	box := got.Boxes[1]
+	if box.Text != "World" || box.Confidence != 0.8 {
+		t.Errorf("box = %+v, want World at 0.8", box)
+	}
+	for key, want := range map[string]int{"x": 40, "y": 2, "width": 30, "height": 10} {
+		if box.BBox[key] != want {
+			t.Errorf("bbox[%s] = %d, want %d", key, box.BBox[key], want)
+		}
+	}
+}
+
+func TestExtractTextPlain(t *testing.T) {
+	engine := testEngine()
+	srv := newTestServer(t, engine)
+
+	// The first request runs OCR, the second is served from the stored
+	// result and picks the format from Accept instead
+	first := postMultipart(t, srv.URL+"/api/extract?format=text",
+		uploadFile{field: "file", name: "scan.png", data: pngImage(t)})
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("file", "scan.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(pngImage(t))
+	writer.Close()
+	req, err := http.NewRequest(http.MethodPost, srv.URL+"/api/extract", &body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.Header.Set("Accept", "text/plain, application/json;q=0.5")
+	second, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer second.Body.Close()
+
+	for name, resp := range map[string]*http.Response{"format=text": first, "Accept": second} {
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("%s: status = %d, want %d", name, resp.StatusCode, http.StatusOK)
+		}
+		if ct := resp.Header.Get("Content-Type"); ct != "text/plain; charset=utf-8" {
+			t.Errorf("%s: Content-Type = %q, want text/plain", name, ct)
+		}
+		text, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(text) != "Hello World" {
+			t.Errorf("%s: body = %q, want %q", name, text, "Hello World")
+		}
+	}
+	if engine.Calls() != 1 {
+		t.Errorf("engine called %d times, want 1", engine.Calls())
+	}
+}
+
+func TestExtractTextCSV(t *testing.T) {
+	engine := ocr.NewFakeEngine(
+		ocr.TextBox{Text: "Total:", Confidence: 0.9, Box: ocr.BoundingBox{X: 1, Y: 2, Width: 30, Height: 10}},
+		ocr.TextBox{Text: "1,250.00", Confidence: 0.875, Box: ocr.BoundingBox{X: 40, Y: 2, Width: 50, Height: 10}},
+		ocr.TextBox{Text: `"Paid"`, Confidence: 0.5, Box: ocr.BoundingBox{X: 100, Y: 2, Width: 30, Height: 10}},
+	)
+	srv := newTestServer(t, engine)
+
+	resp := postMultipart(t, srv.URL+"/api/extract?format=csv",
+		uploadFile{field: "file", name: "receipt.png", data: pngImage(t)})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "text/csv; charset=utf-8" {
+		t.Errorf("Content-Type = %q, want text/csv", ct)
+	}
+	_, params, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition"))
+	if err != nil || params["filename"] != "receipt.csv" {
+		t.Errorf("Content-Disposition = %q, want receipt.csv", resp.Header.Get("Content-Disposition"))
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "text,confidence,x,y,width,height\n" +
+		"Total:,0.9000,1,2,30,10\n" +
+		"\"1,250.00\",0.8750,40,2,50,10\n" +
+		"\"\"\"Paid\"\"\",0.5000,100,2,30,10\n"
+	if string(body) != want {
+		t.Errorf("body =\n%s\nwant\n%s", body, want)
+	}
+}
+
+func TestExtractTextBase64(t *testing.T) {
+	engine := testEngine()
+	srv := newTestServer(t, engine, handler.WithMaxUploadSize(1<<10))
+	encoded := base64.StdEncoding.EncodeToString(pngImage(t))
+
+	post := func(body string) *http.Response {
+		t.Helper()
+		resp, err := http.Post(srv.URL+"/api/extract", "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+
+	// Data URIs and unpadded base64 are accepted as well
+	for _, payload := range []string{encoded, "data:image/png;base64," + encoded, strings.TrimRight(encoded, "=")} {
+		resp := post(`{"image_base64":"` + payload + `","filename":"scan.png","lang":"spa"}`)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+		}
+		var got model.ExtractTextResponse
+		decodeJSON(t, resp, &got)
+		if got.FullText != "Hello World" || got.Filename != "scan.png" || got.Language != "spa" {
+			t.Errorf("result = %+v, want Hello World in spa from scan.png", got)
+		}
+	}
+
+	for _, tc := range []struct {
+		name  string
+		body  string
+		field string
+	}{
+		{"missing", `{}`, "image_base64"},
+		{"invalid", `{"image_base64":"not base64!"}`, "image_base64"},
+		{"language", `{"image_base64":"` + encoded + `","lang":"../eng"}`, "lang"},
+	} {
+		resp := post(tc.body)
+		var got model.ErrorResponse
+		decodeJSON(t, resp, &got)
+		if resp.StatusCode != http.StatusBadRequest || got.Fields[tc.field] == "" {
+			t.Errorf("%s: status = %d, error = %+v; want 400 on %s", tc.name, resp.StatusCode, got, tc.field)
+		}
+	}
+
+	// An image over the upload limit, whether the body still fits the
+	// base64 allowance or not
+	for _, size := range []int{1<<10 + 1, 4 << 10} {
+		payload := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{0}, size))
+		if resp := post(`{"image_base64":"` + payload + `"}`); resp.StatusCode != http.StatusRequestEntityTooLarge {
+			t.Errorf("%d bytes: status = %d, want %d", size, resp.StatusCode, http.StatusRequestEntityTooLarge)
+		}
+	}
+	// Repeats of the valid image are served from the stored result and
+	// rejected requests never reach the engine
+	if engine.Calls() != 1 {
+		t.Errorf("engine called %d times, want 1", engine.Calls())
+	}
+}
+
+func TestExtractTextNormalizedCoords(t *testing.T) {
+	engine := ocr.NewFakeEngine(
+		ocr.TextBox{Text: "Hi", Confidence: 0.9, Box: ocr.BoundingBox{X: 2, Y: 4, Width: 4, Height: 2}},
+	)
+	srv := newTestServer(t, engine)
+
+	resp := postMultipart(t, srv.URL+"/api/extract?coords=normalized",
+		uploadFile{field: "file", name: "scan.png", data: pngImage(t)})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+
+	var got struct {
+		ImageWidth  int `json:"image_width"`
+		ImageHeight int `json:"image_height"`
+		Boxes       []struct {
+			BBox map[string]float64 `json:"bbox"`
+		} `json:"boxes"`
+	}
+	decodeJSON(t, resp, &got)
+
+	if got.ImageWidth != 8 || got.ImageHeight != 8 {
+		t.Errorf("image size = %dx%d, want 8x8", got.ImageWidth, got.ImageHeight)
+	}
+	want := map[string]float64{"x": 0.25, "y": 0.5, "width": 0.5, "height": 0.25}
+	for key, value := range want {
+		if got.Boxes[0].BBox[key] != value {
+			t.Errorf("bbox %s = %v, want %v", key, got.Boxes[0].BBox[key], value)
+		}
+	}
+}
+
+func TestExtractTextPolygons(t *testing.T) {
+	engine := ocr.NewFakeEngine(ocr.TextBox{
+		Text: "Hi", Confidence: 0.9,
+		Box:     ocr.BoundingBox{X: 0, Y: 0, Width: 8, Height: 4},
+		Polygon: []ocr.Point{{X: 0, Y: 0}, {X: 8, Y: 2}, {X: 8, Y: 4}, {X: 0, Y: 2}},
+	})
+	srv := newTestServer(t, engine)
+
+	for _, tc := range []struct {
+		query string
+		want  []map[string]float64
+	}{
+		{"", []map[string]float64{{"x": 0, "y": 0}, {"x": 8, "y": 2}, {"x": 8, "y": 4}, {"x": 0, "y": 2}}},
+		{"&coords=normalized", []map[string]float64{{"x": 0, "y": 0}, {"x": 1, "y": 0.25}, {"x": 1, "y": 0.5}, {"x": 0, "y": 0.25}}},
+	} {
+		resp := postMultipart(t, srv.URL+"/api/extract?polygons=true"+tc.query,
+			uploadFile{field: "file", name: "scan.png", data: pngImage(t)})
+		var got struct {
+			Boxes []struct {
+				Polygon []map[string]float64 `json:"polygon"`
+			} `json:"boxes"`
+		}
+		decodeJSON(t, resp, &got)
+		if len(got.Boxes) != 1 || !reflect.DeepEqual(got.Boxes[0].Polygon, tc.want) {
+			t.Errorf("%q: boxes = %+v, want polygon %v", tc.query, got.Boxes, tc.want)
+		}
+	}
+}
+
+func TestExtractTextMissingFile(t *testing.T) {
+	srv := newTestServer(t, testEngine())
+
+	resp := postMultipart(t, srv.URL+"/api/extract",
+		uploadFile{field: "other", name: "scan.png", data: pngImage(t)})
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusBadRequest)
+	}
+
+	var got model.ErrorResponse
+	decodeJSON(t, resp, &got)
+	if got.Error == "" {
+		t.Error("expected an error message")
+	}
+	if got.Code != "missing_field" || got.Fields["file"] == "" {
+		t.Errorf("code = %q, fields = %v; want missing_field for file", got.Code, got.Fields)
+	}
+}
+
+func TestExtractTextInvalidOption(t *testing.T) {
+	srv := newTestServer(t, testEngine())
+
+	resp := postMultipart(t, srv.URL+"/api/extract?psm=42",
+		uploadFile{field: "file", name: "scan.png", data: pngImage(t)})
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusBadRequest)
+	}
+
+	var got model.ErrorResponse
+	decodeJSON(t, resp, &got)
+	if got.Code != "invalid_field" || got.Fields["psm"] == "" {
+		t.Errorf("code = %q, fields = %v; want invalid_field for psm", got.Code, got.Fields)
+	}
+}
+
+func TestExtractTextInvalidImage(t *testing.T) {
+	engine := testEngine()
+	srv := newTestServer(t, engine)
+
+	resp := postMultipart(t, srv.URL+"/api/extract",
+		uploadFile{field: "file", name: "scan.png", data: []byte("not an image")})
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusBadRequest)
+	}
+	if engine.Calls() != 0 {
+		t.Errorf("engine called %d times for an invalid image", engine.Calls())
+	}
+}
+
+func TestBatchProcess(t *testing.T) {
+	srv := newTestServer(t, testEngine())
+
+	resp := postMultipart(t, srv.URL+"/api/batch",
+		uploadFile{field: "files", name: "a.png", data: pngImage(t)},
+		uploadFile{field: "files", name: "broken.png", data: []byte("garbage")},
+		uploadFile{field: "files", name: "b.png", data: pngImage(t)},
+	)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+
+	var got model.BatchProcessResponse
+	decodeJSON(t, resp, &got)
+
+	if got.TotalFiles != 3 || got.SuccessCount != 2 || got.FailureCount != 1 {
+		t.Fatalf("total/success/failure = %d/%d/%d, want 3/2/1",
+			got.TotalFiles, got.SuccessCount, got.FailureCount)
+	}
+	for i, name := range []string{"a.png", "broken.png", "b.png"} {
+		if got.Results[i].Index != i || got.Results[i].Filename != name {
+			t.Errorf("results[%d] = %d %q, want %d %q",
+				i, got.Results[i].Index, got.Results[i].Filename, i, name)
+		}
+	}
+	if got.Results[1].Success || got.Results[1].Error == "" {
+		t.Errorf("broken.png should fail with an error, got %+v", got.Results[1])
+	}
+}
+
+func TestBatchProcessZip(t *testing.T) {
+	srv := newTestServer(t, testEngine())
+
+	resp := postMultipart(t, srv.URL+"/api/batch?zip=true&annotate=true",
+		uploadFile{field: "files", name: "a.png", data: pngImage(t)},
+		uploadFile{field: "files", name: "broken.png", data: []byte("garbage")},
+		uploadFile{field: "files", name: "b.png", data: pngImage(t)},
+	)
+	var got model.BatchProcessResponse
+	decodeJSON(t, resp, &got)
+	if got.ArchiveFile == "" || got.DownloadURL != "/api/results/"+got.ArchiveFile {
+		t.Fatalf("archive_file = %q, download_url = %q", got.ArchiveFile, got.DownloadURL)
+	}
+
+	files := fetchZip(t, srv.URL+got.DownloadURL)
+	if len(files) != 4 {
+		t.Errorf("archive has %d entries, want 4", len(files))
+	}
+	for _, result := range got.Results {
+		if !result.Success {
+			continue
+		}
+		for _, name := range []string{result.OutputFile, result.AnnotatedFile} {
+			if _, ok := files[name]; name == "" || !ok {
+				t.Errorf("archive is missing %q for %s", name, result.Filename)
+			}
+		}
+	}
+}
+
+func TestBatchProcessNoFiles(t *testing.T) {
+	srv := newTestServer(t, testEngine())
+
+	resp := postMultipart(t, srv.URL+"/api/batch",
+		uploadFile{field: "other", name: "a.png", data: pngImage(t)})
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusBadRequest)
+	}
+}
+
+// fixedClock is a Clock frozen at a single instant
+type fixedClock struct {
+	now time.Time
+}
+
+// Now returns the frozen instant
+func (c fixedClock) Now() time.Time {
+	return c.now
+}
+
+func TestExtractTextUsesClock(t *testing.T) {
+	frozen := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	srv := newTestServer(t, testEngine(), handler.WithClock(fixedClock{now: frozen}))
+
+	resp := postMultipart(t, srv.URL+"/api/extract",
+		uploadFile{field: "file", name: "scan.png", data: pngImage(t)})
+
+	var got model.ExtractTextResponse
+	decodeJSON(t, resp, &got)
+	if !got.ProcessedAt.Equal(frozen) {
+		t.Errorf("processed_at = %v, want %v", got.ProcessedAt, frozen)
+	}
+}
+
+func TestBatchProcessUsesClock(t *testing.T) {
+	frozen := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	srv := newTestServer(t, testEngine(), handler.WithClock(fixedClock{now: frozen}))
+
+	resp := postMultipart(t, srv.URL+"/api/batch",
+		uploadFile{field: "files", name: "a.png", data: pngImage(t)})
+
+	var got model.BatchProcessResponse
+	decodeJSON(t, resp, &got)
+	if got.ProcessingTime != "0s" {
+		t.Errorf("processing_time = %q, want %q", got.ProcessingTime, "0s")
+	}
+}
+
+func TestExtractTextContentHash(t *testing.T) {
+	srv := newTestServer(t, testEngine())
+	data := pngImage(t)
+
+	var got model.ExtractTextResponse
+	decodeJSON(t, postMultipart(t, srv.URL+"/api/extract",
+		uploadFile{field: "file", name: "scan.png", data: data}), &got)
+
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	if got.ContentHash != hash {
+		t.Errorf("content_hash = %q, want %q", got.ContentHash, hash)
+	}
+	if want := "ocr_" + hash[:16] + "_" + got.ID + ".json"; got.ResultFile != want {
+		t.Errorf("result_file = %q, want %q", got.ResultFile, want)
+	}
+
+	// Results are still found by ID alone
+	body := strings.NewReader(`{"reference":"Hello World","result_id":"` + got.ID + `"}`)
+	resp, err := http.Post(srv.URL+"/api/diff", "application/json", body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var diff model.DiffResponse
+	decodeJSON(t, resp, &diff)
+	if resp.StatusCode != http.StatusOK || diff.HypothesisWords != 2 || diff.WER != 0 {
+		t.Errorf("diff by ID = %d, %+v; want the saved text to match", resp.StatusCode, diff)
+	}
+}
+
+func TestExtractTextTenant(t *testing.T) {
+	srv := newTestServer(t, testEngine())
+	data := pngImage(t)
+
+	var got model.ExtractTextResponse
+	decodeJSON(t, postMultipart(t, srv.URL+"/api/extract?tenant=acme",
+		uploadFile{field: "file", name: "scan.png", data: data}), &got)
+	sum := sha256.Sum256(data)
+	if want := "t-acme_ocr_" + hex.EncodeToString(sum[:])[:16] + "_" + got.ID + ".json"; got.ResultFile != want {
+		t.Errorf("result_file = %q, want %q", got.ResultFile, want)
+	}
+	if got.Cached {
+		t.Error("first upload of a tenant was served from cache")
+	}
+	postMultipart(t, srv.URL+"/api/extract?force=true",
+		uploadFile{field: "file", name: "scan.png", data: data})
+
+	resp, err := http.Get(srv.URL + "/api/results?tenant=acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var list model.ListResultsResponse
+	decodeJSON(t, resp, &list)
+	if list.Count != 1 || list.Files[0].Name != got.ResultFile {
+		t.Errorf("results of acme = %+v, want only %q", list.Files, got.ResultFile)
+	}
+
+	// Other tenants and untenanted requests cannot reach the result, even
+	// a tenant named like the untenanted result files
+	for _, query := range []string{"", "?tenant=ocr", "?tenant=other"} {
+		resp, err := http.Get(srv.URL + "/api/results/" + got.ResultFile + query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Errorf("download%s: status = %d, want %d", query, resp.StatusCode, http.StatusNotFound)
+		}
+
+		resp, err = http.Get(srv.URL + "/api/results" + query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var list model.ListResultsResponse
+		decodeJSON(t, resp, &list)
+		resp.Body.Close()
+		for _, file := range list.Files {
+			if file.Name == got.ResultFile {
+				t.Errorf("listing%s includes the result of acme", query)
+			}
+		}
+
+		body := strings.NewReader(`{"reference":"Hello World","result_id":"` + got.ID + `"}`)
+		resp, err = http.Post(srv.URL+"/api/diff"+query, "application/json", body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Errorf("diff%s: status = %d, want %d", query, resp.StatusCode, http.StatusNotFound)
+		}
+	}
+	download, err := http.Get(srv.URL + "/api/results/" + got.ResultFile + "?tenant=acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	download.Body.Close()
+	if download.StatusCode != http.StatusOK {
+		t.Errorf("download by acme: status = %d, want %d", download.StatusCode, http.StatusOK)
+	}
+
+	resp = postMultipart(t, srv.URL+"/api/extract?tenant=../etc",
+		uploadFile{field: "file", name: "scan.png", data: data})
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("invalid tenant: status = %d, want %d", resp.StatusCode, http.StatusBadRequest)
+	}
+}
+
+func TestSearchText(t *testing.T) {
+	srv := newTestServer(t, testEngine())
+
+	for _, tc := range []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{"plain", "q=hell", []string{"Hello"}},
+		{"case", "q=WORLD", []string{"World"}},
+		{"regex", "q=" + url.QueryEscape("^(hello|world)$") + "&regex=true", []string{"Hello", "World"}},
+		{"no match", "q=invoice", []string{}},
+	} {
+		resp := postMultipart(t, srv.URL+"/api/search?"+tc.query,
+			uploadFile{field: "file", name: "scan.png", data: pngImage(t)})
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("%s: status = %d, want %d", tc.name, resp.StatusCode, http.StatusOK)
+		}
+		var got model.SearchResponse
+		decodeJSON(t, resp, &got)
+		resp.Body.Close()
+
+		// No match is an empty list, never null
+		if got.Matches == nil {
+			t.Fatalf("%s: matches = null, want a list", tc.name)
+		}
+		texts := make([]string, 0, len(got.Matches))
+		for _, m := range got.Matches {
+			texts = append(texts, m.Text)
+		}
+		if !reflect.DeepEqual(texts, tc.want) {
+			t.Errorf("%s: matches = %v, want %v", tc.name, texts, tc.want)
+		}
+		if got.TotalMatches != len(tc.want) {
+			t.Errorf("%s: total_matches = %d, want %d", tc.name, got.TotalMatches, len(tc.want))
+		}
+	}
+
+	for _, query := range []string{"", "q=" + url.QueryEscape("(") + "&regex=true"} {
+		resp := postMultipart(t, srv.URL+"/api/search?"+query,
+			uploadFile{field: "file", name: "scan.png", data: pngImage(t)})
+		var got model.ErrorResponse
+		decodeJSON(t, resp, &got)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest || got.Fields["q"] == "" {
+			t.Errorf("query %q: status = %d, error = %+v; want 400 on q", query, resp.StatusCode, got)
+		}
+	}
+}
+
+func TestTenantPrefixesEveryFile(t *testing.T) {
+	srv := newTestServer(t, testEngine())
+	upload := uploadFile{field: "file", name: "scan.png", data: pngImage(t)}
+
+	var extracted model.ExtractTextResponse
+	decodeJSON(t, postMultipart(t, srv.URL+"/api/extract?tenant=acme&thumbnail=true&debug_image=true", upload), &extracted)
+	var visualized model.VisualizeResponse
+	decodeJSON(t, postMultipart(t, srv.URL+"/api/visualize?tenant=acme&format=jpeg", upload), &visualized)
+	var crops model.CropExportResponse
+	decodeJSON(t, postMultipart(t, srv.URL+"/api/crops?tenant=acme", upload), &crops)
+	var batch model.BatchProcessResponse
+	decodeJSON(t, postMultipart(t, srv.URL+"/api/batch?tenant=acme&zip=true&annotate=true",
+		uploadFile{field: "files", name: "scan.png", data: upload.data}), &batch)
+	if len(batch.Results) != 1 {
+		t.Fatalf("batch results = %+v, want one", batch.Results)
+	}
+
+	for _, name := range []string{
+		path.Base(extracted.ThumbnailURL),
+		path.Base(extracted.DebugImageURL),
+		visualized.OutputFile,
+		crops.OutputFile,
+		batch.ArchiveFile,
+		batch.Results[0].AnnotatedFile,
+	} {
+		if !strings.HasPrefix(name, "t-acme_") {
+			t.Errorf("saved %q without the tenant prefix", name)
+			continue
+		}
+		resp, err := http.Get(srv.URL + "/api/results/" + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Errorf("download of %s without a tenant: status = %d, want %d", name, resp.StatusCode, http.StatusNotFound)
+		}
+	}
+
+	resp := postMultipart(t, srv.URL+"/api/crops?tenant=a_b", upload)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("invalid tenant: status = %d, want %d", resp.StatusCode, http.StatusBadRequest)
+	}
+}
+
+// listCounter counts the List calls made on a storage
+type listCounter struct {
+	storage.Storage
+	lists atomic.Int32
+}
+
+func (s *listCounter) List(ctx context.Context) ([]storage.Object, error) {
+	s.lists.Add(1)
+	return s.Storage.List(ctx)
+}
+
+func TestReprocess(t *testing.T) {
+	local, err := storage.NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := &listCounter{Storage: local}
+	engine := testEngine()
+	srv := newTestServer(t, engine, handler.WithStorage(store))
+
+	var first model.ExtractTextResponse
+	decodeJSON(t, postMultipart(t, srv.URL+"/api/extract",
+		uploadFile{field: "file", name: "scan.png", data: pngImage(t)}), &first)
+
+	reprocess := func(id, query string) (*http.Response, model.ExtractTextResponse) {
+		resp, err := http.Post(srv.URL+"/api/reprocess/"+id+query, "", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var got model.ExtractTextResponse
+		if resp.StatusCode == http.StatusOK {
+			decodeJSON(t, resp, &got)
+		}
+		return resp, got
+	}
+
+	// A reprocessed result can itself be reprocessed, both share the
+	// original of the first upload
+	id := first.ID
+	for _, lang := range []string{"spa", "deu"} {
+		resp, got := reprocess(id, "?lang="+lang)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("reprocess %s: status = %d, want %d", id, resp.StatusCode, http.StatusOK)
+		}
+		if got.ID == id || got.SourceFile != first.SourceFile || got.Filename != "scan.png" || got.Language != lang {
+			t.Errorf("reprocess %s = %+v, want a new %s result of %s", id, got, lang, first.SourceFile)
+		}
+		id = got.ID
+	}
+	if opts := engine.Options(); len(opts) != 3 || opts[2].Language != "deu" {
+		t.Errorf("engine options = %+v, want the last call in deu", opts)
+	}
+
+	for _, tc := range []struct {
+		id   string
+		want int
+	}{
+		{"not-a-uuid", http.StatusBadRequest},
+		{"6ba7b810-9dad-11d1-80b4-00c04fd430c8", http.StatusNotFound},
+	} {
+		if resp, _ := reprocess(tc.id, ""); resp.StatusCode != tc.want {
+			t.Errorf("reprocess %s: status = %d, want %d", tc.id, resp.StatusCode, tc.want)
+		}
+	}
+
+	// Results are found through their index, not by listing the storage
+	if n := store.lists.Load(); n != 0 {
+		t.Errorf("storage listed %d times, want 0", n)
+	}
+}
+
+func TestListResultsFilter(t *testing.T) {
+	srv := newTestServer(t, testEngine())
+	var extracted model.ExtractTextResponse
+	decodeJSON(t, postMultipart(t, srv.URL+"/api/extract",
+		uploadFile{field: "file", name: "scan.png", data: pngImage(t)}), &extracted)
+
+	hour := time.Hour
+	past, future := time.Now().Add(-hour).Format(time.RFC3339), time.Now().Add(hour).Format(time.RFC3339)
+	for _, tc := range []struct {
+		query string
+		want  int
+	}{
+		{"", 1},
+		{"?type=json", 1},
+		{"?type=png", 0},
+		{"?type=jpeg", 0},
+		{"?type=zip", 0},
+		{"?since=" + past + "&until=" + future, 1},
+		{"?since=" + future, 0},
+		{"?until=" + past, 0},
+	} {
+		resp, err := http.Get(srv.URL + "/api/results" + strings.ReplaceAll(tc.query, "+", "%2B"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var list model.ListResultsResponse
+		decodeJSON(t, resp, &list)
+		resp.Body.Close()
+		if list.Count != tc.want || (list.TotalSize > 0) != (tc.want > 0) {
+			t.Errorf("%q: count = %d, total_size = %d; want %d files", tc.query, list.Count, list.TotalSize, tc.want)
+		}
+	}
+
+	resp, err := http.Get(srv.URL + "/api/results/usage?type=json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var usage model.ResultsUsageResponse
+	decodeJSON(t, resp, &usage)
+	resp.Body.Close()
+	if usage.Count != 1 || usage.TotalSize <= 0 || usage.Oldest == "" || usage.Oldest != usage.Newest {
+		t.Errorf("usage = %+v, want one file with its size and time", usage)
+	}
+
+	for _, query := range []string{"?type=gif", "?since=yesterday", "?since=" + future + "&until=" + past} {
+		resp, err := http.Get(srv.URL + "/api/results" + strings.ReplaceAll(query, "+", "%2B"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%q: status = %d, want %d", query, resp.StatusCode, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestGetResultConditional(t *testing.T) {
+	srv := newTestServer(t, testEngine())
+
+	resp := postMultipart(t, srv.URL+"/api/extract",
+		uploadFile{field: "file", name: "scan.png", data: pngImage(t)})
+	var extracted model.ExtractTextResponse
+	decodeJSON(t, resp, &extracted)
+
+	url := srv.URL + "/api/results/" + extracted.ResultFile
+	first, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first.Body.Close()
+	etag := first.Header.Get("ETag")
+	if first.StatusCode != http.StatusOK || etag == "" {
+		t.Fatalf("status = %d, ETag = %q; want 200 with an ETag", first.StatusCode, etag)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, url, nil)
+	req.Header.Set("If-None-Match", etag)
+	second, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second.Body.Close()
+	if second.StatusCode != http.StatusNotModified {
+		t.Errorf("conditional status = %d, want %d", second.StatusCode, http.StatusNotModified)
+	}
+}
+
+func TestExtractTextDeduplicates(t *testing.T) {
+	engine := testEngine()
+	srv := newTestServer(t, engine)
+	upload := uploadFile{field: "file", name: "scan.png", data: pngImage(t)}
+
+	var first, second model.ExtractTextResponse
+	decodeJSON(t, postMultipart(t, srv.URL+"/api/extract", upload), &first)
+	decodeJSON(t, postMultipart(t, srv.URL+"/api/extract", upload), &second)
+
+	if !second.Cached || second.ID != first.ID {
+		t.Errorf("second result = %s (cached %v), want cached %s", second.ID, second.Cached, first.ID)
+	}
+	if calls := engine.Calls(); calls != 1 {
+		t.Errorf("engine called %d times, want 1", calls)
+	}
+
+	var forced model.ExtractTextResponse
+	decodeJSON(t, postMultipart(t, srv.URL+"/api/extract?force=true", upload), &forced)
+
+	if forced.Cached || forced.ID == first.ID {
+		t.Errorf("forced result = %s (cached %v), want a fresh result", forced.ID, forced.Cached)
+	}
+	if calls := engine.Calls(); calls != 2 {
+		t.Errorf("engine called %d times, want 2", calls)
+	}
+}
+
+func TestExtractTextGroupPhrases(t *testing.T) {
+	srv := newTestServer(t, ocr.NewFakeEngine(
+		ocr.TextBox{Text: "Invoice", Confidence: 0.9, Box: ocr.BoundingBox{X: 0, Y: 0, Width: 40, Height: 10}},
+		ocr.TextBox{Text: "Number", Confidence: 0.7, Box: ocr.BoundingBox{X: 45, Y: 0, Width: 40, Height: 10}},
+		ocr.TextBox{Text: "12345", Confidence: 0.8, Box: ocr.BoundingBox{X: 200, Y: 0, Width: 40, Height: 10}},
+	))
+
+	resp := postMultipart(t, srv.URL+"/api/extract?group=phrase",
+		uploadFile{field: "file", name: "scan.png", data: pngImage(t)})
+	var got model.ExtractTextResponse
+	decodeJSON(t, resp, &got)
+
+	var texts []string
+	for _, box := range got.Boxes {
+		texts = append(texts, box["text"].(string))
+	}
+	if want := []string{"Invoice Number", "12345"}; !reflect.DeepEqual(texts, want) {
+		t.Errorf("boxes = %q, want %q", texts, want)
+	}
+	if bbox := got.Boxes[0]["bbox"].(map[string]interface{}); bbox["width"] != 85.0 {
+		t.Errorf("phrase width = %v, want 85", bbox["width"])
+	}
+
+	invalid := postMultipart(t, srv.URL+"/api/extract?group=line",
+		uploadFile{field: "file", name: "scan.png", data: pngImage(t)})
+	if invalid.StatusCode != http.StatusBadRequest {
+		t.Errorf("invalid group status = %d, want %d", invalid.StatusCode, http.StatusBadRequest)
+	}
+}
+
+func TestExtractTextNoText(t *testing.T) {
+	srv := newTestServer(t, ocr.NewFakeEngine())
+
+	resp := postMultipart(t, srv.URL+"/api/extract",
+		uploadFile{field: "file", name: "blank.png", data: pngImage(t)})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	var got model.ExtractTextResponse
+	decodeJSON(t, resp, &got)
+	if got.TextFound {
+		t.Error("text_found = true for an image without text")
+	}
+
+	// Both the stored result and a fresh run honor require_text
+	for _, url := range []string{"/api/extract?require_text=true", "/api/extract?require_text=true&force=true"} {
+		resp := postMultipart(t, srv.URL+url,
+			uploadFile{field: "file", name: "blank.png", data: pngImage(t)})
+		if resp.StatusCode != http.StatusUnprocessableEntity {
+			t.Fatalf("%s: status = %d, want %d", url, resp.StatusCode, http.StatusUnprocessableEntity)
+		}
+		var errResp model.ErrorResponse
+		decodeJSON(t, resp, &errResp)
+		if errResp.Code != "no_text" {
+			t.Errorf("%s: code = %q, want no_text", url, errResp.Code)
+		}
+	}
+
+	found := newTestServer(t, testEngine())
+	resp = postMultipart(t, found.URL+"/api/extract?require_text=true",
+		uploadFile{field: "file", name: "scan.png", data: pngImage(t)})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	decodeJSON(t, resp, &got)
+	if !got.TextFound {
+		t.Error("text_found = false for an image with text")
+	}
+}
+
+func TestExtractTextMask(t *testing.T) {
+	engine := testEngine()
+	var masked, unmasked uint8
+	engine.ExtractFunc = func(ctx context.Context, img image.Image, opts ocr.Options) (*ocr.DetailedResult, error) {
+		masked = color.GrayModel.Convert(img.At(1, 1)).(color.Gray).Y
+		unmasked = color.GrayModel.Convert(img.At(6, 6)).(color.Gray).Y
+		return &ocr.DetailedResult{FullText: "Hello"}, nil
+	}
+	srv := newTestServer(t, engine)
+
+	var black bytes.Buffer
+	if err := png.Encode(&black, image.NewGray(image.Rect(0, 0, 8, 8))); err != nil {
+		t.Fatal(err)
+	}
+	mask := url.QueryEscape(`[{"x":0,"y":0,"width":4,"height":4}]`)
+	resp := postMultipart(t, srv.URL+"/api/extract?mask="+mask,
+		uploadFile{field: "file", name: "scan.png", data: black.Bytes()})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	var got model.ExtractTextResponse
+	decodeJSON(t, resp, &got)
+	if got.MaskedRegions != 1 {
+		t.Errorf("masked_regions = %d, want 1", got.MaskedRegions)
+	}
+	if masked != 255 || unmasked != 0 {
+		t.Errorf("masked pixel = %d, unmasked pixel = %d; want 255 and 0", masked, unmasked)
+	}
+
+	for _, mask := range []string{`[{"x":4,"y":4,"width":5,"height":1}]`, `[{"x":0,"y":0,"width":0,"height":1}]`, `{"x":0}`} {
+		resp := postMultipart(t, srv.URL+"/api/extract?mask="+url.QueryEscape(mask),
+			uploadFile{field: "file", name: "scan.png", data: pngImage(t)})
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("mask %s: status = %d, want %d", mask, resp.StatusCode, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestExtractTextDetectCodes(t *testing.T) {
+	srv := newTestServer(t, testEngine())
+
+	matrix, err := qrcode.NewQRCodeWriter().Encode("https://example.com/pay/42", gozxing.BarcodeFormat_QR_CODE, 120, 120, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, matrix); err != nil {
+		t.Fatal(err)
+	}
+
+	resp := postMultipart(t, srv.URL+"/api/extract?detect_codes=true",
+		uploadFile{field: "file", name: "invoice.png", data: buf.Bytes()})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	var got model.ExtractTextResponse
+	decodeJSON(t, resp, &got)
+	if len(got.Codes) != 1 || got.Codes[0].Format != "QR_CODE" || got.Codes[0].Value != "https://example.com/pay/42" {
+		t.Errorf("codes = %+v, want the QR code", got.Codes)
+	}
+	if len(got.Codes) == 1 {
+		// The symbol is about 75 pixels wide, inside a quiet zone
+		if box := got.Codes[0].BBox; box.X < 10 || box.Width < 65 || box.X+box.Width > 110 {
+			t.Errorf("bbox = %+v, want the whole symbol", box)
+		}
+	}
+	if got.FullText == "" {
+		t.Error("full_text is empty, want the OCR text alongside the codes")
+	}
+}
+
+func TestExtractTextSortReading(t *testing.T) {
+	srv := newTestServer(t, ocr.NewFakeEngine(
+		ocr.TextBox{Text: "Total", Confidence: 0.9, Box: ocr.BoundingBox{X: 0, Y: 40, Width: 40, Height: 10}},
+		ocr.TextBox{Text: "Number", Confidence: 0.9, Box: ocr.BoundingBox{X: 50, Y: 1, Width: 40, Height: 10}},
+		ocr.TextBox{Text: "Invoice", Confidence: 0.9, Box: ocr.BoundingBox{X: 0, Y: 0, Width: 40, Height: 10}},
+	))
+
+	texts := func(query string) []string {
+		resp := postMultipart(t, srv.URL+"/api/extract?force=true"+query,
+			uploadFile{field: "file", name: "scan.png", data: pngImage(t)})
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("%s: status = %d, want %d", query, resp.StatusCode, http.StatusOK)
+		}
+		var got model.ExtractTextResponse
+		decodeJSON(t, resp, &got)
+		var texts []string
+		for _, box := range got.Boxes {
+			texts = append(texts, box["text"].(string))
+		}
+		return texts
+	}
+
+	if got, want := texts(""), []string{"Total", "Number", "Invoice"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("default order = %v, want the engine order %v", got, want)
+	}
+	if got, want := texts("&sort=reading"), []string{"Invoice", "Number", "Total"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("reading order = %v, want %v", got, want)
+	}
+}
+
+func TestExtractTextJoinNewline(t *testing.T) {
+	srv := newTestServer(t, ocr.NewFakeEngine(
+		ocr.TextBox{Text: "42", Confidence: 0.9, Box: ocr.BoundingBox{X: 0, Y: 0, Width: 20, Height: 10}},
+		ocr.TextBox{Text: "Main", Confidence: 0.9, Box: ocr.BoundingBox{X: 25, Y: 1, Width: 40, Height: 10}},
+		ocr.TextBox{Text: "Springfield", Confidence: 0.9, Box: ocr.BoundingBox{X: 0, Y: 20, Width: 80, Height: 10}},
+	))
+
+	resp := postMultipart(t, srv.URL+"/api/extract?join=newline",
+		uploadFile{field: "file", name: "scan.png", data: pngImage(t)})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	var got model.ExtractTextResponse
+	decodeJSON(t, resp, &got)
+	if want := "42 Main\nSpringfield"; got.FullText != want {
+		t.Errorf("full_text = %q, want %q", got.FullText, want)
+	}
+
+	resp = postMultipart(t, srv.URL+"/api/extract?join=newline&layout=columns",
+		uploadFile{field: "file", name: "scan.png", data: pngImage(t)})
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("join with layout=columns: status = %d, want %d", resp.StatusCode, http.StatusBadRequest)
+	}
+}
+
+func TestExtractTextValidateOnly(t *testing.T) {
+	engine := testEngine()
+	srv := newTestServer(t, engine)
+
+	resp := postMultipart(t, srv.URL+"/api/extract?validate_only=true",
+		uploadFile{field: "file", name: "scan.png", data: pngImage(t)})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	var got model.ImageValidationResponse
+	decodeJSON(t, resp, &got)
+	want := model.ImageValidationResponse{Valid: true, Width: 8, Height: 8, Format: "png"}
+	if got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	if calls := engine.Calls(); calls != 0 {
+		t.Errorf("engine called %d times, want 0", calls)
+	}
+
+	invalid := postMultipart(t, srv.URL+"/api/extract?validate_only=true",
+		uploadFile{field: "file", name: "scan.png", data: []byte("not an image")})
+	if invalid.StatusCode != http.StatusBadRequest {
+		t.Errorf("invalid image status = %d, want %d", invalid.StatusCode, http.StatusBadRequest)
+	}
+
+	option := postMultipart(t, srv.URL+"/api/extract?validate_only=true&layout=rows",
+		uploadFile{field: "file", name: "scan.png", data: pngImage(t)})
+	if option.StatusCode != http.StatusBadRequest {
+		t.Errorf("invalid option status = %d, want %d", option.StatusCode, http.StatusBadRequest)
+	}
+}
+
+func TestExtractTextGIFFrame(t *testing.T) {
+	srv := newTestServer(t, testEngine())
+
+	palette := color.Palette{color.Black, color.White}
+	frames := []*image.Paletted{
+		image.NewPaletted(image.Rect(0, 0, 8, 8), palette),
+		image.NewPaletted(image.Rect(0, 0, 8, 8), palette),
+		image.NewPaletted(image.Rect(0, 0, 8, 8), palette),
+	}
+	var animated bytes.Buffer
+	if err := gif.EncodeAll(&animated, &gif.GIF{Image: frames, Delay: []int{10, 10, 10}}); err != nil {
+		t.Fatal(err)
+	}
+
+	resp := postMultipart(t, srv.URL+"/api/extract?frame=2",
+		uploadFile{field: "file", name: "scan.gif", data: animated.Bytes()})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	var got model.ExtractTextResponse
+	decodeJSON(t, resp, &got)
+	if got.Frames != 3 {
+		t.Errorf("frames = %d, want 3", got.Frames)
+	}
+
+	for _, url := range []string{"/api/extract?frame=3", "/api/extract?frame=-1"} {
+		resp := postMultipart(t, srv.URL+url,
+			uploadFile{field: "file", name: "scan.gif", data: animated.Bytes()})
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want %d", url, resp.StatusCode, http.StatusBadRequest)
+		}
+	}
+
+	still := postMultipart(t, srv.URL+"/api/extract?frame=1",
+		uploadFile{field: "file", name: "scan.png", data: pngImage(t)})
+	if still.StatusCode != http.StatusBadRequest {
+		t.Errorf("still image status = %d, want %d", still.StatusCode, http.StatusBadRequest)
+	}
+}
+
+func TestExtractTextIdempotencyKey(t *testing.T) {
+	engine := testEngine()
+	srv := newTestServer(t, engine)
+
+	send := func(key string, data []byte) *http.Response {
+		// force=true bypasses the content dedup, so only the key can
+		// prevent a second OCR run
+		req, _ := http.NewRequest(http.MethodPut, srv.URL+"/api/extract?force=true", bytes.NewReader(data))
+		req.Header.Set("Content-Type", "image/png")
+		req.Header.Set("Idempotency-Key", key)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+
+	var first, retry model.ExtractTextResponse
+	decodeJSON(t, send("upload-1", pngImage(t)), &first)
+	replayed := send("upload-1", pngImage(t))
+	if replayed.Header.Get("Idempotent-Replayed") != "true" {
+		t.Error("retry was not marked as replayed")
+	}
+	decodeJSON(t, replayed, &retry)
+
+	if retry.ID != first.ID {
+		t.Errorf("retry result = %s, want %s", retry.ID, first.ID)
+	}
+	if calls := engine.Calls(); calls != 1 {
+		t.Errorf("engine called %d times, want 1", calls)
+	}
+
+	// The same key for another image is a client bug, not a retry
+	other := send("upload-1", append(pngImage(t), 0))
+	if other.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("reused key status = %d, want %d", other.StatusCode, http.StatusUnprocessableEntity)
+	}
+
+	decodeJSON(t, send("upload-2", pngImage(t)), &retry)
+	if retry.ID == first.ID {
+		t.Error("a new key replayed the result of another key")
+	}
+}
+
+// panicMagic starts images whose registered decoder panics
+const panicMagic = "PANICIMG"
+
+func init() {
+	image.RegisterFormat("panic", panicMagic,
+		func(io.Reader) (image.Image, error) { panic("corrupt image") },
+		func(io.Reader) (image.Config, error) { panic("corrupt image") })
+}
+
+func TestBatchProcessRecoversFromPanics(t *testing.T) {
+	srv := newTestServer(t, testEngine())
+
+	resp := postMultipart(t, srv.URL+"/api/batch",
+		uploadFile{field: "files", name: "good.png", data: pngImage(t)},
+		uploadFile{field: "files", name: "crash.img", data: []byte(panicMagic + "data")},
+	)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+
+	var got model.BatchProcessResponse
+	decodeJSON(t, resp, &got)
+
+	if got.SuccessCount != 1 || got.FailureCount != 1 {
+		t.Fatalf("success/failure = %d/%d, want 1/1", got.SuccessCount, got.FailureCount)
+	}
+	if crashed := got.Results[1]; crashed.Success || crashed.Error == "" {
+		t.Errorf("crashed file result = %+v, want a failure with an error", crashed)
+	}
+}
+
+func TestBatchProcessFileTimeout(t *testing.T) {
+	engine := testEngine()
+	engine.ExtractFunc = func(ctx context.Context, img image.Image, opts ocr.Options) (*ocr.DetailedResult, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	srv := newTestServer(t, engine, handler.WithBatchFileTimeout(10*time.Millisecond))
+
+	resp := postMultipart(t, srv.URL+"/api/batch",
+		uploadFile{field: "files", name: "slow.png", data: pngImage(t)})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+
+	var got model.BatchProcessResponse
+	decodeJSON(t, resp, &got)
+
+	if got.FailureCount != 1 || !strings.Contains(got.Results[0].Error, "deadline exceeded") {
+		t.Errorf("result = %+v, want a deadline failure", got.Results[0])
+	}
+}
+
+func TestExtractTextRejectsLargeImages(t *testing.T) {
+	engine := testEngine()
+	srv := newTestServer(t, engine, handler.WithMaxImagePixels(32))
+
+	resp := postMultipart(t, srv.URL+"/api/extract",
+		uploadFile{field: "file", name: "scan.png", data: pngImage(t)})
+
+	if resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusRequestEntityTooLarge)
+	}
+	if calls := engine.Calls(); calls != 0 {
+		t.Errorf("engine called %d times, want 0", calls)
+	}
+}
+
+func TestBatchProcessRejectsLargeImages(t *testing.T) {
+	srv := newTestServer(t, testEngine(), handler.WithMaxImagePixels(32))
+
+	resp := postMultipart(t, srv.URL+"/api/batch",
+		uploadFile{field: "files", name: "scan.png", data: pngImage(t)})
+
+	var got model.BatchProcessResponse
+	decodeJSON(t, resp, &got)
+
+	if result := got.Results[0]; result.Success || !strings.Contains(result.Error, "too large") {
+		t.Errorf("result = %+v, want a too large failure", result)
+	}
+}
+
+func TestOpenAPI(t *testing.T) {
+	srv := newTestServer(t, testEngine())
+
+	resp, err := http.Get(srv.URL + "/api/openapi.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var doc struct {
+		OpenAPI    string                     `json:"openapi"`
+		Paths      map[string]json.RawMessage `json:"paths"`
+		Components struct {
+			Schemas map[string]struct {
+				Properties map[string]json.RawMessage `json:"properties"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	decodeJSON(t, resp, &doc)
+
+	if doc.OpenAPI == "" {
+		t.Error("missing openapi version")
+	}
+	for _, path := range []string{"/api/extract", "/api/batch", "/api/jobs/{id}", "/api/results/{filename}"} {
+		if _, ok := doc.Paths[path]; !ok {
+			t.Errorf("path %s not documented", path)
+		}
+	}
+	if _, ok := doc.Components.Schemas["ExtractTextResponse"].Properties["full_text"]; !ok {
+		t.Error("ExtractTextResponse schema lacks full_text")
+	}
+}
+
+func TestConfig(t *testing.T) {
+	srv := newTestServer(t, testEngine(),
+		handler.WithMaxUploadSize(1<<20),
+		handler.WithBatchFileTimeout(time.Minute),
+		handler.WithServerConfig(model.ServerConfig{AuthEnabled: true}),
+	)
+
+	resp, err := http.Get(srv.URL + "/api/config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var got model.ConfigResponse
+	decodeJSON(t, resp, &got)
+
+	if got.Language != "eng" || got.MaxUploadSize != 1<<20 || got.BatchFileTimeout != "1m0s" {
+		t.Errorf("config = %+v, want language eng, 1MiB uploads and a 1m file timeout", got)
+	}
+	if !got.Server.AuthEnabled {
+		t.Error("server config not reported")
+	}
+}
+
+func TestExtractTextLanguage(t *testing.T) {
+	engine := testEngine()
+	srv := newTestServer(t, engine)
+
+	resp := postMultipart(t, srv.URL+"/api/extract?lang=spa%2Beng",
+		uploadFile{field: "file", name: "scan.png", data: pngImage(t)})
+	var got model.ExtractTextResponse
+	decodeJSON(t, resp, &got)
+
+	if got.Language != "spa+eng" {
+		t.Errorf("language = %q, want %q", got.Language, "spa+eng")
+	}
+	if opts := engine.Options(); len(opts) != 1 || opts[0].Language != "spa+eng" {
+		t.Errorf("engine options = %+v, want language spa+eng", opts)
+	}
+
+	invalid := postMultipart(t, srv.URL+"/api/extract?lang=..%2Fetc",
+		uploadFile{field: "file", name: "scan.png", data: pngImage(t)})
+	if invalid.StatusCode != http.StatusBadRequest {
+		t.Errorf("invalid lang status = %d, want %d", invalid.StatusCode, http.StatusBadRequest)
+	}
+}
+
+func TestExtractTextLanguageNotInstalled(t *testing.T) {
+	engine := ocr.NewLanguageEngine(testEngine(), func(lang string) (ocr.Engine, error) {
+		return nil, &ocr.MissingLanguageError{Missing: []string{lang}, Installed: []string{"eng", "spa"}}
+	}, 2)
+	srv := newTestServer(t, engine)
+
+	resp := postMultipart(t, srv.URL+"/api/extract?lang=deu",
+		uploadFile{field: "file", name: "scan.png", data: pngImage(t)})
+	var got model.ErrorResponse
+	decodeJSON(t, resp, &got)
+	if resp.StatusCode != http.StatusBadRequest || !strings.Contains(got.Fields["lang"], "installed: eng, spa") {
+		t.Errorf("status = %d, error = %+v; want 400 on lang listing the installed languages", resp.StatusCode, got)
+	}
+}
+
+func TestExtractTextLanguageNotInstalledPerCall(t *testing.T) {
+	// A single engine switching languages per call reports the missing
+	// language wrapped in its own error
+	engine := testEngine()
+	engine.ExtractFunc = func(ctx context.Context, img image.Image, opts ocr.Options) (*ocr.DetailedResult, error) {
+		return nil, fmt.Errorf("failed to set language: %w",
+			&ocr.MissingLanguageError{Missing: []string{opts.Language}, Installed: []string{"eng"}})
+	}
+	srv := newTestServer(t, engine)
+
+	resp := postMultipart(t, srv.URL+"/api/extract?lang=deu",
+		uploadFile{field: "file", name: "scan.png", data: pngImage(t)})
+	var got model.ErrorResponse
+	decodeJSON(t, resp, &got)
+	if resp.StatusCode != http.StatusBadRequest || !strings.Contains(got.Fields["lang"], "deu is not installed") {
+		t.Errorf("status = %d, error = %+v; want 400 on lang", resp.StatusCode, got)
+	}
+}
+
+func TestConvertImage(t *testing.T) {
+	srv := newTestServer(t, testEngine())
+
+	resp := postMultipart(t, srv.URL+"/api/convert?to=jpeg&quality=80",
+		uploadFile{field: "file", name: "reçu.png", data: pngImage(t)})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	if got := resp.Header.Get("Content-Type"); got != "image/jpeg" {
+		t.Errorf("Content-Type = %q, want image/jpeg", got)
+	}
+	// Header values are ASCII; other names go in an RFC 2231 filename*
+	disposition := resp.Header.Get("Content-Disposition")
+	_, params, err := mime.ParseMediaType(disposition)
+	if err != nil || params["filename"] != "reçu.jpeg" || strings.ContainsFunc(disposition, func(r rune) bool { return r > 127 }) {
+		t.Errorf("Content-Disposition = %q, want an ASCII header naming reçu.jpeg", disposition)
+	}
+
+	config, format, err := image.DecodeConfig(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if format != "jpeg" || config.Width != 8 || config.Height != 8 {
+		t.Errorf("converted to %s %dx%d, want jpeg 8x8", format, config.Width, config.Height)
+	}
+
+	invalid := postMultipart(t, srv.URL+"/api/convert?to=gif",
+		uploadFile{field: "file", name: "scan.png", data: pngImage(t)})
+	if invalid.StatusCode != http.StatusBadRequest {
+		t.Errorf("unsupported format status = %d, want %d", invalid.StatusCode, http.StatusBadRequest)
+	}
+}
+
+func TestExtractTextThumbnail(t *testing.T) {
+	srv := newTestServer(t, testEngine(), handler.WithThumbnailSize(4))
+
+	resp := postMultipart(t, srv.URL+"/api/extract?thumbnail=true",
+		uploadFile{field: "file", name: "scan.png", data: pngImage(t)})
+	var got model.ExtractTextResponse
+	decodeJSON(t, resp, &got)
+	if got.ThumbnailURL == "" {
+		t.Fatal("missing thumbnail_url")
+	}
+
+	thumb, err := http.Get(srv.URL + got.ThumbnailURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer thumb.Body.Close()
+
+	config, _, err := image.DecodeConfig(thumb.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Width != 4 || config.Height != 4 {
+		t.Errorf("thumbnail is %dx%d, want 4x4", config.Width, config.Height)
+	}
+}
+
+func TestVisualizeBoxesJPEG(t *testing.T) {
+	srv := newTestServer(t, testEngine())
+
+	resp := postMultipart(t, srv.URL+"/api/visualize?format=jpeg&quality=85",
+		uploadFile{field: "file", name: "scan.png", data: pngImage(t)})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	var got model.VisualizeResponse
+	decodeJSON(t, resp, &got)
+	if !strings.HasSuffix(got.OutputFile, ".jpg") {
+		t.Fatalf("output_file = %q, want a .jpg", got.OutputFile)
+	}
+
+	saved, err := http.Get(srv.URL + got.DownloadURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer saved.Body.Close()
+	if ct := saved.Header.Get("Content-Type"); ct != "image/jpeg" {
+		t.Errorf("Content-Type = %q, want image/jpeg", ct)
+	}
+	if _, err := jpeg.Decode(saved.Body); err != nil {
+		t.Errorf("saved image is not a JPEG: %v", err)
+	}
+
+	invalid := postMultipart(t, srv.URL+"/api/visualize?format=jpeg&quality=0",
+		uploadFile{field: "file", name: "scan.png", data: pngImage(t)})
+	if invalid.StatusCode != http.StatusBadRequest {
+		t.Errorf("quality=0 status = %d, want %d", invalid.StatusCode, http.StatusBadRequest)
+	}
+}
+
+func TestExportCrops(t *testing.T) {
+	srv := newTestServer(t, testEngine())
+
+	resp := postMultipart(t, srv.URL+"/api/crops",
+		uploadFile{field: "file", name: "scan.png", data: pngImage(t)})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	var got model.CropExportResponse
+	decodeJSON(t, resp, &got)
+
+	// "World" lies outside the 8x8 image, so only "Hello" is cropped
+	if got.TotalCrops != 1 {
+		t.Errorf("total_crops = %d, want 1", got.TotalCrops)
+	}
+
+	files := fetchZip(t, srv.URL+got.DownloadURL)
+	crop, ok := files["0000_Hello_90.png"]
+	if !ok {
+		t.Fatalf("archive has %v, want 0000_Hello_90.png", files)
+	}
+	rc, err := crop.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	config, _, err := image.DecodeConfig(rc)
+	rc.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Width != 7 || config.Height != 6 {
+		t.Errorf("crop is %dx%d, want 7x6", config.Width, config.Height)
+	}
+
+	rc, err = files["manifest.json"].Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	var manifest map[string]model.CropEntry
+	if err := json.NewDecoder(rc).Decode(&manifest); err != nil {
+		t.Fatal(err)
+	}
+	if entry := manifest["0000_Hello_90.png"]; entry.Text != "Hello" || entry.BBox.Width != 30 {
+		t.Errorf("manifest entry = %+v", entry)
+	}
+}
+
+func TestExtractTextOCRBusy(t *testing.T) {
+	engine := testEngine()
+	busy := make(chan struct{})
+	release := make(chan struct{})
+	var calls atomic.Int32
+	engine.ExtractFunc = func(ctx context.Context, img image.Image, opts ocr.Options) (*ocr.DetailedResult, error) {
+		if calls.Add(1) == 1 {
+			close(busy)
+			<-release
+		}
+		return &ocr.DetailedResult{FullText: "Hello"}, nil
+	}
+	srv := newTestServer(t, engine, handler.WithMaxConcurrentOCR(1), handler.WithOCRQueue(0, 0))
+
+	// Hold the only OCR slot with a request sent as the raw body
+	first := make(chan int)
+	go func() {
+		req, _ := http.NewRequest(http.MethodPut, srv.URL+"/api/extract", bytes.NewReader(pngImage(t)))
+		req.Header.Set("Content-Type", "image/png")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			first <- 0
+			return
+		}
+		resp.Body.Close()
+		first <- resp.StatusCode
+	}()
+	<-busy
+
+	resp := postMultipart(t, srv.URL+"/api/table",
+		uploadFile{field: "file", name: "scan.png", data: pngImage(t)})
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusServiceUnavailable)
+	}
+	if got := resp.Header.Get("Retry-After"); got != "1" {
+		t.Errorf("Retry-After = %q, want 1", got)
+	}
+
+	close(release)
+	if status := <-first; status != http.StatusOK {
+		t.Errorf("first request status = %d, want %d", status, http.StatusOK)
+	}
+}
+
+func TestExtractTextOCRQueue(t *testing.T) {
+	engine := testEngine()
+	busy := make(chan struct{})
+	release := make(chan struct{})
+	var calls atomic.Int32
+	engine.ExtractFunc = func(ctx context.Context, img image.Image, opts ocr.Options) (*ocr.DetailedResult, error) {
+		if calls.Add(1) == 1 {
+			close(busy)
+			<-release
+		}
+		return &ocr.DetailedResult{FullText: "Hello"}, nil
+	}
+	srv := newTestServer(t, engine,
+		handler.WithMaxConcurrentOCR(1), handler.WithOCRQueue(1, time.Minute))
+
+	put := func() <-chan int {
+		status := make(chan int, 1)
+		go func() {
+			req, _ := http.NewRequest(http.MethodPut, srv.URL+"/api/extract?force=true", bytes.NewReader(pngImage(t)))
+			req.Header.Set("Content-Type", "image/png")
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				status <- 0
+				return
+			}
+			resp.Body.Close()
+			status <- resp.StatusCode
+		}()
+		return status
+	}
+
+	// The first request holds the only slot and the second waits for it
+	first := put()
+	<-busy
+	second := put()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		resp, err := http.Get(srv.URL + "/metrics")
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if strings.Contains(string(body), "\nocr_queue_depth 1\n") {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("second request never queued:\n%s", body)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// The queue is full, so a third request is turned away at once
+	resp := postMultipart(t, srv.URL+"/api/table",
+		uploadFile{field: "file", name: "scan.png", data: pngImage(t)})
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusServiceUnavailable)
+	}
+
+	close(release)
+	for i, status := range []<-chan int{first, second} {
+		if got := <-status; got != http.StatusOK {
+			t.Errorf("request %d status = %d, want %d", i+1, got, http.StatusOK)
+		}
+	}
+}
+
+func TestLiveOCR(t *testing.T) {
+	engine := testEngine()
+	busy := make(chan struct{})
+	release := make(chan struct{})
+	var calls atomic.Int32
+	engine.ExtractFunc = func(ctx context.Context, img image.Image, opts ocr.Options) (*ocr.DetailedResult, error) {
+		// Hold the first frame so the following ones arrive while busy
+		if calls.Add(1) == 1 {
+			close(busy)
+			<-release
+		}
+		return &ocr.DetailedResult{
+			FullText: "Hello",
+			Boxes:    []ocr.TextBox{{Text: "Hello", Confidence: 0.9}},
+		}, nil
+	}
+	srv := newTestServer(t, engine, handler.WithStreamMaxFPS(1000))
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/api/stream", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	send := func() {
+		if err := conn.WriteMessage(websocket.BinaryMessage, pngImage(t)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	send()
+	<-busy
+	send()
+	send()
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+
+	// Frame 1 was waiting when frame 2 arrived, so it is dropped
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for _, want := range []int{0, 2} {
+		var got model.StreamFrame
+		if err := conn.ReadJSON(&got); err != nil {
+			t.Fatal(err)
+		}
+		if got.Frame != want || got.FullText != "Hello" || len(got.Boxes) != 1 {
+			t.Errorf("result = %+v, want frame %d with Hello", got, want)
+		}
+		if want == 2 && got.Dropped != 1 {
+			t.Errorf("dropped = %d, want 1", got.Dropped)
+		}
+	}
+}
+
+func TestLiveOCRSubprotocol(t *testing.T) {
+	srv := newTestServer(t, testEngine())
+
+	// Browsers offer the API key next to the protocol the server picks
+	dialer := websocket.Dialer{Subprotocols: []string{"ocr", "bearer.secret"}}
+	conn, _, err := dialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/api/stream", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if got := conn.Subprotocol(); got != "ocr" {
+		t.Errorf("subprotocol = %q, want ocr", got)
+	}
+}
+
+func TestExtractTable(t *testing.T) {
+	cell := func(text string, x, y int) ocr.TextBox {
+		return ocr.TextBox{Text: text, Confidence: 0.9, Box: ocr.BoundingBox{X: x, Y: y, Width: 40, Height: 10}}
+	}
+	srv := newTestServer(t, ocr.NewFakeEngine(
+		cell("Item", 0, 0), cell("Qty", 100, 0),
+		cell("Apples", 0, 20), cell("3", 100, 20),
+		cell("Pears", 0, 40),
+	))
+	upload := uploadFile{field: "file", name: "invoice.png", data: pngImage(t)}
+
+	var got model.TableResponse
+	decodeJSON(t, postMultipart(t, srv.URL+"/api/table", upload), &got)
+
+	want := [][]string{{"Item", "Qty"}, {"Apples", "3"}, {"Pears", ""}}
+	if !reflect.DeepEqual(got.Cells, want) || got.Rows != 3 || got.Columns != 2 {
+		t.Errorf("got %d x %d cells %q, want 3 x 2 cells %q", got.Rows, got.Columns, got.Cells, want)
+	}
+	if bounds := got.CellBounds[2][1]; bounds != (model.BBox{X: 100, Y: 40, Width: 40, Height: 10}) {
+		t.Errorf("empty cell bounds = %+v", bounds)
+	}
+
+	resp := postMultipart(t, srv.URL+"/api/table?format=csv", upload)
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "Item,Qty\nApples,3\nPears,\n" {
+		t.Errorf("csv = %q", body)
+	}
+}
+
+func TestExtractKeyValues(t *testing.T) {
+	srv := newTestServer(t, ocr.NewFakeEngine(
+		ocr.TextBox{Text: "Total:", Confidence: 0.9, Box: ocr.BoundingBox{X: 0, Y: 0, Width: 48, Height: 10}},
+		ocr.TextBox{Text: "$12.50", Confidence: 0.7, Box: ocr.BoundingBox{X: 100, Y: 0, Width: 48, Height: 10}},
+	))
+
+	resp := postMultipart(t, srv.URL+"/api/key-values",
+		uploadFile{field: "file", name: "receipt.png", data: pngImage(t)})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	var got model.KeyValueResponse
+	decodeJSON(t, resp, &got)
+
+	want := []model.KeyValuePair{{
+		Key:             "Total",
+		Value:           "$12.50",
+		KeyConfidence:   0.9,
+		ValueConfidence: 0.7,
+		KeyBBox:         model.BBox{X: 0, Y: 0, Width: 48, Height: 10},
+		ValueBBox:       model.BBox{X: 100, Y: 0, Width: 48, Height: 10},
+	}}
+	if !reflect.DeepEqual(got.Pairs, want) || got.TotalPairs != 1 {
+		t.Errorf("pairs = %+v, want %+v", got.Pairs, want)
+	}
+}
+
+func TestStats(t *testing.T) {
+	srv := newTestServer(t, testEngine())
+
+	postMultipart(t, srv.URL+"/api/extract",
+		uploadFile{field: "file", name: "scan.png", data: pngImage(t)})
+	postMultipart(t, srv.URL+"/api/batch",
+		uploadFile{field: "files", name: "a.png", data: pngImage(t)},
+		uploadFile{field: "files", name: "broken.png", data: []byte("garbage")},
+	)
+
+	resp, err := http.Get(srv.URL + "/api/stats")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var got model.StatsResponse
+	decodeJSON(t, resp, &got)
+	if got.DocumentsProcessed != 2 {
+		t.Errorf("documents_processed = %d, want 2", got.DocumentsProcessed)
+	}
+	if got.AverageConfidence < 0.849 || got.AverageConfidence > 0.851 {
+		t.Errorf("average_confidence = %v, want 0.85", got.AverageConfidence)
+	}
+	if len(got.TopLanguages) != 1 || got.TopLanguages[0] != (model.LanguageCount{Language: "eng", Count: 2}) {
+		t.Errorf("top_languages = %+v, want eng x2", got.TopLanguages)
+	}
+}
+
+func TestUploadLanguage(t *testing.T) {
+	tessdata := t.TempDir()
+	srv := newTestServer(t, testEngine(), handler.WithTessdataDir(tessdata))
+
+	// A header with one component at offset 12, right after it
+	traineddata := append([]byte{1, 0, 0, 0, 12, 0, 0, 0, 0, 0, 0, 0}, "lstm"...)
+	resp := postMultipart(t, srv.URL+"/admin/languages",
+		uploadFile{field: "file", name: "forms.traineddata", data: traineddata})
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusCreated)
+	}
+	installed, err := os.ReadFile(filepath.Join(tessdata, "forms.traineddata"))
+	if err != nil || !bytes.Equal(installed, traineddata) {
+		t.Errorf("installed model = %q, %v", installed, err)
+	}
+
+	invalid := postMultipart(t, srv.URL+"/admin/languages",
+		uploadFile{field: "file", name: "notes.traineddata", data: []byte("not a model")})
+	if invalid.StatusCode != http.StatusBadRequest {
+		t.Errorf("invalid model status = %d, want %d", invalid.StatusCode, http.StatusBadRequest)
+	}
+	if _, err := os.Stat(filepath.Join(tessdata, "notes.traineddata")); err == nil {
+		t.Error("invalid model was installed")
+	}
+}
+
+func TestExtractTextBody(t *testing.T) {
+	srv := newTestServer(t, testEngine(), handler.WithMaxUploadSize(1<<10))
+
+	req, _ := http.NewRequest(http.MethodPut, srv.URL+"/api/extract", bytes.NewReader(pngImage(t)))
+	req.Header.Set("Content-Type", "image/png")
+	req.Header.Set("Content-Disposition", `attachment; filename="scan.png"`)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	var got model.ExtractTextResponse
+	decodeJSON(t, resp, &got)
+	if got.FullText != "Hello World" || got.Filename != "scan.png" {
+		t.Errorf("got %q from %q, want Hello World from scan.png", got.FullText, got.Filename)
+	}
+
+	large, err := http.Post(srv.URL+"/api/extract", "image/png", bytes.NewReader(make([]byte, 2<<10)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer large.Body.Close()
+	if large.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("large body status = %d, want %d", large.StatusCode, http.StatusRequestEntityTooLarge)
+	}
+}
+
+func TestExtractTextRaw(t *testing.T) {
+	srv := newTestServer(t, testEngine())
+
+	resp := postMultipart(t, srv.URL+"/api/extract?raw=true",
+		uploadFile{field: "file", name: "scan.png", data: pngImage(t)})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	var got model.ExtractTextResponse
+	decodeJSON(t, resp, &got)
+	if got.FullText != "Hello World" || got.ImageWidth != 8 || got.ImageHeight != 8 {
+		t.Errorf("got %q %dx%d, want Hello World 8x8", got.FullText, got.ImageWidth, got.ImageHeight)
+	}
+
+	// Preprocessing needs the decoded image
+	conflict := postMultipart(t, srv.URL+"/api/extract?raw=true&preprocess=grayscale",
+		uploadFile{field: "file", name: "scan.png", data: pngImage(t)})
+	if conflict.StatusCode != http.StatusBadRequest {
+		t.Errorf("raw with preprocess status = %d, want %d", conflict.StatusCode, http.StatusBadRequest)
+	}
+}
+
+func TestWatchDir(t *testing.T) {
+	dir, outputs := t.TempDir(), t.TempDir()
+	h := handler.New(testEngine(),
+		handler.WithOutputDir(outputs),
+		handler.WithUploadDir(t.TempDir()),
+	)
+
+	write := func(name string, data []byte) {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A file dropped before the watcher starts is processed as well
+	write("before.png", pngImage(t))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- h.WatchDir(ctx, dir) }()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("WatchDir() = %v", err)
+		}
+	})
+
+	write("after.png", pngImage(t))
+	write("broken.png", []byte("not an image"))
+	write("notes.txt", []byte("left alone"))
+
+	moved := []string{
+		filepath.Join(dir, "processed", "before.png"),
+		filepath.Join(dir, "processed", "after.png"),
+		filepath.Join(dir, "failed", "broken.png"),
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for _, path := range moved {
+		for {
+			if _, err := os.Stat(path); err == nil {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("%s never appeared", path)
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+	}
+
+	results, _ := filepath.Glob(filepath.Join(outputs, "ocr_*.json"))
+	if len(results) != 2 {
+		t.Errorf("saved results = %v, want one per image", results)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "notes.txt")); err != nil {
+		t.Errorf("notes.txt was touched: %v", err)
+	}
+}
+
+func TestBatchAsyncCallbackInternal(t *testing.T) {
+	srv := newTestServer(t, testEngine())
+
+	for _, callback := range []string{"http://127.0.0.1:8080/hook", "http://169.254.169.254/latest", "http://localhost/hook", "ftp://example.com/hook"} {
+		resp := postMultipart(t, srv.URL+"/api/batch?async=true&callback="+url.QueryEscape(callback),
+			uploadFile{field: "files", name: "scan.png", data: pngImage(t)})
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("callback %s: status = %d, want %d", callback, resp.StatusCode, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestGetJob(t *testing.T) {
+	srv := newTestServer(t, testEngine())
+
+	var accepted model.JobAcceptedResponse
+	resp := postMultipart(t, srv.URL+"/api/batch?async=true",
+		uploadFile{field: "files", name: "a.png", data: pngImage(t)},
+		uploadFile{field: "files", name: "b.png", data: pngImage(t)})
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusAccepted)
+	}
+	decodeJSON(t, resp, &accepted)
+
+	// Poll until the job finishes
+	var job model.JobStatus
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		resp, err := http.Get(srv.URL + "/api/jobs/" + accepted.JobID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+		}
+		job = model.JobStatus{}
+		decodeJSON(t, resp, &job)
+		resp.Body.Close()
+		if job.State == "done" || job.State == "failed" || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if job.ID != accepted.JobID || job.State != "done" || job.Completed != 2 || job.Total != 2 {
+		t.Errorf("job = %+v, want 2 of 2 files done", job)
+	}
+	if job.Result == nil || job.Result.SuccessCount != 2 || job.FinishedAt == nil {
+		t.Errorf("result = %+v, finished at %v; want 2 successes", job.Result, job.FinishedAt)
+	}
+
+	resp, err := http.Get(srv.URL + "/api/jobs/6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("unknown job: status = %d, want %d", resp.StatusCode, http.StatusNotFound)
+	}
+}
+
+func TestExtractFromURLInvalid(t *testing.T) {
+	srv := newTestServer(t, testEngine())
+
+	for _, tc := range []struct {
+		body  string
+		field string
+	}{
+		{`{}`, "url"},
+		{`{"url":"::not a url"}`, "url"},
+		{`{"url":"ftp://example.com/scan.png"}`, "url"},
+		{`{"url":"http:///scan.png"}`, "url"},
+		{`{"url":"http://10.0.0.1/scan.png"}`, "url"},
+		{`{"url":"http://localhost/scan.png"}`, "url"},
+	} {
+		resp, err := http.Post(srv.URL+"/api/extract-url", "application/json", strings.NewReader(tc.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got model.ErrorResponse
+		decodeJSON(t, resp, &got)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest || got.Fields[tc.field] == "" {
+			t.Errorf("%s: status = %d, error = %+v; want 400 on %s", tc.body, resp.StatusCode, got, tc.field)
+		}
+	}
+}
+
+func TestExtractFromURLUnreachable(t *testing.T) {
+	srv := newTestServer(t, testEngine())
+
+	// .invalid never resolves, so the fetch fails upstream
+	resp, err := http.Post(srv.URL+"/api/extract-url", "application/json",
+		strings.NewReader(`{"url":"http://scans.invalid/scan.png"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got model.ErrorResponse
+	decodeJSON(t, resp, &got)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadGateway {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusBadGateway)
+	}
+	if strings.Contains(got.Error, "scans.invalid") {
+		t.Errorf("error = %q, leaks the fetch error", got.Error)
+	}
+}
+
+// postDiff posts req to the diff endpoint of srv
+func postDiff(t *testing.T, srv *httptest.Server, req interface{}) *http.Response {
+	t.Helper()
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.Post(srv.URL+"/api/diff", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func TestDiff(t *testing.T) {
+	srv := newTestServer(t, testEngine())
+
+	resp := postDiff(t, srv, model.DiffRequest{Reference: "Hello brave World", Hypothesis: "Hel1o World"})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	var got model.DiffResponse
+	decodeJSON(t, resp, &got)
+
+	want := []model.DiffEdit{
+		{Op: "substitute", RefIndex: 0, HypIndex: 0, Reference: "Hello", Hypothesis: "Hel1o"},
+		{Op: "delete", RefIndex: 1, HypIndex: -1, Reference: "brave"},
+		{Op: "equal", RefIndex: 2, HypIndex: 1, Reference: "World", Hypothesis: "World"},
+	}
+	if !reflect.DeepEqual(got.Edits, want) {
+		t.Errorf("edits = %+v, want %+v", got.Edits, want)
+	}
+	if got.ReferenceWords != 3 || got.HypothesisWords != 2 || got.Substitutions != 1 || got.Deletions != 1 || got.Insertions != 0 {
+		t.Errorf("counts = %+v, want 3 and 2 words with 1 substitution and 1 deletion", got)
+	}
+	// One substituted character and "brave " deleted, out of 17
+	if math.Abs(got.CER-7.0/17) > 1e-9 || math.Abs(got.WER-2.0/3) > 1e-9 {
+		t.Errorf("cer = %v, wer = %v; want 7/17 and 2/3", got.CER, got.WER)
+	}
+}
+
+func TestDiffResult(t *testing.T) {
+	srv := newTestServer(t, testEngine())
+
+	var extracted model.ExtractTextResponse
+	decodeJSON(t, postMultipart(t, srv.URL+"/api/extract",
+		uploadFile{field: "file", name: "scan.png", data: pngImage(t)}), &extracted)
+
+	resp := postDiff(t, srv, model.DiffRequest{Reference: "Hello World", ResultID: extracted.ID})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	var got model.DiffResponse
+	decodeJSON(t, resp, &got)
+	if got.HypothesisWords != 2 || len(got.Edits) != 2 || got.CER != 0 || got.WER != 0 {
+		t.Errorf("diff = %+v, want the saved text to match exactly", got)
+	}
+
+	resp = postDiff(t, srv, model.DiffRequest{Reference: "Hello World", ResultID: "6ba7b810-9dad-11d1-80b4-00c04fd430c8"})
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("unknown result: status = %d, want %d", resp.StatusCode, http.StatusNotFound)
+	}
+}
+
+func TestDiffInvalid(t *testing.T) {
+	srv := newTestServer(t, testEngine())
+
+	resp, err := http.Post(srv.URL+"/api/diff", "application/json", strings.NewReader(`{"reference":`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var got model.ErrorResponse
+	decodeJSON(t, resp, &got)
+	if resp.StatusCode != http.StatusBadRequest || got.Fields["body"] == "" {
+		t.Errorf("bad JSON: status = %d, error = %+v; want 400 on body", resp.StatusCode, got)
+	}
+
+	resp = postDiff(t, srv, model.DiffRequest{Reference: "Hello", ResultID: "not-a-uuid"})
+	got = model.ErrorResponse{}
+	decodeJSON(t, resp, &got)
+	if resp.StatusCode != http.StatusBadRequest || got.Fields["result_id"] == "" {
+		t.Errorf("bad result_id: status = %d, error = %+v; want 400 on result_id", resp.StatusCode, got)
+	}
+}
+
+func TestDiffTooLong(t *testing.T) {
+	long := strings.Repeat("a", 20000)
+	srv := newTestServer(t, ocr.NewFakeEngine(
+		ocr.TextBox{Text: long, Confidence: 0.9, Box: ocr.BoundingBox{Width: 10, Height: 10}},
+	))
+	var extracted model.ExtractTextResponse
+	decodeJSON(t, postMultipart(t, srv.URL+"/api/extract",
+		uploadFile{field: "file", name: "scan.png", data: pngImage(t)}), &extracted)
+
+	// A few huge words pass the word limit but not the character limit.
+	// Texts sent by the client are its mistake, a saved result over the
+	// limit cannot be compared.
+	for _, tc := range []struct {
+		name  string
+		req   model.DiffRequest
+		code  int
+		field string
+	}{
+		{"reference", model.DiffRequest{Reference: long, Hypothesis: "b"}, http.StatusBadRequest, "reference"},
+		{"hypothesis", model.DiffRequest{Reference: "a", Hypothesis: long}, http.StatusBadRequest, "hypothesis"},
+		{"saved result", model.DiffRequest{Reference: "a", ResultID: extracted.ID}, http.StatusUnprocessableEntity, "hypothesis"},
+	} {
+		resp := postDiff(t, srv, tc.req)
+		var got model.ErrorResponse
+		decodeJSON(t, resp, &got)
+		if resp.StatusCode != tc.code || got.Fields[tc.field] == "" {
+			t.Errorf("%s: status = %d, error = %+v; want %d on %s", tc.name, resp.StatusCode, got, tc.code, tc.field)
+		}
+	}
+}
+
+func TestEvaluateTooLong(t *testing.T) {
+	long := strings.Repeat("a", 20000)
+	srv := newTestServer(t, ocr.NewFakeEngine(
+		ocr.TextBox{Text: long, Confidence: 0.9, Box: ocr.BoundingBox{Width: 10, Height: 10}},
+	))
+
+	evaluate := func(groundTruth string) int {
+		var body bytes.Buffer
+		writer := multipart.NewWriter(&body)
+		part, _ := writer.CreateFormFile("file", "scan.png")
+		part.Write(pngImage(t))
+		writer.WriteField("ground_truth", groundTruth)
+		writer.Close()
+		resp, err := http.Post(srv.URL+"/api/evaluate", writer.FormDataContentType(), &body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if got := evaluate(long); got != http.StatusRequestEntityTooLarge {
+		t.Errorf("long ground truth: status = %d, want %d", got, http.StatusRequestEntityTooLarge)
+	}
+	if got := evaluate("short"); got != http.StatusUnprocessableEntity {
+		t.Errorf("long OCR text: status = %d, want %d", got, http.StatusUnprocessableEntity)
+	}
+}
+
+func TestUploadTooLarge(t *testing.T) {
+	srv := newTestServer(t, testEngine(), handler.WithMaxUploadSize(1<<10))
+	big := make([]byte, 2<<20)
+
+	for _, path := range []string{"/api/extract", "/api/visualize", "/api/table"} {
+		resp := postMultipart(t, srv.URL+path, uploadFile{field: "file", name: "scan.png", data: big})
+		if resp.StatusCode != http.StatusRequestEntityTooLarge {
+			t.Errorf("%s: status = %d, want %d", path, resp.StatusCode, http.StatusRequestEntityTooLarge)
+		}
+	}
+
+	// Each file of a batch is held to the limit, not just the total
+	resp := postMultipart(t, srv.URL+"/api/batch",
+		uploadFile{field: "files", name: "scan.png", data: pngImage(t)},
+		uploadFile{field: "files", name: "big.png", data: make([]byte, 2<<10)})
+	if resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("batch: status = %d, want %d", resp.StatusCode, http.StatusRequestEntityTooLarge)
+	}
+}
+
+func TestJobEventsOutlastWriteTimeout(t *testing.T) {
+	engine := testEngine()
+	engine.ExtractFunc = func(ctx context.Context, img image.Image, opts ocr.Options) (*ocr.DetailedResult, error) {
+		time.Sleep(300 * time.Millisecond)
+		return engine.Detailed, nil
+	}
+	h := handler.New(engine,
+		handler.WithOutputDir(t.TempDir()),
+		handler.WithUploadDir(t.TempDir()),
+	)
+	r := chi.NewRouter()
+	r.Post("/api/batch", h.BatchProcess)
+	r.Get("/api/jobs/{id}/events", h.JobEvents)
+	srv := httptest.NewUnstartedServer(r)
+	srv.Config.WriteTimeout = 200 * time.Millisecond
+	srv.Start()
+	t.Cleanup(srv.Close)
+
+	var job model.JobAcceptedResponse
+	decodeJSON(t, postMultipart(t, srv.URL+"/api/batch?async=true",
+		uploadFile{field: "files", name: "a.png", data: pngImage(t)},
+		uploadFile{field: "files", name: "b.png", data: pngImage(t)}), &job)
+
+	resp, err := http.Get(srv.URL + "/api/jobs/" + job.JobID + "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if !strings.Contains(string(body), "event: done\n") {
+		t.Errorf("stream ended without the done event:\n%s", body)
+	}
+}
+
+func TestUploadLanguageSlow(t *testing.T) {
+	h := handler.New(testEngine(),
+		handler.WithOutputDir(t.TempDir()),
+		handler.WithUploadDir(t.TempDir()),
+		handler.WithTessdataDir(t.TempDir()),
+	)
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(h.UploadLanguage))
+	srv.Config.ReadTimeout = 100 * time.Millisecond
+	srv.Config.WriteTimeout = 100 * time.Millisecond
+	srv.Start()
+	t.Cleanup(srv.Close)
+
+	// The model trickles in for longer than both server timeouts
+	body, pipe := io.Pipe()
+	writer := multipart.NewWriter(pipe)
+	go func() {
+		part, _ := writer.CreateFormFile("file", "forms.traineddata")
+		part.Write([]byte{1, 0, 0, 0, 12, 0, 0, 0})
+		time.Sleep(300 * time.Millisecond)
+		part.Write(append([]byte{0, 0, 0, 0}, "lstm"...))
+		writer.Close()
+		pipe.Close()
+	}()
+
+	resp, err := http.Post(srv.URL, writer.FormDataContentType(), body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusCreated)
+	}
+}
@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"image/draw"
+	"net/http"
+	"time"
+
+	"github.com/username/ocr-go/internal/model"
+	"github.com/username/ocr-go/internal/ocr"
+)
+
+// Health status values
+const (
+	statusHealthy   = "healthy"
+	statusDegraded  = "degraded"
+	statusUnhealthy = "unhealthy"
+	statusAlive     = "alive"
+	statusReady     = "ready"
+	statusNotReady  = "not_ready"
+)
+
+// probeTimeout bounds how long the health probe OCR may run
+const probeTimeout = 5 * time.Second
+
+// Health check endpoint that runs a probe OCR against the engine. The
+// probe takes an OCR slot like any request, so a saturated server reports
+// itself degraded instead of adding to the load.
+func (h *Handler) Health(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), probeTimeout)
+	defer cancel()
+
+	response := model.HealthResponse{
+		Status:   statusHealthy,
+		Language: h.engine.Language(),
+		Version:  h.engine.Version(),
+	}
+
+	if err := h.queueOCR(ctx); err != nil {
+		response.Status = statusDegraded
+		response.Error = err.Error()
+		h.respondJSON(w, http.StatusOK, response)
+		return
+	}
+	result, err := h.probe(ctx)
+	h.releaseOCR()
+	h.probeFailed.Store(err != nil)
+
+	switch {
+	case err != nil:
+		response.Status = statusUnhealthy
+		response.Error = err.Error()
+		h.respondJSON(w, http.StatusServiceUnavailable, response)
+		return
+	case len(result.Boxes) == 0:
+		// The engine runs but failed to read the probe text
+		response.Status = statusDegraded
+		response.Error = "probe image returned no text"
+	}
+
+	h.respondJSON(w, http.StatusOK, response)
+}
+
+// Liveness reports that the process is up and serving requests
+func (h *Handler) Liveness(w http.ResponseWriter, r *http.Request) {
+	h.respondJSON(w, http.StatusOK, model.HealthResponse{Status: statusAlive})
+}
+
+// Readiness reports whether the engine is warmed up and the last health
+// probe, if any, succeeded. It runs no OCR, so orchestrators can poll it
+// often without taking OCR slots from requests.
+func (h *Handler) Readiness(w http.ResponseWriter, r *http.Request) {
+	response := model.HealthResponse{
+		Status:   statusReady,
+		Language: h.engine.Language(),
+	}
+
+	switch {
+	case !h.ready.Load():
+		response.Status = statusNotReady
+		response.Error = "engine warm-up has not completed"
+	case h.probeFailed.Load():
+		response.Status = statusNotReady
+		response.Error = "last health probe failed"
+	}
+	if response.Status != statusReady {
+		h.respondJSON(w, http.StatusServiceUnavailable, response)
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, response)
+}
+
+// Warmup runs a probe OCR and marks the handler ready once it succeeds
+func (h *Handler) Warmup(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+
+	if err := h.acquireOCR(ctx); err != nil {
+		return err
+	}
+	_, err := h.probe(ctx)
+	h.releaseOCR()
+	if err != nil {
+		return err
+	}
+
+	h.ready.Store(true)
+	return nil
+}
+
+// probe runs OCR on the probe image. The result cache is bypassed so the
+// probe always exercises the engine itself.
+func (h *Handler) probe(ctx context.Context) (*ocr.DetailedResult, error) {
+	engine := h.engine
+	if cached, ok := engine.(*ocr.CachedEngine); ok {
+		engine = cached.Engine
+	}
+	return engine.ExtractTextWithBoxes(ctx, probeImage())
+}
+
+// probeImage renders a small image with known text for the health probe
+func probeImage() image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, 120, 40))
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	drawText(img, 10, 25, "OCR 123", color.Black)
+	return img
+}
@@ -0,0 +1,180 @@
+package handler_test
+
+import (
+	"context"
+	"errors"
+	"image"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"sync/atomic"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/username/ocr-go/internal/handler"
+	"github.com/username/ocr-go/internal/model"
+	"github.com/username/ocr-go/internal/ocr"
+)
+
+// newHealthServer serves the health, liveness, readiness and version
+// endpoints of a handler around engine
+func newHealthServer(t *testing.T, engine ocr.Engine, opts ...handler.Option) (*handler.Handler, *httptest.Server) {
+	t.Helper()
+
+	opts = append([]handler.Option{
+		handler.WithOutputDir(t.TempDir()),
+		handler.WithUploadDir(t.TempDir()),
+	}, opts...)
+	h := handler.New(engine, opts...)
+
+	r := chi.NewRouter()
+	r.Get("/health", h.Health)
+	r.Get("/healthz", h.Liveness)
+	r.Get("/readyz", h.Readiness)
+	r.Get("/api/version", h.Version)
+
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+	return h, srv
+}
+
+// getHealth fetches a health endpoint and decodes its response
+func getHealth(t *testing.T, url string) (int, model.HealthResponse) {
+	t.Helper()
+
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var got model.HealthResponse
+	decodeJSON(t, resp, &got)
+	return resp.StatusCode, got
+}
+
+func TestHealth(t *testing.T) {
+	tests := []struct {
+		name   string
+		engine *ocr.FakeEngine
+		code   int
+		status string
+	}{
+		{"healthy", testEngine(), http.StatusOK, "healthy"},
+		{"no text", ocr.NewFakeEngine(), http.StatusOK, "degraded"},
+		{"engine error", &ocr.FakeEngine{Err: errors.New("tesseract crashed"), Lang: "eng"}, http.StatusServiceUnavailable, "unhealthy"},
+	}
+	for _, tt := range tests {
+		_, srv := newHealthServer(t, tt.engine)
+
+		code, got := getHealth(t, srv.URL+"/health")
+		if code != tt.code || got.Status != tt.status {
+			t.Errorf("%s: got %d %q, want %d %q", tt.name, code, got.Status, tt.code, tt.status)
+		}
+		if tt.engine.Calls() != 1 {
+			t.Errorf("%s: engine called %d times, want 1 probe", tt.name, tt.engine.Calls())
+		}
+	}
+}
+
+func TestHealthWaitsForOCRSlot(t *testing.T) {
+	started := make(chan struct{})
+	unblock := make(chan struct{})
+	engine := testEngine()
+	engine.ExtractFunc = func(ctx context.Context, img image.Image, opts ocr.Options) (*ocr.DetailedResult, error) {
+		close(started)
+		<-unblock
+		return testEngine().Detailed, nil
+	}
+	_, srv := newHealthServer(t, engine, handler.WithMaxConcurrentOCR(1), handler.WithOCRQueue(0, 0))
+
+	// The first probe holds the only slot until unblocked
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		getHealth(t, srv.URL+"/health")
+	}()
+	<-started
+
+	code, got := getHealth(t, srv.URL+"/health")
+	close(unblock)
+	<-done
+	if code != http.StatusOK || got.Status != "degraded" {
+		t.Errorf("busy probe: got %d %q, want %d degraded", code, got.Status, http.StatusOK)
+	}
+	if engine.Calls() != 1 {
+		t.Errorf("engine called %d times, want 1", engine.Calls())
+	}
+}
+
+func TestLiveness(t *testing.T) {
+	engine := &ocr.FakeEngine{Err: errors.New("tesseract crashed")}
+	_, srv := newHealthServer(t, engine)
+
+	code, got := getHealth(t, srv.URL+"/healthz")
+	if code != http.StatusOK || got.Status != "alive" {
+		t.Errorf("got %d %q, want %d alive", code, got.Status, http.StatusOK)
+	}
+	if engine.Calls() != 0 {
+		t.Errorf("engine called %d times, want 0", engine.Calls())
+	}
+}
+
+func TestReadiness(t *testing.T) {
+	var failing atomic.Bool
+	engine := testEngine()
+	engine.ExtractFunc = func(ctx context.Context, img image.Image, opts ocr.Options) (*ocr.DetailedResult, error) {
+		if failing.Load() {
+			return nil, errors.New("tesseract crashed")
+		}
+		return testEngine().Detailed, nil
+	}
+	h, srv := newHealthServer(t, engine)
+
+	code, got := getHealth(t, srv.URL+"/readyz")
+	if code != http.StatusServiceUnavailable || got.Status != "not_ready" {
+		t.Errorf("before warm-up: got %d %q, want %d not_ready", code, got.Status, http.StatusServiceUnavailable)
+	}
+
+	if err := h.Warmup(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		code, got = getHealth(t, srv.URL+"/readyz")
+		if code != http.StatusOK || got.Status != "ready" {
+			t.Errorf("after warm-up: got %d %q, want %d ready", code, got.Status, http.StatusOK)
+		}
+	}
+	if engine.Calls() != 1 {
+		t.Errorf("engine called %d times, want only the warm-up probe", engine.Calls())
+	}
+
+	// A failed health probe takes the server out of rotation until one
+	// passes again
+	failing.Store(true)
+	getHealth(t, srv.URL+"/health")
+	if code, got = getHealth(t, srv.URL+"/readyz"); code != http.StatusServiceUnavailable {
+		t.Errorf("after a failed probe: got %d %q, want %d", code, got.Status, http.StatusServiceUnavailable)
+	}
+	failing.Store(false)
+	getHealth(t, srv.URL+"/health")
+	if code, got = getHealth(t, srv.URL+"/readyz"); code != http.StatusOK {
+		t.Errorf("after a passing probe: got %d %q, want %d", code, got.Status, http.StatusOK)
+	}
+}
+
+func TestVersion(t *testing.T) {
+	_, srv := newHealthServer(t, testEngine())
+
+	resp, err := http.Get(srv.URL + "/api/version")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	var got model.VersionResponse
+	decodeJSON(t, resp, &got)
+	if got.EngineVersion != "fake" || got.GoVersion != runtime.Version() {
+		t.Errorf("version = %+v, want engine fake on %s", got, runtime.Version())
+	}
+}
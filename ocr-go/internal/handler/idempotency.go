@@ -0,0 +1,235 @@
+package handler
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// idempotencyHeader carries the client-chosen key of a retried request
+	idempotencyHeader = "Idempotency-Key"
+
+	// replayedHeader marks a response served from the idempotency store
+	replayedHeader = "Idempotent-Replayed"
+
+	// maxIdempotencyKeyLength bounds the keys kept in memory
+	maxIdempotencyKeyLength = 255
+
+	// idempotencySweepInterval is how often expired keys are dropped
+	idempotencySweepInterval = time.Minute
+
+	// idempotencyEntryOverhead is charged per stored response on top of its
+	// body, so many small responses are bounded too
+	idempotencyEntryOverhead = 512
+)
+
+// replayHeaders are the response headers kept for replay. The rest are set
+// by middleware, which runs again for the replayed response.
+var replayHeaders = []string{"Content-Type", "Content-Disposition"}
+
+// idempotentResponse is a completed response kept for replay, or a
+// reservation while the first request with its key is still running
+type idempotentResponse struct {
+	key         string
+	fingerprint string
+	done        bool
+	status      int
+	header      http.Header
+	body        []byte
+	expires     time.Time
+}
+
+// idempotencyStore remembers the responses of requests sent with an
+// Idempotency-Key, so a client retrying after a dropped connection gets the
+// original response instead of a second OCR run. Unlike the content dedup
+// the key is chosen by the client and the stored response is replayed as
+// is, whatever its format. Completed responses take at most maxBytes; the
+// least recently used are dropped first to make room.
+type idempotencyStore struct {
+	mu        sync.Mutex
+	clock     Clock
+	ttl       time.Duration
+	maxBytes  int64
+	size      int64
+	entries   map[string]*idempotentResponse
+	order     *list.List
+	elements  map[string]*list.Element
+	lastSweep time.Time
+}
+
+// newIdempotencyStore returns a store keeping up to maxBytes of responses
+// for ttl
+func newIdempotencyStore(clock Clock, ttl time.Duration, maxBytes int64) *idempotencyStore {
+	return &idempotencyStore{
+		clock:     clock,
+		ttl:       ttl,
+		maxBytes:  maxBytes,
+		entries:   make(map[string]*idempotentResponse),
+		order:     list.New(),
+		elements:  make(map[string]*list.Element),
+		lastSweep: clock.Now(),
+	}
+}
+
+// cost is what a completed response counts against maxBytes
+func (entry *idempotentResponse) cost() int64 {
+	return int64(len(entry.key)+len(entry.body)) + idempotencyEntryOverhead
+}
+
+// remove drops key and its response, if completed, from the LRU order
+func (s *idempotencyStore) remove(key string) {
+	if element, ok := s.elements[key]; ok {
+		s.size -= element.Value.(*idempotentResponse).cost()
+		s.order.Remove(element)
+		delete(s.elements, key)
+	}
+	delete(s.entries, key)
+}
+
+// begin looks up key. It returns the stored response when the key already
+// completed, or reserves the key for the caller when it is unknown; inFlight
+// reports that another request holds the reservation.
+func (s *idempotencyStore) begin(key, fingerprint string) (stored *idempotentResponse, inFlight bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.clock.Now()
+	if now.Sub(s.lastSweep) >= idempotencySweepInterval {
+		for k, entry := range s.entries {
+			if entry.done && now.After(entry.expires) {
+				s.remove(k)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	entry, ok := s.entries[key]
+	if ok && entry.done && now.After(entry.expires) {
+		ok = false
+	}
+	if !ok {
+		s.remove(key)
+		s.entries[key] = &idempotentResponse{key: key, fingerprint: fingerprint}
+		return nil, false
+	}
+	if !entry.done {
+		return nil, true
+	}
+	s.order.MoveToFront(s.elements[key])
+	return entry, false
+}
+
+// finish stores the response of a reserved key. Only successful responses
+// that fit the store are kept; otherwise the reservation is released so the
+// client can retry.
+func (s *idempotencyStore) finish(key string, rec *responseRecorder) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[key]
+	if !ok {
+		return
+	}
+	if rec.status < 200 || rec.status >= 300 || int64(len(key)+rec.body.Len())+idempotencyEntryOverhead > s.maxBytes {
+		delete(s.entries, key)
+		return
+	}
+	entry.done = true
+	entry.status = rec.status
+	entry.header = make(http.Header)
+	for _, name := range replayHeaders {
+		if value := rec.Header().Get(name); value != "" {
+			entry.header.Set(name, value)
+		}
+	}
+	entry.body = rec.body.Bytes()
+	entry.expires = s.clock.Now().Add(s.ttl)
+
+	s.elements[key] = s.order.PushFront(entry)
+	s.size += entry.cost()
+	for s.size > s.maxBytes {
+		s.remove(s.order.Back().Value.(*idempotentResponse).key)
+	}
+}
+
+// idempotencyScope hashes the client-chosen key together with the caller's
+// credentials, so clients sharing a server cannot read each other's results
+// by guessing keys
+func idempotencyScope(r *http.Request, key string) string {
+	hash := sha256.New()
+	for _, part := range []string{r.Header.Get("X-API-Key"), r.Header.Get("Authorization"), key} {
+		hash.Write([]byte(part))
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// responseRecorder passes a response through to the client while keeping a
+// copy of its status and body
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+// WriteHeader records the status code
+func (rec *responseRecorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+// Write records the body, defaulting the status to 200 like net/http
+func (rec *responseRecorder) Write(p []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	rec.body.Write(p)
+	return rec.ResponseWriter.Write(p)
+}
+
+// withIdempotency runs extract for a request, replaying the stored response
+// instead when the request carries an Idempotency-Key that already
+// completed. fingerprint identifies the request content, a key reused for a
+// different request is rejected.
+func (h *Handler) withIdempotency(w http.ResponseWriter, r *http.Request, fingerprint func() string, extract func(http.ResponseWriter)) {
+	key := r.Header.Get(idempotencyHeader)
+	if key == "" {
+		extract(w)
+		return
+	}
+	if len(key) > maxIdempotencyKeyLength {
+		h.respondFieldError(w, codeInvalidField, idempotencyHeader, "Idempotency-Key is too long")
+		return
+	}
+
+	scoped := idempotencyScope(r, key)
+	sum := fingerprint()
+	stored, inFlight := h.idempotency.begin(scoped, sum)
+	switch {
+	case inFlight:
+		h.respondError(w, http.StatusConflict, "A request with this Idempotency-Key is still being processed")
+		return
+	case stored != nil && stored.fingerprint != sum:
+		h.respondError(w, http.StatusUnprocessableEntity, "Idempotency-Key was already used for a different request")
+		return
+	case stored != nil:
+		for name, values := range stored.header {
+			w.Header()[name] = values
+		}
+		w.Header().Set(replayedHeader, "true")
+		w.WriteHeader(stored.status)
+		w.Write(stored.body)
+		return
+	}
+
+	rec := &responseRecorder{ResponseWriter: w}
+	defer h.idempotency.finish(scoped, rec)
+	extract(rec)
+}
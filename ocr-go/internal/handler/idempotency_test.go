@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// completeKey runs a request with key through the store with a response
+// body of n bytes
+func completeKey(t *testing.T, s *idempotencyStore, key string, n int) {
+	t.Helper()
+	if stored, inFlight := s.begin(key, "fp"); stored != nil || inFlight {
+		t.Fatalf("begin(%q) found an entry", key)
+	}
+	rec := &responseRecorder{ResponseWriter: httptest.NewRecorder()}
+	rec.Write([]byte(strings.Repeat("x", n)))
+	s.finish(key, rec)
+}
+
+func TestIdempotencyStoreEvictsLeastRecentlyUsed(t *testing.T) {
+	// Room for two responses of 1000 bytes
+	s := newIdempotencyStore(realClock{}, time.Hour, 2*(1000+1+idempotencyEntryOverhead))
+
+	completeKey(t, s, "a", 1000)
+	completeKey(t, s, "b", 1000)
+	if stored, _ := s.begin("a", "fp"); stored == nil {
+		t.Fatal("a was dropped while the store had room")
+	}
+
+	// a was used last, so b makes room for c
+	completeKey(t, s, "c", 1000)
+	for key, kept := range map[string]bool{"a": true, "b": false, "c": true} {
+		s.mu.Lock()
+		_, ok := s.entries[key]
+		s.mu.Unlock()
+		if ok != kept {
+			t.Errorf("%s kept = %v, want %v", key, ok, kept)
+		}
+	}
+	if s.size > s.maxBytes {
+		t.Errorf("size = %d, over the limit of %d", s.size, s.maxBytes)
+	}
+
+	// A response larger than the whole store is not kept
+	completeKey(t, s, "huge", 10000)
+	if stored, inFlight := s.begin("huge", "fp"); stored != nil || inFlight {
+		t.Error("oversized response was kept")
+	}
+}
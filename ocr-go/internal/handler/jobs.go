@@ -0,0 +1,259 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/gofrs/uuid"
+	"github.com/username/ocr-go/internal/fetch"
+	"github.com/username/ocr-go/internal/model"
+)
+
+// Async batch job settings
+const (
+	jobQueueSize     = 100
+	callbackAttempts = 3
+	callbackBackoff  = time.Second
+	callbackTimeout  = 10 * time.Second
+)
+
+// Job states
+const (
+	jobQueued  = "queued"
+	jobRunning = "running"
+	jobDone    = "done"
+	jobFailed  = "failed"
+)
+
+// batchJob is a batch queued for background processing
+type batchJob struct {
+	id       string
+	files    []batchFile
+	opts     batchOptions
+	callback string
+}
+
+// GetJob returns the state, progress and results of an async batch job
+func (h *Handler) GetJob(w http.ResponseWriter, r *http.Request) {
+	job, ok := h.jobStore.Get(chi.URLParam(r, "id"))
+	if !ok {
+		h.respondError(w, http.StatusNotFound, "Job not found")
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, job)
+}
+
+// enqueueBatch queues the uploaded files for background processing and
+// responds with 202 Accepted and the job ID
+func (h *Handler) enqueueBatch(w http.ResponseWriter, r *http.Request, headers []*multipart.FileHeader, opts batchOptions) {
+	callback := r.URL.Query().Get("callback")
+	if callback != "" {
+		if err := validateCallbackURL(callback); err != nil {
+			h.respondFieldError(w, codeInvalidField, "callback", "Invalid callback URL: "+err.Error())
+			return
+		}
+	}
+
+	job := batchJob{
+		id:       uuid.Must(uuid.NewV4()).String(),
+		files:    bufferBatchFiles(headers),
+		opts:     opts,
+		callback: callback,
+	}
+
+	now := h.clock.Now()
+	h.jobStore.Put(model.JobStatus{
+		ID:        job.id,
+		State:     jobQueued,
+		Total:     len(job.files),
+		CreatedAt: now,
+		UpdatedAt: now,
+	})
+
+	if err := h.submitJob(job); err != nil {
+		h.jobStore.Update(job.id, func(s *model.JobStatus) {
+			s.State = jobFailed
+			s.Error = err.Error()
+			s.FinishedAt = &now
+		})
+		msg := "Job queue is full"
+		if errors.Is(err, errShuttingDown) {
+			msg = "Server is shutting down"
+		}
+		h.respondError(w, http.StatusServiceUnavailable, msg)
+		return
+	}
+
+	h.respondJSON(w, http.StatusAccepted, model.JobAcceptedResponse{
+		JobID:      job.id,
+		Status:     jobQueued,
+		TotalFiles: len(job.files),
+		Callback:   callback,
+	})
+}
+
+// Errors of submitJob
+var (
+	errJobQueueFull = errors.New("job queue is full")
+	errShuttingDown = errors.New("server is shutting down")
+)
+
+// submitJob queues job for runJobs unless the queue is full or closed
+func (h *Handler) submitJob(job batchJob) error {
+	h.queueMu.RLock()
+	defer h.queueMu.RUnlock()
+
+	if h.queueClosed {
+		return errShuttingDown
+	}
+	select {
+	case h.queue <- job:
+		return nil
+	default:
+		return errJobQueueFull
+	}
+}
+
+// runJobs processes queued batch jobs until the queue is closed
+func (h *Handler) runJobs() {
+	defer close(h.jobsDone)
+	for job := range h.queue {
+		h.runJob(job)
+	}
+}
+
+// Shutdown stops accepting async batch jobs and waits for the queued and
+// running ones to finish. When ctx ends first, the remaining jobs are
+// canceled and marked failed, and Shutdown returns once they have stopped
+// using the engine. Call it before closing the engine.
+func (h *Handler) Shutdown(ctx context.Context) error {
+	h.queueMu.Lock()
+	if !h.queueClosed {
+		h.queueClosed = true
+		close(h.queue)
+	}
+	h.queueMu.Unlock()
+
+	select {
+	case <-h.jobsDone:
+		return nil
+	case <-ctx.Done():
+	}
+	h.cancelJobs()
+	<-h.jobsDone
+	return ctx.Err()
+}
+
+// runJob processes a single batch job, tracking progress in the job store
+func (h *Handler) runJob(job batchJob) {
+	h.jobStore.Update(job.id, func(s *model.JobStatus) {
+		s.State = jobRunning
+	})
+
+	events := make(chan model.BatchEvent)
+	forwarded := make(chan struct{})
+	go func() {
+		defer close(forwarded)
+		for event := range events {
+			h.jobStore.Update(job.id, func(s *model.JobStatus) {
+				s.Completed++
+				event.Completed = s.Completed
+			})
+			h.events.publish(job.id, event)
+		}
+	}()
+
+	response := h.runBatch(h.jobsCtx, job.files, job.opts, events)
+	close(events)
+	<-forwarded
+	response.JobID = job.id
+
+	h.jobStore.Update(job.id, func(s *model.JobStatus) {
+		now := h.clock.Now()
+		s.State = jobDone
+		switch {
+		case h.jobsCtx.Err() != nil && response.FailureCount > 0:
+			s.State = jobFailed
+			s.Error = "canceled by server shutdown"
+		case response.SuccessCount == 0:
+			s.State = jobFailed
+			s.Error = "all files failed"
+		}
+		s.Result = &response
+		s.FinishedAt = &now
+	})
+	h.events.finish(job.id)
+
+	if job.callback == "" {
+		return
+	}
+	if err := deliverCallback(h.jobsCtx, job.callback, response); err != nil {
+		log.Printf("Job %s: callback delivery failed: %v", job.id, err)
+	}
+}
+
+// deliverCallback POSTs the batch result to the callback URL, retrying
+// with exponential backoff on network errors and non-2xx responses. The
+// URL is client-chosen, so the client refuses internal addresses, also
+// after redirects and DNS resolution. Retries stop when ctx ends.
+func deliverCallback(ctx context.Context, callbackURL string, response model.BatchProcessResponse) error {
+	body, err := json.Marshal(response)
+	if err != nil {
+		return fmt.Errorf("failed to encode result: %w", err)
+	}
+
+	client := fetch.NewClient(callbackTimeout)
+	backoff := callbackBackoff
+
+	for attempt := 1; ; attempt++ {
+		err = postCallback(ctx, client, callbackURL, body)
+		if err == nil || attempt == callbackAttempts {
+			return err
+		}
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return err
+		}
+		backoff *= 2
+	}
+}
+
+// postCallback performs a single callback delivery attempt
+func postCallback(ctx context.Context, client *http.Client, callbackURL string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, callbackURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("callback returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// validateCallbackURL checks that the callback is an absolute http(s) URL
+// whose host is not a literal internal address
+func validateCallbackURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return errors.New("callback must be an absolute http(s) URL")
+	}
+	return fetch.ValidateURL(u)
+}
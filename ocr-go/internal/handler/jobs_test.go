@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/png"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/username/ocr-go/internal/model"
+	"github.com/username/ocr-go/internal/ocr"
+)
+
+// queueTestJob submits a one-file batch job to h and returns its ID
+func queueTestJob(t *testing.T, h *Handler) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 8, 8))); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	job := batchJob{
+		id: "job-1",
+		files: []batchFile{{
+			name: "scan.png",
+			size: int64(len(data)),
+			open: func() (io.ReadSeekCloser, error) { return nopSeekCloser{bytes.NewReader(data)}, nil },
+		}},
+	}
+	h.jobStore.Put(model.JobStatus{ID: job.id, State: jobQueued, Total: 1})
+	if err := h.submitJob(job); err != nil {
+		t.Fatal(err)
+	}
+	return job.id
+}
+
+// newJobsHandler creates a handler around engine with temporary storage
+func newJobsHandler(t *testing.T, engine ocr.Engine) *Handler {
+	t.Helper()
+	return New(engine, WithOutputDir(t.TempDir()), WithUploadDir(t.TempDir()))
+}
+
+func TestShutdownDrainsJobs(t *testing.T) {
+	h := newJobsHandler(t, ocr.NewFakeEngine(ocr.TextBox{Text: "Hello", Confidence: 0.9}))
+	id := queueTestJob(t, h)
+
+	if err := h.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	if job, _ := h.jobStore.Get(id); job.State != jobDone {
+		t.Errorf("job state = %q (%s), want %q", job.State, job.Error, jobDone)
+	}
+
+	err := h.submitJob(batchJob{id: "late"})
+	if !errors.Is(err, errShuttingDown) {
+		t.Errorf("submit after Shutdown: err = %v, want errShuttingDown", err)
+	}
+	if err := h.Shutdown(context.Background()); err != nil {
+		t.Errorf("second Shutdown: %v", err)
+	}
+}
+
+func TestShutdownCancelsJobs(t *testing.T) {
+	started := make(chan struct{})
+	engine := ocr.NewFakeEngine()
+	engine.ExtractFunc = func(ctx context.Context, img image.Image, opts ocr.Options) (*ocr.DetailedResult, error) {
+		close(started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	h := newJobsHandler(t, engine)
+	id := queueTestJob(t, h)
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := h.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Shutdown: err = %v, want context.DeadlineExceeded", err)
+	}
+	job, _ := h.jobStore.Get(id)
+	if job.State != jobFailed || job.Error != "canceled by server shutdown" {
+		t.Errorf("job = %q (%s), want failed by shutdown", job.State, job.Error)
+	}
+}
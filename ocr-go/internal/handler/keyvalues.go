@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/username/ocr-go/internal/model"
+	"github.com/username/ocr-go/internal/ocr"
+)
+
+// ExtractKeyValues handles pairing the labels of an uploaded form or
+// receipt with their values
+func (h *Handler) ExtractKeyValues(w http.ResponseWriter, r *http.Request) {
+	if !h.parseUploadForm(w, r, h.maxUploadSize, 10<<20) {
+		return
+	}
+
+	opts, err := parseOCROptions(r)
+	if err != nil {
+		h.respondOptionsError(w, err)
+		return
+	}
+
+	// Get uploaded file
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		h.respondFieldError(w, codeMissingField, "file", "No file uploaded")
+		return
+	}
+	defer file.Close()
+
+	// Decode image
+	img, _, err := h.decodeImage(file)
+	if err != nil {
+		h.respondDecodeError(w, err)
+		return
+	}
+
+	// Extract text with boxes
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	result, err := h.recognize(ctx, img, opts)
+	if err != nil {
+		h.respondOCRError(w, err)
+		return
+	}
+	sanitizeResult(result)
+
+	pairs := make([]model.KeyValuePair, 0)
+	for _, pair := range ocr.KeyValues(result.Boxes) {
+		pairs = append(pairs, model.KeyValuePair{
+			Key:             pair.Key.Text,
+			Value:           pair.Value.Text,
+			KeyConfidence:   pair.Key.Confidence,
+			ValueConfidence: pair.Value.Confidence,
+			KeyBBox:         newBBox(pair.Key.Box),
+			ValueBBox:       newBBox(pair.Value.Box),
+		})
+	}
+
+	h.respondJSON(w, http.StatusOK, model.KeyValueResponse{
+		Filename:   header.Filename,
+		Pairs:      pairs,
+		TotalPairs: len(pairs),
+	})
+}
+
+// newBBox converts an engine bounding box to its API form
+func newBBox(box ocr.BoundingBox) model.BBox {
+	return model.BBox{
+		X:      box.X,
+		Y:      box.Y,
+		Width:  box.Width,
+		Height: box.Height,
+	}
+}
@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+
+	"github.com/username/ocr-go/internal/ocr"
+)
+
+// Legend geometry, in pixels
+const (
+	legendBarWidth  = 100
+	legendBarHeight = 10
+	legendPadding   = 6
+	legendMargin    = 8
+	legendLabelSize = 13 // basicfont.Face7x13 line height
+)
+
+// legendCorners are the corners a legend may be placed in, in the order
+// tried when the requested corner would cover detected text
+var legendCorners = []string{"bottom-right", "bottom-left", "top-right", "top-left"}
+
+// validLegendCorner reports whether corner names a supported corner
+func validLegendCorner(corner string) bool {
+	for _, c := range legendCorners {
+		if c == corner {
+			return true
+		}
+	}
+	return false
+}
+
+// confidenceColor maps a confidence from 0 to 1 onto a red to green gradient
+func confidenceColor(confidence float64) color.RGBA {
+	confidence = min(max(confidence, 0), 1)
+	return color.RGBA{
+		R: uint8(255 * (1 - confidence)),
+		G: uint8(255 * confidence),
+		A: 255,
+	}
+}
+
+// legendSize is the size of the legend panel: the bar above its labels
+func legendSize() image.Point {
+	return image.Pt(legendBarWidth+2*legendPadding, legendBarHeight+legendLabelSize+3*legendPadding)
+}
+
+// legendRect places the legend in corner of bounds. If that covers any
+// box, the other corners are tried in turn; when every corner is taken
+// the requested one is used.
+func legendRect(bounds image.Rectangle, corner string, boxes []ocr.TextBox) image.Rectangle {
+	candidates := append([]string{corner}, legendCorners...)
+	for _, c := range candidates {
+		rect := cornerRect(bounds, c, legendSize())
+		if !overlapsBoxes(rect, boxes) {
+			return rect
+		}
+	}
+	return cornerRect(bounds, corner, legendSize())
+}
+
+// cornerRect returns a rectangle of size inset by legendMargin in corner
+func cornerRect(bounds image.Rectangle, corner string, size image.Point) image.Rectangle {
+	x := bounds.Max.X - legendMargin - size.X
+	y := bounds.Max.Y - legendMargin - size.Y
+	switch corner {
+	case "top-left":
+		x, y = bounds.Min.X+legendMargin, bounds.Min.Y+legendMargin
+	case "top-right":
+		y = bounds.Min.Y + legendMargin
+	case "bottom-left":
+		x = bounds.Min.X + legendMargin
+	}
+	return image.Rect(x, y, x+size.X, y+size.Y)
+}
+
+// overlapsBoxes reports whether rect intersects any box or the label
+// drawn above it
+func overlapsBoxes(rect image.Rectangle, boxes []ocr.TextBox) bool {
+	for _, box := range boxes {
+		r := image.Rect(box.Box.X, box.Box.Y, box.Box.X+box.Box.Width, box.Box.Y+box.Box.Height)
+		label := image.Rect(box.Box.X, box.Box.Y-5-legendLabelSize, box.Box.X+7*len(boxLabel(box)), box.Box.Y)
+		if r.Overlaps(rect) || label.Overlaps(rect) {
+			return true
+		}
+	}
+	return false
+}
+
+// drawLegend draws a red to green confidence bar with 0% and 100% labels
+// on a white panel at rect
+func drawLegend(img *image.RGBA, rect image.Rectangle) {
+	draw.Draw(img, rect, image.NewUniform(color.White), image.Point{}, draw.Src)
+	drawRect(img, rect.Min.X, rect.Min.Y, rect.Max.X-1, rect.Max.Y-1, color.Black, 1)
+
+	bar := image.Rect(0, 0, legendBarWidth, legendBarHeight).
+		Add(rect.Min.Add(image.Pt(legendPadding, legendPadding)))
+	for x := bar.Min.X; x < bar.Max.X; x++ {
+		c := confidenceColor(float64(x-bar.Min.X) / float64(legendBarWidth-1))
+		draw.Draw(img, image.Rect(x, bar.Min.Y, x+1, bar.Max.Y), image.NewUniform(c), image.Point{}, draw.Src)
+	}
+
+	baseline := bar.Max.Y + legendPadding + legendLabelSize - 3
+	drawText(img, bar.Min.X, baseline, "0%", color.Black)
+	drawText(img, bar.Max.X-4*7, baseline, "100%", color.Black)
+}
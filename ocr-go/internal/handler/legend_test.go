@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/username/ocr-go/internal/ocr"
+)
+
+func TestConfidenceColor(t *testing.T) {
+	tests := []struct {
+		confidence float64
+		want       color.RGBA
+	}{
+		{0, color.RGBA{R: 255, A: 255}},
+		{1, color.RGBA{G: 255, A: 255}},
+		{-0.5, color.RGBA{R: 255, A: 255}},
+		{1.5, color.RGBA{G: 255, A: 255}},
+	}
+	for _, tt := range tests {
+		if got := confidenceColor(tt.confidence); got != tt.want {
+			t.Errorf("confidenceColor(%v) = %v, want %v", tt.confidence, got, tt.want)
+		}
+	}
+}
+
+func TestLegendRect(t *testing.T) {
+	bounds := image.Rect(0, 0, 400, 300)
+	size := legendSize()
+
+	// Nothing to avoid: the requested corner is used
+	rect := legendRect(bounds, "top-left", nil)
+	if rect.Min != image.Pt(legendMargin, legendMargin) || rect.Size() != size {
+		t.Errorf("top-left legend = %v", rect)
+	}
+
+	// A word in the bottom-right corner pushes the legend elsewhere
+	word := ocr.TextBox{Text: "total", Confidence: 0.9,
+		Box: ocr.BoundingBox{X: 300, Y: 260, Width: 90, Height: 30}}
+	rect = legendRect(bounds, "bottom-right", []ocr.TextBox{word})
+	if overlapsBoxes(rect, []ocr.TextBox{word}) {
+		t.Errorf("legend %v overlaps word %v", rect, word.Box)
+	}
+	if want := cornerRect(bounds, "bottom-left", size); rect != want {
+		t.Errorf("legend = %v, want bottom-left %v", rect, want)
+	}
+
+	// Every corner taken: fall back to the requested one
+	full := ocr.TextBox{Box: ocr.BoundingBox{Width: 400, Height: 300}}
+	rect = legendRect(bounds, "top-right", []ocr.TextBox{full})
+	if want := cornerRect(bounds, "top-right", size); rect != want {
+		t.Errorf("legend = %v, want top-right %v", rect, want)
+	}
+}
+
+func TestDrawLegend(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 200, 100))
+	rect := cornerRect(img.Bounds(), "top-left", legendSize())
+	drawLegend(img, rect)
+
+	bar := rect.Min.Add(image.Pt(legendPadding, legendPadding+legendBarHeight/2))
+	if got := img.RGBAAt(bar.X, bar.Y); got != confidenceColor(0) {
+		t.Errorf("bar start = %v, want red", got)
+	}
+	if got := img.RGBAAt(bar.X+legendBarWidth-1, bar.Y); got != confidenceColor(1) {
+		t.Errorf("bar end = %v, want green", got)
+	}
+}
@@ -0,0 +1,37 @@
+package handler
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/username/ocr-go/internal/ocr"
+)
+
+// cacheStatser is implemented by engines that cache results
+type cacheStatser interface {
+	Stats() ocr.CacheStats
+}
+
+// Metrics exposes service counters in the Prometheus text format
+func (h *Handler) Metrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+	if cache, ok := h.engine.(cacheStatser); ok {
+		stats := cache.Stats()
+		writeMetric(w, "ocr_cache_hits_total", "counter", "OCR results served from the in-memory cache.", stats.Hits)
+		writeMetric(w, "ocr_cache_misses_total", "counter", "OCR requests not found in the in-memory cache.", stats.Misses)
+		writeMetric(w, "ocr_cache_entries", "gauge", "OCR results currently cached in memory.", stats.Entries)
+		writeMetric(w, "ocr_cache_capacity", "gauge", "Maximum number of OCR results cached in memory.", stats.Capacity)
+	}
+
+	writeMetric(w, "ocr_slots_in_use", "gauge", "OCR calls currently running.", len(h.ocrSlots))
+	writeMetric(w, "ocr_slots", "gauge", "Maximum number of OCR calls running at once.", cap(h.ocrSlots))
+	writeMetric(w, "ocr_queue_depth", "gauge", "Requests waiting for a free OCR slot.", h.ocrWaiting.Load())
+	writeMetric(w, "ocr_queue_rejected_total", "counter", "Requests rejected with 503 because no OCR slot freed up.", h.ocrRejected.Load())
+}
+
+// writeMetric writes a single unlabeled sample with its metadata
+func writeMetric(w io.Writer, name, kind, help string, value interface{}) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %v\n", name, help, name, kind, name, value)
+}
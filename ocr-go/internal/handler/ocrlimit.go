@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/username/ocr-go/internal/ocr"
+)
+
+// ocrRetryAfter is the Retry-After, in seconds, sent when every OCR slot
+// is taken; a page takes about that long to recognize
+const ocrRetryAfter = 1
+
+// errOCRBusy reports that maxConcurrentOCR engine calls are already running
+var errOCRBusy = errors.New("all OCR slots are busy")
+
+// tryAcquireOCR takes one of the engine slots shared by every endpoint,
+// failing with errOCRBusy instead of waiting when all are taken
+func (h *Handler) tryAcquireOCR() error {
+	select {
+	case h.ocrSlots <- struct{}{}:
+		return nil
+	default:
+		return errOCRBusy
+	}
+}
+
+// queueOCR takes an engine slot, waiting up to ocrQueueWait for one when
+// all are taken. At most ocrQueueSize requests wait at a time; each holds
+// its decoded image, so requests beyond that fail with errOCRBusy right
+// away, as do requests that time out waiting.
+func (h *Handler) queueOCR(ctx context.Context) error {
+	if err := h.tryAcquireOCR(); err == nil {
+		return nil
+	}
+	if h.ocrQueueWait <= 0 {
+		h.ocrRejected.Add(1)
+		return errOCRBusy
+	}
+	defer h.ocrWaiting.Add(-1)
+	if h.ocrWaiting.Add(1) > int64(h.ocrQueueSize) {
+		h.ocrRejected.Add(1)
+		return errOCRBusy
+	}
+
+	timer := time.NewTimer(h.ocrQueueWait)
+	defer timer.Stop()
+	select {
+	case h.ocrSlots <- struct{}{}:
+		return nil
+	case <-timer.C:
+		h.ocrRejected.Add(1)
+		return errOCRBusy
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// acquireOCR waits for an engine slot. It is used by work that is already
+// queued and bounded, such as the files of a batch.
+func (h *Handler) acquireOCR(ctx context.Context) error {
+	select {
+	case h.ocrSlots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// releaseOCR frees a slot taken by tryAcquireOCR, queueOCR or acquireOCR
+func (h *Handler) releaseOCR() {
+	<-h.ocrSlots
+}
+
+// respondOCRError sends a 503 with Retry-After when no OCR slot could be
+// taken, a 400 on lang when the requested language is not installed and a
+// 500 for any other engine error
+func (h *Handler) respondOCRError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errOCRBusy) {
+		w.Header().Set("Retry-After", strconv.Itoa(ocrRetryAfter))
+		h.respondError(w, http.StatusServiceUnavailable, "OCR capacity exhausted, retry later")
+		return
+	}
+	var missing *ocr.MissingLanguageError
+	if errors.As(err, &missing) {
+		installed := strings.Join(missing.Installed, ", ")
+		if installed == "" {
+			installed = "none"
+		}
+		h.respondFieldError(w, codeInvalidField, "lang", fmt.Sprintf("Language %s is not installed (installed: %s)",
+			strings.Join(missing.Missing, "+"), installed))
+		return
+	}
+	h.respondError(w, http.StatusInternalServerError,
+		fmt.Sprintf("OCR failed: %v", err))
+}
@@ -0,0 +1,440 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/username/ocr-go/internal/model"
+	"github.com/username/ocr-go/internal/ocr"
+	"github.com/username/ocr-go/internal/openapi"
+)
+
+// apiSpecVersion is the version reported in the OpenAPI document
+const apiSpecVersion = "1.0.0"
+
+// apiSpec builds the OpenAPI document once; it only depends on the code
+var apiSpec = sync.OnceValue(buildAPISpec)
+
+// OpenAPI serves the OpenAPI 3 document describing the /api endpoints
+func (h *Handler) OpenAPI(w http.ResponseWriter, r *http.Request) {
+	h.respondJSON(w, http.StatusOK, apiSpec())
+}
+
+// buildAPISpec describes every /api route. Request and response schemas
+// are derived from the model types the handlers decode and encode.
+func buildAPISpec() *openapi.Document {
+	doc := openapi.New("OCR API", apiSpecVersion)
+	doc.Info.Description = "Extract text and word boxes from images with Tesseract OCR."
+
+	errorResponse := jsonResponse(doc, "Error", model.ErrorResponse{})
+	withErrors := func(responses map[string]openapi.Response, codes ...int) map[string]openapi.Response {
+		for _, code := range codes {
+			responses[strconv.Itoa(code)] = errorResponse
+		}
+		return responses
+	}
+
+	extractResponses := func() map[string]openapi.Response {
+		ok := jsonResponse(doc, "Extracted text and word boxes", model.ExtractTextResponse{})
+		ok.Content["text/plain"] = openapi.MediaType{Schema: &openapi.Schema{Type: "string"}}
+		ok.Content["text/csv"] = openapi.MediaType{Schema: &openapi.Schema{Type: "string"}}
+		return withErrors(map[string]openapi.Response{"200": ok},
+			http.StatusBadRequest, http.StatusConflict, http.StatusRequestEntityTooLarge,
+			http.StatusUnprocessableEntity, http.StatusInternalServerError, http.StatusServiceUnavailable)
+	}
+
+	doc.Path("/api/extract").Post = &openapi.Operation{
+		Summary:    "Extract text from an uploaded image",
+		Parameters: append([]openapi.Parameter{formatParam()}, extractParams()...),
+		RequestBody: &openapi.RequestBody{
+			Required: true,
+			Content: map[string]openapi.MediaType{
+				"multipart/form-data": {Schema: fileSchema("file", "Image to read")},
+				"application/json":    {Schema: doc.SchemaOf(model.ExtractBase64Request{})},
+				"image/*":             {Schema: imageSchema()},
+			},
+		},
+		Responses: extractResponses(),
+	}
+	doc.Path("/api/extract").Put = &openapi.Operation{
+		Summary:    "Extract text from an image sent as the request body",
+		Parameters: append([]openapi.Parameter{formatParam()}, extractParams()...),
+		RequestBody: &openapi.RequestBody{
+			Required: true,
+			Content: map[string]openapi.MediaType{
+				"image/*": {Schema: imageSchema()},
+			},
+		},
+		Responses: extractResponses(),
+	}
+
+	doc.Path("/api/extract-url").Post = &openapi.Operation{
+		Summary:     "Extract text from an image fetched by URL",
+		Parameters:  append([]openapi.Parameter{formatParam()}, extractParams()...),
+		RequestBody: jsonBody(doc, model.ExtractURLRequest{}),
+		Responses:   extractResponses(),
+	}
+
+	doc.Path("/api/reprocess/{id}").Post = &openapi.Operation{
+		Summary:    "Re-run OCR on a stored original with new options",
+		Parameters: append([]openapi.Parameter{pathParam("id", "Result ID"), formatParam()}, extractParams()...),
+		Responses:  withErrors(extractResponses(), http.StatusNotFound),
+	}
+
+	doc.Path("/api/visualize").Post = &openapi.Operation{
+		Summary: "Draw the recognized word boxes onto the image",
+		Parameters: append(append(ocrOptionParams(),
+			queryParam("legend", "Color boxes by confidence and draw a legend", boolSchema()),
+			queryParam("legend_corner", "Preferred legend corner; another is used if it would cover text",
+				&openapi.Schema{Type: "string", Enum: legendCorners, Default: legendCorners[0]}),
+			queryParam("format", "Format of the saved image", &openapi.Schema{Type: "string", Enum: []string{"png", "jpeg"}, Default: "png"}),
+			queryParam("quality", "JPEG quality", &openapi.Schema{Type: "integer", Default: defaultJPEGQuality})),
+			tenantParams()...),
+		RequestBody: multipartBody(fileSchema("file", "Image to annotate")),
+		Responses: withErrors(map[string]openapi.Response{
+			"200": jsonResponse(doc, "Annotated image saved", model.VisualizeResponse{}),
+		}, http.StatusBadRequest, http.StatusRequestEntityTooLarge, http.StatusInternalServerError, http.StatusServiceUnavailable),
+	}
+
+	doc.Path("/api/stream").Get = &openapi.Operation{
+		Summary: "WebSocket for live camera OCR: send frames as binary messages, get one JSON result per processed frame",
+		Parameters: []openapi.Parameter{
+			queryParam("fps", "Max frames per second to process, capped by the server", &openapi.Schema{Type: "number"}),
+		},
+		Responses: withErrors(map[string]openapi.Response{
+			"101": {Description: "Switched to the WebSocket protocol"},
+		}, http.StatusBadRequest, http.StatusForbidden),
+	}
+
+	doc.Path("/api/crops").Post = &openapi.Operation{
+		Summary:     "Export every recognized word as a cropped PNG, zipped with a manifest",
+		Parameters:  append(ocrOptionParams(), tenantParams()...),
+		RequestBody: multipartBody(fileSchema("file", "Image to crop")),
+		Responses: withErrors(map[string]openapi.Response{
+			"200": jsonResponse(doc, "Crops saved", model.CropExportResponse{}),
+		}, http.StatusBadRequest, http.StatusRequestEntityTooLarge, http.StatusInternalServerError, http.StatusServiceUnavailable),
+	}
+
+	doc.Path("/api/convert").Post = &openapi.Operation{
+		Summary: "Re-encode an image as PNG or JPEG without running OCR",
+		Parameters: []openapi.Parameter{
+			{Name: "to", In: "query", Description: "Output format", Required: true,
+				Schema: &openapi.Schema{Type: "string", Enum: []string{"png", "jpeg"}}},
+			queryParam("quality", "JPEG quality", &openapi.Schema{Type: "integer", Default: defaultJPEGQuality}),
+		},
+		RequestBody: multipartBody(fileSchema("file", "Image to convert")),
+		Responses: withErrors(map[string]openapi.Response{
+			"200": {
+				Description: "Converted image",
+				Content: map[string]openapi.MediaType{
+					"image/png":  {Schema: &openapi.Schema{Type: "string", Format: "binary"}},
+					"image/jpeg": {Schema: &openapi.Schema{Type: "string", Format: "binary"}},
+				},
+			},
+		}, http.StatusBadRequest, http.StatusRequestEntityTooLarge),
+	}
+
+	doc.Path("/api/batch").Post = &openapi.Operation{
+		Summary: "Process multiple images",
+		Parameters: append([]openapi.Parameter{
+			queryParam("async", "Process in the background and return a job", boolSchema()),
+			queryParam("callback", "URL notified when an async job finishes", &openapi.Schema{Type: "string", Format: "uri"}),
+			queryParam("zip", "Bundle the result files into one ZIP download", boolSchema()),
+			queryParam("annotate", "Also save each image with its word boxes drawn on", boolSchema()),
+		}, tenantParams()...),
+		RequestBody: multipartBody(&openapi.Schema{
+			Type: "object",
+			Properties: map[string]*openapi.Schema{
+				"files": {Type: "array", Items: &openapi.Schema{Type: "string", Format: "binary"}},
+			},
+			Required: []string{"files"},
+		}),
+		Responses: withErrors(map[string]openapi.Response{
+			"200": jsonResponse(doc, "Results of every file", model.BatchProcessResponse{}),
+			"202": jsonResponse(doc, "Async job accepted", model.JobAcceptedResponse{}),
+		}, http.StatusBadRequest, http.StatusServiceUnavailable),
+	}
+
+	doc.Path("/api/jobs/{id}").Get = &openapi.Operation{
+		Summary:    "Async batch job state, progress and results",
+		Parameters: []openapi.Parameter{pathParam("id", "Job ID")},
+		Responses: withErrors(map[string]openapi.Response{
+			"200": jsonResponse(doc, "Job status", model.JobStatus{}),
+		}, http.StatusNotFound),
+	}
+
+	doc.Path("/api/jobs/{id}/events").Get = &openapi.Operation{
+		Summary:    "Async batch job progress as Server-Sent Events",
+		Parameters: []openapi.Parameter{pathParam("id", "Job ID")},
+		Responses: withErrors(map[string]openapi.Response{
+			"200": {
+				Description: "Stream of events whose data is a BatchEvent",
+				Content: map[string]openapi.MediaType{
+					"text/event-stream": {Schema: doc.SchemaOf(model.BatchEvent{})},
+				},
+			},
+		}, http.StatusNotFound),
+	}
+
+	searchForm := fileSchema("file", "Image to search")
+	searchForm.Properties["q"] = &openapi.Schema{Type: "string", Description: "Word or pattern to find"}
+	searchForm.Properties["regex"] = boolSchema()
+	searchForm.Properties["fuzzy"] = boolSchema()
+	searchForm.Properties["distance"] = &openapi.Schema{Type: "integer", Description: "Maximum edit distance for fuzzy matches", Default: defaultFuzzyDistance}
+	searchForm.Required = append(searchForm.Required, "q")
+	doc.Path("/api/search").Post = &openapi.Operation{
+		Summary:     "Find words in an image and return their boxes",
+		Parameters:  ocrOptionParams(),
+		RequestBody: multipartBody(searchForm),
+		Responses: withErrors(map[string]openapi.Response{
+			"200": jsonResponse(doc, "Matching words", model.SearchResponse{}),
+		}, http.StatusBadRequest, http.StatusRequestEntityTooLarge, http.StatusInternalServerError, http.StatusServiceUnavailable),
+	}
+
+	table := jsonResponse(doc, "Cell texts and bounds, by row and column", model.TableResponse{})
+	table.Content["text/csv"] = openapi.MediaType{Schema: &openapi.Schema{Type: "string"}}
+	doc.Path("/api/table").Post = &openapi.Operation{
+		Summary: "Detect the table grid of an image and return the text of each cell",
+		Parameters: append(ocrOptionParams(), queryParam("format", "Response format, otherwise taken from Accept",
+			&openapi.Schema{Type: "string", Enum: []string{formatJSON, formatCSV}, Default: formatJSON})),
+		RequestBody: multipartBody(fileSchema("file", "Image to read")),
+		Responses: withErrors(map[string]openapi.Response{
+			"200": table,
+		}, http.StatusBadRequest, http.StatusRequestEntityTooLarge, http.StatusInternalServerError, http.StatusServiceUnavailable),
+	}
+
+	doc.Path("/api/key-values").Post = &openapi.Operation{
+		Summary:     "Pair form labels ending in a colon with the values right of or below them",
+		Parameters:  ocrOptionParams(),
+		RequestBody: multipartBody(fileSchema("file", "Image to read")),
+		Responses: withErrors(map[string]openapi.Response{
+			"200": jsonResponse(doc, "Label and value pairs", model.KeyValueResponse{}),
+		}, http.StatusBadRequest, http.StatusRequestEntityTooLarge, http.StatusInternalServerError, http.StatusServiceUnavailable),
+	}
+
+	doc.Path("/api/diff").Post = &openapi.Operation{
+		Summary:     "Word-level diff and error rates against a reference text",
+		Parameters:  tenantParams(),
+		RequestBody: jsonBody(doc, model.DiffRequest{}),
+		Responses: withErrors(map[string]openapi.Response{
+			"200": jsonResponse(doc, "Edits and error rates", model.DiffResponse{}),
+		}, http.StatusBadRequest, http.StatusNotFound, http.StatusRequestEntityTooLarge, http.StatusUnprocessableEntity),
+	}
+
+	evaluateForm := fileSchema("file", "Image to read")
+	evaluateForm.Properties["ground_truth"] = &openapi.Schema{Type: "string", Description: "Expected transcript"}
+	evaluateForm.Required = append(evaluateForm.Required, "ground_truth")
+	doc.Path("/api/evaluate").Post = &openapi.Operation{
+		Summary:     "OCR an image and score it against its ground truth",
+		Parameters:  ocrOptionParams(),
+		RequestBody: multipartBody(evaluateForm),
+		Responses: withErrors(map[string]openapi.Response{
+			"200": jsonResponse(doc, "Transcript and error rates", model.EvaluateResponse{}),
+		}, http.StatusBadRequest, http.StatusRequestEntityTooLarge, http.StatusInternalServerError, http.StatusServiceUnavailable),
+	}
+
+	doc.Path("/api/version").Get = &openapi.Operation{
+		Summary: "Tesseract version and build info",
+		Responses: map[string]openapi.Response{
+			"200": jsonResponse(doc, "Version information", model.VersionResponse{}),
+		},
+	}
+
+	doc.Path("/admin/languages").Post = &openapi.Operation{
+		Summary: "Install a custom .traineddata model; requires an admin key and TESSDATA_PREFIX",
+		RequestBody: multipartBody(&openapi.Schema{
+			Type: "object",
+			Properties: map[string]*openapi.Schema{
+				"file": {Type: "string", Format: "binary", Description: "Tesseract .traineddata file"},
+				"lang": {Type: "string", Description: "Language name, defaults to the file name"},
+			},
+			Required: []string{"file"},
+		}),
+		Responses: withErrors(map[string]openapi.Response{
+			"201": jsonResponse(doc, "Model installed", model.LanguageUploadResponse{}),
+		}, http.StatusBadRequest, http.StatusUnauthorized, http.StatusRequestEntityTooLarge, http.StatusServiceUnavailable),
+	}
+
+	doc.Path("/api/stats").Get = &openapi.Operation{
+		Summary: "Documents processed since startup, average confidence and time, top languages",
+		Responses: map[string]openapi.Response{
+			"200": jsonResponse(doc, "Processing statistics", model.StatsResponse{}),
+		},
+	}
+
+	doc.Path("/api/config").Get = &openapi.Operation{
+		Summary: "Effective runtime configuration, without secrets",
+		Responses: map[string]openapi.Response{
+			"200": jsonResponse(doc, "Configuration", model.ConfigResponse{}),
+		},
+	}
+
+	doc.Path("/api/results").Get = &openapi.Operation{
+		Summary:    "List saved results and their total size",
+		Parameters: resultFilterParams(),
+		Responses: withErrors(map[string]openapi.Response{
+			"200": jsonResponse(doc, "Stored result files", model.ListResultsResponse{}),
+		}, http.StatusBadRequest, http.StatusInternalServerError),
+	}
+
+	doc.Path("/api/results/usage").Get = &openapi.Operation{
+		Summary:    "Number and total size of saved results",
+		Parameters: resultFilterParams(),
+		Responses: withErrors(map[string]openapi.Response{
+			"200": jsonResponse(doc, "Storage usage", model.ResultsUsageResponse{}),
+		}, http.StatusBadRequest, http.StatusInternalServerError),
+	}
+
+	doc.Path("/api/results/{filename}").Get = &openapi.Operation{
+		Summary:    "Download a result file",
+		Parameters: append([]openapi.Parameter{pathParam("filename", "Result file name")}, tenantParams()...),
+		Responses: withErrors(map[string]openapi.Response{
+			"200": {
+				Description: "Result JSON or annotated PNG",
+				Content: map[string]openapi.MediaType{
+					"application/json": {Schema: &openapi.Schema{}},
+					"image/png":        {Schema: &openapi.Schema{Type: "string", Format: "binary"}},
+				},
+			},
+			"304": {Description: "Not modified since the ETag in If-None-Match"},
+		}, http.StatusBadRequest, http.StatusNotFound),
+	}
+
+	doc.Path("/api/openapi.json").Get = &openapi.Operation{
+		Summary: "This OpenAPI document",
+		Responses: map[string]openapi.Response{
+			"200": {
+				Description: "OpenAPI 3 document",
+				Content:     map[string]openapi.MediaType{"application/json": {Schema: &openapi.Schema{Type: "object"}}},
+			},
+		},
+	}
+
+	return doc
+}
+
+// jsonResponse describes a JSON response encoding v
+func jsonResponse(doc *openapi.Document, description string, v interface{}) openapi.Response {
+	return openapi.Response{
+		Description: description,
+		Content:     map[string]openapi.MediaType{"application/json": {Schema: doc.SchemaOf(v)}},
+	}
+}
+
+// jsonBody describes a required JSON request body decoded into v
+func jsonBody(doc *openapi.Document, v interface{}) *openapi.RequestBody {
+	return &openapi.RequestBody{
+		Required: true,
+		Content:  map[string]openapi.MediaType{"application/json": {Schema: doc.SchemaOf(v)}},
+	}
+}
+
+// multipartBody describes a required multipart form
+func multipartBody(form *openapi.Schema) *openapi.RequestBody {
+	return &openapi.RequestBody{
+		Required: true,
+		Content:  map[string]openapi.MediaType{"multipart/form-data": {Schema: form}},
+	}
+}
+
+// fileSchema describes a multipart form with a single required file field
+func fileSchema(field, description string) *openapi.Schema {
+	return &openapi.Schema{
+		Type: "object",
+		Properties: map[string]*openapi.Schema{
+			field: {Type: "string", Format: "binary", Description: description},
+		},
+		Required: []string{field},
+	}
+}
+
+// imageSchema describes an image sent as the whole request body
+func imageSchema() *openapi.Schema {
+	return &openapi.Schema{Type: "string", Format: "binary"}
+}
+
+// boolSchema describes a "true"/"false" flag
+func boolSchema() *openapi.Schema {
+	return &openapi.Schema{Type: "boolean"}
+}
+
+// pathParam describes a required path parameter
+func pathParam(name, description string) openapi.Parameter {
+	return openapi.Parameter{Name: name, In: "path", Description: description, Required: true, Schema: &openapi.Schema{Type: "string"}}
+}
+
+// queryParam describes an optional query parameter. Handlers read these
+// with FormValue, so they may also be sent as form fields.
+func queryParam(name, description string, schema *openapi.Schema) openapi.Parameter {
+	return openapi.Parameter{Name: name, In: "query", Description: description, Schema: schema}
+}
+
+// headerParam describes an optional string request header
+func headerParam(name, description string) openapi.Parameter {
+	return openapi.Parameter{Name: name, In: "header", Description: description, Schema: &openapi.Schema{Type: "string"}}
+}
+
+// formatParam describes the response format selector of the extract endpoints
+func formatParam() openapi.Parameter {
+	return queryParam("format", "Response format, otherwise taken from Accept",
+		&openapi.Schema{Type: "string", Enum: []string{formatJSON, formatText, formatCSV}, Default: formatJSON})
+}
+
+// ocrOptionParams describes the engine options read by parseOCROptions
+func ocrOptionParams() []openapi.Parameter {
+	minDPI, maxDPI := float64(ocr.MinDPI), float64(ocr.MaxDPI)
+	minPSM, maxPSM := 1.0, 13.0
+
+	return []openapi.Parameter{
+		queryParam("lang", "Tesseract language, or \"auto\" to detect it", &openapi.Schema{Type: "string"}),
+		queryParam("whitelist", "Only recognize these characters", &openapi.Schema{Type: "string"}),
+		queryParam("blacklist", "Never recognize these characters", &openapi.Schema{Type: "string"}),
+		queryParam("variables", "JSON object of Tesseract variables", &openapi.Schema{Type: "string"}),
+		queryParam("dpi", "Image resolution", &openapi.Schema{Type: "integer", Minimum: &minDPI, Maximum: &maxDPI}),
+		queryParam("psm", "Page segmentation mode", &openapi.Schema{Type: "integer", Minimum: &minPSM, Maximum: &maxPSM}),
+		queryParam("polygons", "Also outline words along the text baseline, returned in polygon", boolSchema()),
+	}
+}
+
+// extractParams describes the options of the extract flow
+func extractParams() []openapi.Parameter {
+	return append(append(ocrOptionParams(),
+		queryParam("coords", "Box coordinates", &openapi.Schema{Type: "string", Enum: []string{"absolute", "normalized"}, Default: "absolute"}),
+		queryParam("layout", "Text layout", &openapi.Schema{Type: "string", Enum: []string{"none", "columns"}}),
+		queryParam("join", "Join full_text in engine order, breaking lines where a box starts more than its height above or below the previous one", &openapi.Schema{Type: "string", Enum: []string{"newline"}}),
+		queryParam("group", "Return a box per word or per phrase of adjacent words", &openapi.Schema{Type: "string", Enum: []string{"word", "phrase"}, Default: "word"}),
+		queryParam("sort", "Box order: as emitted by the engine, or lines top to bottom and words left to right", &openapi.Schema{Type: "string", Enum: []string{"engine", "reading"}, Default: "engine"}),
+		queryParam("preprocess", "Comma-separated preprocessing steps", &openapi.Schema{Type: "string"}),
+		queryParam("upscale", "Enlarge small images before OCR", boolSchema()),
+		queryParam("debug_image", "Save the image the engine saw", boolSchema()),
+		queryParam("thumbnail", "Save a downscaled preview of the upload", boolSchema()),
+		queryParam("detect_codes", "Also decode QR codes and barcodes, returned in codes", boolSchema()),
+		queryParam("mask", `JSON array of {"x", "y", "width", "height"} pixel regions to fill white before OCR`, &openapi.Schema{Type: "string"}),
+		queryParam("frame", "Frame of an animated GIF to recognize, counting from 0", &openapi.Schema{Type: "integer", Default: 0}),
+		queryParam("raw", "Pass the file to the engine undecoded; no preprocessing or engine options", boolSchema()),
+		queryParam("correct", "Correct unreliable words against the dictionary", boolSchema()),
+		queryParam("force", "Run OCR even if an identical result is stored", boolSchema()),
+		queryParam("require_text", "Respond 422 with code no_text when no text is recognized", boolSchema()),
+		queryParam("validate_only", "Only check the image and options; returns valid, width, height and format without running OCR", boolSchema()),
+		headerParam(idempotencyHeader, "Client-chosen key, at most 255 characters; a retry with the same key replays the first response"),
+	), tenantParams()...)
+}
+
+// resultFilterParams describe the filters of the result listing
+func resultFilterParams() []openapi.Parameter {
+	return []openapi.Parameter{
+		queryParam("tenant", "Only include the results of this tenant", &openapi.Schema{Type: "string"}),
+		queryParam("type", "Only include result files of this type", &openapi.Schema{Type: "string", Enum: []string{"json", "png", "jpg", "jpeg", "zip"}}),
+		queryParam("since", "Only include files modified at or after this RFC 3339 time", &openapi.Schema{Type: "string", Format: "date-time"}),
+		queryParam("until", "Only include files modified before this RFC 3339 time", &openapi.Schema{Type: "string", Format: "date-time"}),
+	}
+}
+
+// tenantParams describe the tenant namespacing the saved result files
+func tenantParams() []openapi.Parameter {
+	return []openapi.Parameter{
+		headerParam(tenantHeader, "Tenant whose result files are saved and read: 1 to 64 letters, digits or hyphens"),
+		queryParam("tenant", "Tenant, used when the "+tenantHeader+" header is absent", &openapi.Schema{Type: "string"}),
+	}
+}
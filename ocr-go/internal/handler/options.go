@@ -0,0 +1,281 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/username/ocr-go/internal/jobstore"
+	"github.com/username/ocr-go/internal/model"
+	"github.com/username/ocr-go/internal/ocr"
+	"github.com/username/ocr-go/internal/storage"
+)
+
+// Option configures a Handler
+type Option func(*Handler)
+
+// WithClock sets the clock used for timestamps and durations
+func WithClock(clock Clock) Option {
+	return func(h *Handler) {
+		h.clock = clock
+	}
+}
+
+// WithJobStore sets the store used to track async batch jobs
+func WithJobStore(store jobstore.Store) Option {
+	return func(h *Handler) {
+		h.jobStore = store
+	}
+}
+
+// WithBatchConcurrency sets how many batch files are processed in parallel
+func WithBatchConcurrency(n int) Option {
+	return func(h *Handler) {
+		if n > 0 {
+			h.batchConcurrency = n
+		}
+	}
+}
+
+// WithMaxConcurrentOCR sets how many OCR calls may run at once across all
+// endpoints; single-image requests beyond it get a 503
+func WithMaxConcurrentOCR(n int) Option {
+	return func(h *Handler) {
+		if n > 0 {
+			h.maxConcurrentOCR = n
+		}
+	}
+}
+
+// WithOCRQueue sets how many requests may wait for a free OCR slot and for
+// how long before they get a 503. A zero wait rejects requests as soon as
+// every slot is taken.
+func WithOCRQueue(size int, wait time.Duration) Option {
+	return func(h *Handler) {
+		if size >= 0 {
+			h.ocrQueueSize = size
+		}
+		if wait >= 0 {
+			h.ocrQueueWait = wait
+		}
+	}
+}
+
+// WithMaxBatchFiles sets the maximum number of files accepted per batch
+func WithMaxBatchFiles(n int) Option {
+	return func(h *Handler) {
+		if n > 0 {
+			h.maxBatchFiles = n
+		}
+	}
+}
+
+// WithBatchFileTimeout sets how long OCR may run on each file in a batch
+func WithBatchFileTimeout(d time.Duration) Option {
+	return func(h *Handler) {
+		if d > 0 {
+			h.batchFileTimeout = d
+		}
+	}
+}
+
+// WithMaxUploadSize sets the maximum accepted image size in bytes
+func WithMaxUploadSize(n int64) Option {
+	return func(h *Handler) {
+		if n > 0 {
+			h.maxUploadSize = n
+		}
+	}
+}
+
+// WithMaxImagePixels sets the maximum accepted image width*height, so
+// small files that decode into huge bitmaps are rejected
+func WithMaxImagePixels(n int64) Option {
+	return func(h *Handler) {
+		if n > 0 {
+			h.maxImagePixels = n
+		}
+	}
+}
+
+// WithThumbnailSize sets the longest side, in pixels, of thumbnails saved
+// for thumbnail=true
+func WithThumbnailSize(n int) Option {
+	return func(h *Handler) {
+		if n > 0 {
+			h.thumbnailSize = n
+		}
+	}
+}
+
+// WithStreamMaxFPS sets how many frames per second a live OCR stream
+// processes at most
+func WithStreamMaxFPS(fps float64) Option {
+	return func(h *Handler) {
+		if fps > 0 {
+			h.streamMaxFPS = fps
+		}
+	}
+}
+
+// WithIdempotencyTTL sets how long responses to requests sent with an
+// Idempotency-Key are kept for replay
+func WithIdempotencyTTL(d time.Duration) Option {
+	return func(h *Handler) {
+		if d > 0 {
+			h.idempotencyTTL = d
+		}
+	}
+}
+
+// WithIdempotencyMaxBytes bounds the memory taken by responses kept for
+// Idempotency-Key replay; the least recently used are dropped beyond it
+func WithIdempotencyMaxBytes(n int64) Option {
+	return func(h *Handler) {
+		if n > 0 {
+			h.idempotencyBytes = n
+		}
+	}
+}
+
+// WithPrettyResults sets whether saved JSON results are indented for
+// people reading them directly
+func WithPrettyResults(pretty bool) Option {
+	return func(h *Handler) {
+		h.prettyResults = pretty
+	}
+}
+
+// WithStorage sets where result files are persisted
+func WithStorage(s storage.Storage) Option {
+	return func(h *Handler) {
+		h.storage = s
+	}
+}
+
+// WithOutputDir sets the directory for result files on local storage
+func WithOutputDir(dir string) Option {
+	return func(h *Handler) {
+		h.outputDir = dir
+	}
+}
+
+// WithUploadDir sets the directory for uploaded originals on local storage
+func WithUploadDir(dir string) Option {
+	return func(h *Handler) {
+		h.uploadDir = dir
+	}
+}
+
+// WithUploadStorage sets where uploaded originals are persisted. Replicas
+// sharing result storage need it shared too, or reprocessing an ID saved
+// by another replica finds no original.
+func WithUploadStorage(s storage.Storage) Option {
+	return func(h *Handler) {
+		h.uploads = s
+	}
+}
+
+// WithAutoLanguages sets the candidate languages probed for lang=auto
+func WithAutoLanguages(langs []string) Option {
+	return func(h *Handler) {
+		h.autoLanguages = langs
+	}
+}
+
+// WithDictionaryDir sets the directory holding <lang>.txt word lists used
+// for spelling correction
+func WithDictionaryDir(dir string) Option {
+	return func(h *Handler) {
+		h.dictionaryDir = dir
+	}
+}
+
+// WithTessdataDir sets the directory uploaded language models are
+// installed into; it must be the directory the engine loads them from
+func WithTessdataDir(dir string) Option {
+	return func(h *Handler) {
+		h.tessdataDir = dir
+	}
+}
+
+// WithServerConfig sets the server-level settings reported by Config
+func WithServerConfig(cfg model.ServerConfig) Option {
+	return func(h *Handler) {
+		h.serverConfig = cfg
+	}
+}
+
+// fieldError is a validation error for a single request field
+type fieldError struct {
+	field string
+	err   error
+}
+
+// Error implements error
+func (e *fieldError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the underlying validation error
+func (e *fieldError) Unwrap() error {
+	return e.err
+}
+
+// maxCharFilterLength caps the size of whitelist and blacklist values
+const maxCharFilterLength = 256
+
+// languagePattern matches Tesseract language names such as "eng",
+// "chi_sim" or "spa+eng"
+var languagePattern = regexp.MustCompile(`^[a-z][a-z_]*(\+[a-z][a-z_]*)*$`)
+
+// parseOCROptions reads per-request engine options from form or query values
+func parseOCROptions(r *http.Request) (ocr.Options, error) {
+	opts := ocr.Options{
+		Language:  r.FormValue("lang"),
+		Whitelist: r.FormValue("whitelist"),
+		Blacklist: r.FormValue("blacklist"),
+		Polygons:  r.FormValue("polygons") == "true",
+	}
+	if opts.Language != "" && !languagePattern.MatchString(opts.Language) {
+		return opts, &fieldError{"lang", errors.New("lang must be a Tesseract language such as eng or spa+eng")}
+	}
+	tooLong := fmt.Errorf("whitelist and blacklist are limited to %d characters", maxCharFilterLength)
+	if len(opts.Whitelist) > maxCharFilterLength {
+		return opts, &fieldError{"whitelist", tooLong}
+	}
+	if len(opts.Blacklist) > maxCharFilterLength {
+		return opts, &fieldError{"blacklist", tooLong}
+	}
+
+	if value := r.FormValue("variables"); value != "" {
+		if err := json.Unmarshal([]byte(value), &opts.Variables); err != nil {
+			return opts, &fieldError{"variables", errors.New("variables must be a JSON object of strings")}
+		}
+		if err := ocr.ValidateVariables(opts.Variables); err != nil {
+			return opts, &fieldError{"variables", err}
+		}
+	}
+
+	if value := r.FormValue("dpi"); value != "" {
+		dpi, err := strconv.Atoi(value)
+		if err != nil || dpi < ocr.MinDPI || dpi > ocr.MaxDPI {
+			return opts, &fieldError{"dpi", fmt.Errorf("dpi must be between %d and %d", ocr.MinDPI, ocr.MaxDPI)}
+		}
+		opts.DPI = dpi
+	}
+
+	if value := r.FormValue("psm"); value != "" {
+		psm, err := strconv.Atoi(value)
+		if err != nil || psm < 1 || psm > 13 {
+			return opts, &fieldError{"psm", errors.New("psm must be between 1 and 13")}
+		}
+		opts.PageSegMode = psm
+	}
+
+	return opts, nil
+}
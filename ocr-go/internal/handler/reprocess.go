@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/gofrs/uuid"
+	"github.com/username/ocr-go/internal/model"
+	"github.com/username/ocr-go/internal/storage"
+)
+
+// Reprocess re-runs OCR on a previously uploaded original with new options
+func (h *Handler) Reprocess(w http.ResponseWriter, r *http.Request) {
+	format := responseFormat(r)
+	if !validExtractFormat(format) {
+		h.respondFieldError(w, codeInvalidField, "format", "Unsupported format")
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	if _, err := uuid.FromString(id); err != nil {
+		h.respondError(w, http.StatusBadRequest, "Invalid result ID")
+		return
+	}
+
+	opts, err := parseOCROptions(r)
+	if err != nil {
+		h.respondOptionsError(w, err)
+		return
+	}
+
+	// The stored result names its original, which a reprocessed result
+	// shares with the one it came from
+	tenant, err := requestTenant(r)
+	if err != nil {
+		h.respondFieldError(w, codeInvalidField, "tenant", err.Error())
+		return
+	}
+	resultFile, err := h.findResultFile(r.Context(), tenant, id)
+	var stored model.ExtractTextResponse
+	if err == nil {
+		err = h.loadJSON(r.Context(), resultFile, &stored)
+	}
+	if errors.Is(err, storage.ErrNotFound) {
+		h.respondError(w, http.StatusNotFound, "Result not found")
+		return
+	}
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, "Failed to read result")
+		return
+	}
+
+	data, err := h.loadUpload(r.Context(), stored.SourceFile)
+	if errors.Is(err, storage.ErrNotFound) {
+		h.respondError(w, http.StatusNotFound, "Original upload not found")
+		return
+	}
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, "Failed to read original upload")
+		return
+	}
+
+	h.extractAndRespond(w, r, extractRequest{
+		format:     format,
+		filename:   stored.Filename,
+		data:       data,
+		options:    opts,
+		sourceFile: stored.SourceFile,
+	})
+}
+
+// loadUpload reads a stored original. Results saved when the original
+// could not be stored name none, which is reported as not found.
+func (h *Handler) loadUpload(ctx context.Context, name string) ([]byte, error) {
+	if name == "" {
+		return nil, storage.ErrNotFound
+	}
+	file, _, err := h.uploads.Get(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return io.ReadAll(file)
+}
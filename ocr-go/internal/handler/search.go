@@ -0,0 +1,168 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/username/ocr-go/internal/model"
+	"github.com/username/ocr-go/internal/ocr"
+	"github.com/username/ocr-go/internal/textutil"
+)
+
+// SearchText handles searching for a word within an uploaded image
+func (h *Handler) SearchText(w http.ResponseWriter, r *http.Request) {
+	if !h.parseUploadForm(w, r, h.maxUploadSize, 10<<20) {
+		return
+	}
+
+	query := r.FormValue("q")
+	if query == "" {
+		h.respondFieldError(w, codeMissingField, "q", "Missing search query")
+		return
+	}
+	useRegex := r.FormValue("regex") == "true"
+	fuzzy := r.FormValue("fuzzy") == "true"
+	if useRegex && fuzzy {
+		h.respondFieldError(w, codeInvalidField, "fuzzy", "regex and fuzzy cannot be combined")
+		return
+	}
+
+	maxDistance := defaultFuzzyDistance
+	if value := r.FormValue("distance"); value != "" {
+		d, err := strconv.Atoi(value)
+		if err != nil || d < 0 {
+			h.respondFieldError(w, codeInvalidField, "distance", "Invalid distance")
+			return
+		}
+		maxDistance = d
+	}
+
+	match, err := newMatcher(query, useRegex)
+	if err != nil {
+		h.respondFieldError(w, codeInvalidField, "q",
+			fmt.Sprintf("Invalid regular expression: %v", err))
+		return
+	}
+
+	opts, err := parseOCROptions(r)
+	if err != nil {
+		h.respondOptionsError(w, err)
+		return
+	}
+
+	// Get uploaded file
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		h.respondFieldError(w, codeMissingField, "file", "No file uploaded")
+		return
+	}
+	defer file.Close()
+
+	// Decode image
+	img, _, err := h.decodeImage(file)
+	if err != nil {
+		h.respondDecodeError(w, err)
+		return
+	}
+
+	// Extract text with boxes
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	result, err := h.recognize(ctx, img, opts)
+	if err != nil {
+		h.respondOCRError(w, err)
+		return
+	}
+
+	var matches []model.SearchMatch
+	if fuzzy {
+		matches = fuzzyMatches(result.Boxes, query, maxDistance)
+	} else {
+		matches = make([]model.SearchMatch, 0)
+		for _, box := range result.Boxes {
+			if !match(box.Text) {
+				continue
+			}
+			matches = append(matches, newSearchMatch(box))
+		}
+	}
+
+	response := model.SearchResponse{
+		Filename:     header.Filename,
+		Query:        query,
+		Regex:        useRegex,
+		Fuzzy:        fuzzy,
+		Matches:      matches,
+		TotalMatches: len(matches),
+	}
+	if fuzzy {
+		response.MaxDistance = &maxDistance
+	}
+
+	h.respondJSON(w, http.StatusOK, response)
+}
+
+// defaultFuzzyDistance is the edit distance threshold used when none is given
+const defaultFuzzyDistance = 2
+
+// fuzzyMatches returns boxes within maxDistance edits of the query, closest first
+func fuzzyMatches(boxes []ocr.TextBox, query string, maxDistance int) []model.SearchMatch {
+	needle := strings.ToLower(query)
+
+	matches := make([]model.SearchMatch, 0)
+	for _, box := range boxes {
+		distance := textutil.Levenshtein(needle, strings.ToLower(box.Text))
+		if distance > maxDistance {
+			continue
+		}
+		m := newSearchMatch(box)
+		m.Distance = &distance
+		matches = append(matches, m)
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return *matches[i].Distance < *matches[j].Distance
+	})
+
+	return matches
+}
+
+// newMatcher builds a case-insensitive predicate for the search query
+func newMatcher(query string, useRegex bool) (func(string) bool, error) {
+	if useRegex {
+		re, err := regexp.Compile("(?i)" + query)
+		if err != nil {
+			return nil, err
+		}
+		return re.MatchString, nil
+	}
+
+	needle := strings.ToLower(query)
+	return func(text string) bool {
+		return strings.Contains(strings.ToLower(text), needle)
+	}, nil
+}
+
+// newSearchMatch converts an OCR text box into a search match
+func newSearchMatch(box ocr.TextBox) model.SearchMatch {
+	return model.SearchMatch{
+		Text:       box.Text,
+		Confidence: box.Confidence,
+		BBox: model.BBox{
+			X:      box.Box.X,
+			Y:      box.Box.Y,
+			Width:  box.Box.Width,
+			Height: box.Box.Height,
+		},
+	}
+}
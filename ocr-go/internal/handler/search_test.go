@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"testing"
+
+	"github.com/username/ocr-go/internal/ocr"
+)
+
+func TestFuzzyMatches(t *testing.T) {
+	boxes := []ocr.TextBox{
+		{Text: "Totl"},
+		{Text: "Invoice"},
+		{Text: "TOTAL"},
+		{Text: "Tota1s"},
+		{Text: "Subtotal"},
+		{Text: "Tota"},
+	}
+
+	tests := []struct {
+		name        string
+		maxDistance int
+		want        []string
+		distances   []int
+	}{
+		// Closest first, ties in reading order; case is ignored
+		{"default", 2, []string{"TOTAL", "Totl", "Tota", "Tota1s"}, []int{0, 1, 1, 2}},
+		{"tight", 1, []string{"TOTAL", "Totl", "Tota"}, []int{0, 1, 1}},
+		{"exact", 0, []string{"TOTAL"}, []int{0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := fuzzyMatches(boxes, "total", tt.maxDistance)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d matches, want %v", len(got), tt.want)
+			}
+			for i, m := range got {
+				if m.Text != tt.want[i] || m.Distance == nil || *m.Distance != tt.distances[i] {
+					t.Errorf("match %d = %q at %v, want %q at %d", i, m.Text, m.Distance, tt.want[i], tt.distances[i])
+				}
+			}
+		})
+	}
+
+	if got := fuzzyMatches(boxes, "receipt", 2); got == nil || len(got) != 0 {
+		t.Errorf("no match = %v, want an empty list", got)
+	}
+}
@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/username/ocr-go/internal/model"
+)
+
+// statsTopLanguages is how many languages /api/stats lists
+const statsTopLanguages = 5
+
+// statsCollector keeps running totals of processed documents in memory,
+// so reporting stats never has to scan the stored results
+type statsCollector struct {
+	mu         sync.Mutex
+	since      time.Time
+	documents  int64
+	confidence float64
+	duration   time.Duration
+	languages  map[string]int64
+}
+
+// newStatsCollector returns a collector counting from since
+func newStatsCollector(since time.Time) *statsCollector {
+	return &statsCollector{
+		since:     since,
+		languages: make(map[string]int64),
+	}
+}
+
+// record counts one processed document
+func (s *statsCollector) record(language string, confidence float64, elapsed time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.documents++
+	s.confidence += confidence
+	s.duration += elapsed
+	if language != "" {
+		s.languages[language]++
+	}
+}
+
+// snapshot summarizes the totals recorded so far
+func (s *statsCollector) snapshot() model.StatsResponse {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := model.StatsResponse{
+		DocumentsProcessed: s.documents,
+		TopLanguages:       make([]model.LanguageCount, 0, len(s.languages)),
+		Since:              s.since,
+	}
+	if s.documents > 0 {
+		stats.AverageConfidence = s.confidence / float64(s.documents)
+		stats.AverageProcessingTime = (s.duration / time.Duration(s.documents)).String()
+	}
+
+	for lang, count := range s.languages {
+		stats.TopLanguages = append(stats.TopLanguages, model.LanguageCount{Language: lang, Count: count})
+	}
+	sort.Slice(stats.TopLanguages, func(i, j int) bool {
+		a, b := stats.TopLanguages[i], stats.TopLanguages[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.Language < b.Language
+	})
+	if len(stats.TopLanguages) > statsTopLanguages {
+		stats.TopLanguages = stats.TopLanguages[:statsTopLanguages]
+	}
+	return stats
+}
+
+// Stats summarizes the documents processed since the server started
+func (h *Handler) Stats(w http.ResponseWriter, r *http.Request) {
+	h.respondJSON(w, http.StatusOK, h.stats.snapshot())
+}
@@ -0,0 +1,235 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/username/ocr-go/internal/model"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// streamWriteTimeout bounds sending one frame result to a slow client
+	streamWriteTimeout = 10 * time.Second
+
+	// streamPongWait is how long a connection may stay silent, neither
+	// sending a frame nor answering a ping, before it is closed
+	streamPongWait = 60 * time.Second
+
+	// streamPingPeriod is how often the server pings; it must be shorter
+	// than streamPongWait so a live client always answers in time
+	streamPingPeriod = streamPongWait * 9 / 10
+
+	// streamProtocol is the subprotocol the server accepts. Browsers that
+	// offer an API key as a subprotocol must offer this one as well, since
+	// the handshake fails unless the server picks one of them.
+	streamProtocol = "ocr"
+)
+
+// streamFrame is an encoded image received on a live OCR stream
+type streamFrame struct {
+	seq  int
+	data []byte
+}
+
+// LiveOCR upgrades to a WebSocket for live camera OCR. Clients send each
+// frame as an encoded image in a binary message and get a JSON
+// model.StreamFrame back for every frame processed. Frames are processed
+// at most fps times a second; a frame arriving while the engine is busy
+// replaces the one waiting, so stale frames are dropped instead of queued.
+// The server pings every streamPingPeriod and closes connections that
+// stay silent for streamPongWait.
+func (h *Handler) LiveOCR(w http.ResponseWriter, r *http.Request) {
+	fps := h.streamMaxFPS
+	if value := r.URL.Query().Get("fps"); value != "" {
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil || f <= 0 {
+			h.respondFieldError(w, codeInvalidField, "fps", "fps must be a positive number")
+			return
+		}
+		fps = min(f, fps)
+	}
+
+	upgrader := websocket.Upgrader{
+		CheckOrigin:  h.allowedOrigin,
+		Subprotocols: []string{streamProtocol},
+	}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade has already replied with an HTTP error
+		return
+	}
+	defer conn.Close()
+	conn.SetReadLimit(h.maxUploadSize)
+
+	// Upgrade clears the HTTP server's deadlines; a silent connection is
+	// closed once it misses its pongs
+	conn.SetReadDeadline(time.Now().Add(streamPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(streamPongWait))
+	})
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	go pingStream(ctx, conn)
+
+	pending := make(chan streamFrame, 1)
+	var dropped atomic.Int64
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		// Unblock the read loop if the client stops accepting results
+		defer conn.Close()
+		h.processFrames(ctx, conn, pending, fps, &dropped)
+	}()
+
+	for seq := 0; ; {
+		kind, data, err := conn.ReadMessage()
+		if err != nil {
+			break
+		}
+		conn.SetReadDeadline(time.Now().Add(streamPongWait))
+		if kind != websocket.BinaryMessage {
+			continue
+		}
+
+		frame := streamFrame{seq: seq, data: data}
+		seq++
+		select {
+		case pending <- frame:
+		default:
+			// A frame is already waiting for the engine; replace it
+			select {
+			case <-pending:
+				dropped.Add(1)
+			default:
+			}
+			pending <- frame
+		}
+	}
+
+	cancel()
+	<-done
+}
+
+// pingStream pings conn every streamPingPeriod until ctx is done.
+// WriteControl may run alongside the frame results being written.
+func pingStream(ctx context.Context, conn *websocket.Conn) {
+	ticker := time.NewTicker(streamPingPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(streamWriteTimeout)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// processFrames runs OCR on pending frames, at most fps a second, and
+// writes each result to conn until ctx is done or a write fails
+func (h *Handler) processFrames(ctx context.Context, conn *websocket.Conn, pending chan streamFrame, fps float64, dropped *atomic.Int64) {
+	limiter := rate.NewLimiter(rate.Limit(fps), 1)
+	for {
+		var frame streamFrame
+		select {
+		case <-ctx.Done():
+			return
+		case frame = <-pending:
+		}
+
+		if err := limiter.Wait(ctx); err != nil {
+			return
+		}
+		// Prefer a frame that arrived while throttled
+		select {
+		case newer := <-pending:
+			dropped.Add(1)
+			frame = newer
+		default:
+		}
+
+		result := h.processFrame(ctx, frame)
+		result.Dropped = dropped.Load()
+
+		conn.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+		if err := conn.WriteJSON(result); err != nil {
+			return
+		}
+	}
+}
+
+// processFrame decodes a frame and recognizes its words
+func (h *Handler) processFrame(ctx context.Context, frame streamFrame) model.StreamFrame {
+	result := model.StreamFrame{Frame: frame.seq}
+	start := time.Now()
+
+	img, _, err := h.decodeImage(bytes.NewReader(frame.data))
+	if err != nil {
+		result.Error = fmt.Sprintf("Invalid image: %v", err)
+		return result
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	// A busy server drops the frame, the next one is coming anyway
+	if err := h.tryAcquireOCR(); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	ocrResult, err := h.engine.ExtractTextWithBoxes(ctx, img)
+	h.releaseOCR()
+	if err != nil {
+		result.Error = fmt.Sprintf("OCR failed: %v", err)
+		return result
+	}
+	sanitizeResult(ocrResult)
+
+	result.FullText = ocrResult.FullText
+	result.Boxes = make([]model.WordBox, len(ocrResult.Boxes))
+	for i, box := range ocrResult.Boxes {
+		result.Boxes[i] = model.WordBox{
+			Text:       box.Text,
+			Confidence: box.Confidence,
+			BBox: model.BBox{
+				X:      box.Box.X,
+				Y:      box.Box.Y,
+				Width:  box.Box.Width,
+				Height: box.Box.Height,
+			},
+		}
+	}
+	result.ProcessingTime = time.Since(start).String()
+	return result
+}
+
+// allowedOrigin accepts WebSocket handshakes from the server's own host
+// and from the configured CORS origins
+func (h *Handler) allowedOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err == nil && u.Host == r.Host {
+		return true
+	}
+	for _, allowed := range h.serverConfig.CORSOrigins {
+		if allowed == "*" || allowed == origin {
+			return true
+		}
+	}
+	return false
+}
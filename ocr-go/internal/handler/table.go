@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"context"
+	"encoding/csv"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/username/ocr-go/internal/model"
+	"github.com/username/ocr-go/internal/ocr"
+)
+
+// ExtractTable handles detecting the table grid of an uploaded image and
+// returning the text of each cell
+func (h *Handler) ExtractTable(w http.ResponseWriter, r *http.Request) {
+	format := responseFormat(r)
+	if format != formatJSON && format != formatCSV {
+		h.respondFieldError(w, codeInvalidField, "format", "Unsupported format")
+		return
+	}
+
+	if !h.parseUploadForm(w, r, h.maxUploadSize, 10<<20) {
+		return
+	}
+
+	opts, err := parseOCROptions(r)
+	if err != nil {
+		h.respondOptionsError(w, err)
+		return
+	}
+
+	// Get uploaded file
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		h.respondFieldError(w, codeMissingField, "file", "No file uploaded")
+		return
+	}
+	defer file.Close()
+
+	// Decode image
+	img, _, err := h.decodeImage(file)
+	if err != nil {
+		h.respondDecodeError(w, err)
+		return
+	}
+
+	// Extract text with boxes
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	result, err := h.recognize(ctx, img, opts)
+	if err != nil {
+		h.respondOCRError(w, err)
+		return
+	}
+	sanitizeResult(result)
+
+	table := ocr.DetectTable(result.Boxes)
+	if format == formatCSV {
+		h.respondTableCSV(w, header.Filename, table)
+		return
+	}
+
+	response := model.TableResponse{
+		Filename:   header.Filename,
+		Rows:       len(table.Cells),
+		Cells:      table.Texts(),
+		CellBounds: make([][]model.BBox, len(table.Cells)),
+	}
+	for i, row := range table.Cells {
+		response.Columns = len(row)
+		response.CellBounds[i] = make([]model.BBox, len(row))
+		for j, cell := range row {
+			response.CellBounds[i][j] = model.BBox{
+				X:      cell.Box.X,
+				Y:      cell.Box.Y,
+				Width:  cell.Box.Width,
+				Height: cell.Box.Height,
+			}
+		}
+	}
+
+	h.respondJSON(w, http.StatusOK, response)
+}
+
+// respondTableCSV sends the cell texts as a CSV attachment named after the
+// upload, one record per table row
+func (h *Handler) respondTableCSV(w http.ResponseWriter, uploadName string, table ocr.Table) {
+	name := strings.TrimSuffix(filepath.Base(uploadName), filepath.Ext(uploadName)) + "_table.csv"
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition",
+		mime.FormatMediaType("attachment", map[string]string{"filename": name}))
+	w.WriteHeader(http.StatusOK)
+
+	writer := csv.NewWriter(w)
+	writer.WriteAll(table.Texts())
+}
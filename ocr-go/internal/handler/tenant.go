@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// tenantHeader names the tenant whose results a request creates. The
+// header is not an access control: any client may send any tenant, so it
+// only keeps the files of cooperating clients apart. Isolating untrusted
+// clients needs a proxy that sets the header from the caller's identity.
+const tenantHeader = "X-Tenant-Id"
+
+// tenantNamespace starts the names of tenant files. No other stored name
+// starts with it, so a tenant named like a file kind, such as "ocr" or
+// "thumb", cannot claim the files of that kind.
+const tenantNamespace = "t-"
+
+// tenantPattern limits tenant IDs to characters that are safe in file
+// names. Underscores separate the parts of result names, so a tenant can
+// not contain one and "acme" never matches the results of "acme_eu".
+var tenantPattern = regexp.MustCompile(`^[A-Za-z0-9-]{1,64}$`)
+
+// errInvalidTenant reports a tenant ID outside tenantPattern
+var errInvalidTenant = errors.New("tenant must be 1 to 64 letters, digits or hyphens")
+
+// requestTenant returns the tenant from the X-Tenant-Id header or, when it
+// is absent, the tenant parameter, or "" for requests without one. The
+// value is taken as sent, see tenantHeader.
+func requestTenant(r *http.Request) (string, error) {
+	tenant := r.Header.Get(tenantHeader)
+	if tenant == "" {
+		tenant = r.URL.Query().Get("tenant")
+	}
+	if tenant != "" && !tenantPattern.MatchString(tenant) {
+		return "", errInvalidTenant
+	}
+	return tenant, nil
+}
+
+// tenantPrefix is the prefix of every file name stored for tenant
+func tenantPrefix(tenant string) string {
+	if tenant == "" {
+		return ""
+	}
+	return tenantNamespace + tenant + "_"
+}
+
+// tenantOwns reports whether the stored file name is visible to tenant.
+// Files of a tenant are only visible to it, files saved without a tenant
+// to everyone.
+func tenantOwns(tenant, name string) bool {
+	if !strings.HasPrefix(name, tenantNamespace) {
+		return true
+	}
+	return tenant != "" && strings.HasPrefix(name, tenantPrefix(tenant))
+}
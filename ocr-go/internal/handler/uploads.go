@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+)
+
+// uploadExtensions maps decoder format names to file extensions
+var uploadExtensions = map[string]string{
+	"png":  ".png",
+	"jpeg": ".jpg",
+	"gif":  ".gif",
+}
+
+// saveUpload stores the original image bytes under the result ID and
+// returns the stored name, or "" if the original could not be saved
+func (h *Handler) saveUpload(ctx context.Context, id, format string, data []byte) string {
+	ext, ok := uploadExtensions[format]
+	if !ok {
+		ext = ".bin"
+	}
+	name := id + ext
+
+	if err := h.uploads.Put(ctx, name, bytes.NewReader(data)); err != nil {
+		log.Printf("Failed to save upload %s: %v", name, err)
+		return ""
+	}
+	return name
+}
+
+// multipartOverhead allows for the boundaries, headers and plain fields of
+// a multipart upload on top of its files
+const multipartOverhead = 1 << 20
+
+// parseUploadForm parses a multipart upload of at most limit bytes of
+// files, responding 413 when the body is larger and 400 when it cannot be
+// parsed. memory is the part kept in memory, the rest spills to temporary
+// files, so only the byte limit bounds what a request can make the server
+// store.
+func (h *Handler) parseUploadForm(w http.ResponseWriter, r *http.Request, limit, memory int64) bool {
+	r.Body = http.MaxBytesReader(w, r.Body, limit+multipartOverhead)
+	if err := r.ParseMultipartForm(memory); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			h.respondError(w, http.StatusRequestEntityTooLarge,
+				fmt.Sprintf("Upload exceeds %d bytes", limit))
+			return false
+		}
+		h.respondError(w, http.StatusBadRequest, "Failed to parse form")
+		return false
+	}
+	return true
+}
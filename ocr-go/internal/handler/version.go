@@ -0,0 +1,32 @@
+package handler
+
+import (
+	"net/http"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/username/ocr-go/internal/model"
+)
+
+// Version reports the OCR engine version and Go build information
+func (h *Handler) Version(w http.ResponseWriter, r *http.Request) {
+	response := model.VersionResponse{
+		EngineVersion: h.engine.Version(),
+		GoVersion:     runtime.Version(),
+	}
+
+	if info, ok := debug.ReadBuildInfo(); ok {
+		response.Module = info.Main.Path
+		response.ModuleVersion = info.Main.Version
+		for _, setting := range info.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				response.Revision = setting.Value
+			case "vcs.time":
+				response.BuildTime = setting.Value
+			}
+		}
+	}
+
+	h.respondJSON(w, http.StatusOK, response)
+}
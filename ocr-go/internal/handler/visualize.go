@@ -1,22 +1,27 @@
 package handler
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"image"
 	"image/color"
 	"image/draw"
 	_ "image/gif"
-	_ "image/jpeg"
-	"image/png"
+	"image/jpeg"
 	_ "image/png"
 	"io"
 	"net/http"
-	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/gofrs/uuid"
+	"github.com/username/ocr-go/internal/model"
+	"github.com/username/ocr-go/internal/ocr"
+	"github.com/username/ocr-go/internal/storage"
 	"golang.org/x/image/font"
 	"golang.org/x/image/font/basicfont"
 	"golang.org/x/image/math/fixed"
@@ -24,24 +29,62 @@ import (
 
 // VisualizeBoxes handles bounding box visualization
 func (h *Handler) VisualizeBoxes(w http.ResponseWriter, r *http.Request) {
-	// Parse multipart form (10MB max)
-	if err := r.ParseMultipartForm(10 << 20); err != nil {
-		h.respondError(w, http.StatusBadRequest, "Failed to parse form")
+	if !h.parseUploadForm(w, r, h.maxUploadSize, 10<<20) {
+		return
+	}
+
+	opts, err := parseOCROptions(r)
+	if err != nil {
+		h.respondOptionsError(w, err)
+		return
+	}
+
+	legend := r.FormValue("legend") == "true"
+	corner := r.FormValue("legend_corner")
+	if corner == "" {
+		corner = legendCorners[0]
+	}
+	if !validLegendCorner(corner) {
+		h.respondFieldError(w, codeInvalidField, "legend_corner",
+			"legend_corner must be top-left, top-right, bottom-left or bottom-right")
+		return
+	}
+
+	// Annotated photos are much smaller as JPEG; PNG stays the default
+	// since it keeps the box outlines and labels crisp
+	format := strings.ToLower(r.FormValue("format"))
+	switch format {
+	case "", "png":
+		format = "png"
+	case "jpeg", "jpg":
+		format = "jpeg"
+	default:
+		h.respondFieldError(w, codeInvalidField, "format", "format must be png or jpeg")
+		return
+	}
+	quality, ok := parseJPEGQuality(r.FormValue("quality"))
+	if !ok {
+		h.respondFieldError(w, codeInvalidField, "quality", "quality must be between 1 and 100")
+		return
+	}
+	tenant, err := requestTenant(r)
+	if err != nil {
+		h.respondFieldError(w, codeInvalidField, "tenant", err.Error())
 		return
 	}
 
 	// Get uploaded file
 	file, header, err := r.FormFile("file")
 	if err != nil {
-		h.respondError(w, http.StatusBadRequest, "No file uploaded")
+		h.respondFieldError(w, codeMissingField, "file", "No file uploaded")
 		return
 	}
 	defer file.Close()
 
 	// Decode image
-	img, _, err := image.Decode(file)
+	img, _, err := h.decodeImage(file)
 	if err != nil {
-		h.respondError(w, http.StatusBadRequest, "Invalid image file")
+		h.respondDecodeError(w, err)
 		return
 	}
 
@@ -49,146 +92,386 @@ func (h *Handler) VisualizeBoxes(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
 	defer cancel()
 
-	result, err := h.engine.ExtractTextWithBoxes(ctx, img)
+	result, err := h.recognize(ctx, img, opts)
 	if err != nil {
-		h.respondError(w, http.StatusInternalServerError,
-			fmt.Sprintf("OCR failed: %v", err))
+		h.respondOCRError(w, err)
 		return
 	}
 
 	// Create drawable image
-	bounds := img.Bounds()
-	rgba := image.NewRGBA(bounds)
-	draw.Draw(rgba, bounds, img, bounds.Min, draw.Src)
+	rgba, release := newDrawable(img)
+	defer release()
+
+	// Draw bounding boxes. With a legend, outlines are colored by
+	// confidence so the legend has something to explain.
+	drawBoxes(rgba, result.Boxes, legend)
+
+	if legend {
+		drawLegend(rgba, legendRect(rgba.Bounds(), corner, result.Boxes))
+	}
+
+	// Save annotated image
+	resultID := uuid.Must(uuid.NewV4()).String()
+	outputName := fmt.Sprintf("%sboxes_%s.png", tenantPrefix(tenant), resultID)
+
+	var buf bytes.Buffer
+	if format == "jpeg" {
+		outputName = fmt.Sprintf("%sboxes_%s.jpg", tenantPrefix(tenant), resultID)
+		err = jpeg.Encode(&buf, flatten(rgba), &jpeg.Options{Quality: quality})
+	} else {
+		err = pngEncoder.Encode(&buf, rgba)
+	}
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, "Failed to encode image")
+		return
+	}
+
+	if err := h.storage.Put(r.Context(), outputName, &buf); err != nil {
+		h.respondError(w, http.StatusInternalServerError, "Failed to save image")
+		return
+	}
+
+	// Send response
+	h.respondJSON(w, http.StatusOK, model.VisualizeResponse{
+		Filename:    header.Filename,
+		OutputFile:  outputName,
+		TotalBoxes:  len(result.Boxes),
+		DownloadURL: fmt.Sprintf("/api/results/%s", outputName),
+	})
+}
 
-	// Draw bounding boxes
+// drawBoxes outlines and labels each box, coloring outlines by confidence
+// when byConfidence is set
+func drawBoxes(img *image.RGBA, boxes []ocr.TextBox, byConfidence bool) {
 	green := color.RGBA{0, 255, 0, 255}
 	red := color.RGBA{255, 0, 0, 255}
 
-	for _, box := range result.Boxes {
-		// Draw green rectangle
-		drawRect(rgba, box.Box.X, box.Box.Y,
-			box.Box.X+box.Box.Width, box.Box.Y+box.Box.Height, green, 2)
+	for _, box := range boxes {
+		outline := green
+		if byConfidence {
+			outline = confidenceColor(box.Confidence)
+		}
+
+		if len(box.Polygon) >= 3 {
+			drawPolygon(img, box.Polygon, outline, 2)
+		} else {
+			drawRect(img, box.Box.X, box.Box.Y,
+				box.Box.X+box.Box.Width, box.Box.Y+box.Box.Height, outline, 2)
+		}
 
 		// Draw red text label
 		labelY := box.Box.Y - 5
 		if labelY < 15 {
 			labelY = 15
 		}
-		drawText(rgba, box.Box.X, labelY,
-			fmt.Sprintf("%s (%.0f%%)", box.Text, box.Confidence*100), red)
+		drawText(img, box.Box.X, labelY, boxLabel(box), red)
 	}
+}
 
-	// Save annotated image
-	resultID := uuid.Must(uuid.NewV4()).String()
-	outputPath := filepath.Join("outputs", fmt.Sprintf("boxes_%s.png", resultID))
+// rgbaPool recycles pixel buffers of annotated images so visualizing many
+// large scans does not allocate a full-size image per request
+var rgbaPool sync.Pool
 
-	outputFile, err := os.Create(outputPath)
-	if err != nil {
-		h.respondError(w, http.StatusInternalServerError, "Failed to save image")
-		return
+// newDrawable returns img as an *image.RGBA that may be drawn on, along
+// with a func to call once the image is no longer used. Decoded RGBA images
+// are used as is; others are copied into a pooled buffer.
+func newDrawable(img image.Image) (*image.RGBA, func()) {
+	if rgba, ok := img.(*image.RGBA); ok {
+		return rgba, func() {}
 	}
-	defer outputFile.Close()
 
-	if err := png.Encode(outputFile, rgba); err != nil {
-		h.respondError(w, http.StatusInternalServerError, "Failed to encode image")
-		return
+	bounds := img.Bounds()
+	n := 4 * bounds.Dx() * bounds.Dy()
+	var pix []byte
+	if pooled, ok := rgbaPool.Get().(*[]byte); ok && cap(*pooled) >= n {
+		pix = (*pooled)[:n]
+	} else {
+		pix = make([]byte, n)
 	}
 
-	// Send response
-	h.respondJSON(w, http.StatusOK, map[string]interface{}{
-		"filename":     header.Filename,
-		"output_file":  filepath.Base(outputPath),
-		"total_boxes":  len(result.Boxes),
-		"download_url": fmt.Sprintf("/api/results/%s", filepath.Base(outputPath)),
-	})
+	rgba := &image.RGBA{Pix: pix, Stride: 4 * bounds.Dx(), Rect: bounds}
+	draw.Draw(rgba, bounds, img, bounds.Min, draw.Src)
+	return rgba, func() { rgbaPool.Put(&pix) }
 }
 
 // GetResult serves a result file
 func (h *Handler) GetResult(w http.ResponseWriter, r *http.Request) {
 	filename := filepath.Base(r.URL.Path)
-	filePath := filepath.Join("outputs", filename)
-
-	// Check if file exists
-	if _, err := os.Stat(filePath); os.IsNotExist(err) {
+	tenant, err := requestTenant(r)
+	if err != nil {
+		h.respondFieldError(w, codeInvalidField, "tenant", err.Error())
+		return
+	}
+	if !tenantOwns(tenant, filename) {
 		h.respondError(w, http.StatusNotFound, "File not found")
 		return
 	}
 
-	// Determine content type
-	ext := filepath.Ext(filename)
-	switch ext {
-	case ".json":
-		w.Header().Set("Content-Type", "application/json")
-	case ".png":
-		w.Header().Set("Content-Type", "image/png")
-	default:
-		w.Header().Set("Content-Type", "application/octet-stream")
+	file, obj, err := h.storage.Get(r.Context(), filename)
+	if errors.Is(err, storage.ErrNotFound) {
+		h.respondError(w, http.StatusNotFound, "File not found")
+		return
 	}
-
-	// Serve file
-	file, err := os.Open(filePath)
 	if err != nil {
 		h.respondError(w, http.StatusInternalServerError, "Failed to open file")
 		return
 	}
 	defer file.Close()
 
-	io.Copy(w, file)
+	// ServeContent needs to seek; buffer backends that cannot
+	content, ok := file.(io.ReadSeeker)
+	if !ok {
+		data, err := io.ReadAll(file)
+		if err != nil {
+			h.respondError(w, http.StatusInternalServerError, "Failed to read file")
+			return
+		}
+		content = bytes.NewReader(data)
+	}
+
+	// Result names are unique, so a file never changes once written.
+	// ServeContent handles If-None-Match, If-Modified-Since and ranges.
+	w.Header().Set("Content-Type", storage.ContentType(filename))
+	w.Header().Set("Cache-Control", resultCacheControl)
+	w.Header().Set("ETag", resultETag(obj))
+	http.ServeContent(w, r, filename, obj.ModTime, content)
 }
 
-// ListResults lists all result files
-func (h *Handler) ListResults(w http.ResponseWriter, r *http.Request) {
-	entries, err := os.ReadDir("outputs")
+// resultCacheControl lets clients keep result files for a day
+const resultCacheControl = "private, max-age=86400"
+
+// resultETag derives a validator from the size and modification time of a
+// stored result
+func resultETag(obj storage.Object) string {
+	return fmt.Sprintf(`"%x-%x"`, obj.Size, obj.ModTime.UnixNano())
+}
+
+// resultTypes maps the type filter of ListResults to the extension of the
+// result files written with it: JSON results, annotated images, previews
+// and crop or batch archives
+var resultTypes = map[string]string{
+	"json": ".json",
+	"png":  ".png",
+	"jpg":  ".jpg",
+	"jpeg": ".jpg",
+	"zip":  ".zip",
+}
+
+// resultFilter narrows the listing of result files
+type resultFilter struct {
+	tenant string
+	// allTenants also keeps the files of other tenants when tenant is
+	// empty, otherwise only files saved without a tenant are kept
+	allTenants bool
+	// ext is the file extension to keep, with its dot
+	ext          string
+	since, until time.Time
+}
+
+// parseResultFilter reads the tenant, type, since and until parameters of
+// ListResults, reporting the offending field of an invalid one
+func parseResultFilter(r *http.Request) (resultFilter, string, error) {
+	query := r.URL.Query()
+	var filter resultFilter
+
+	tenant, err := requestTenant(r)
 	if err != nil {
-		h.respondError(w, http.StatusInternalServerError, "Failed to read outputs directory")
-		return
+		return filter, "tenant", err
 	}
+	filter.tenant = tenant
 
-	files := make([]map[string]interface{}, 0, len(entries))
-	for _, entry := range entries {
-		if entry.IsDir() {
+	if kind := query.Get("type"); kind != "" {
+		ext, ok := resultTypes[kind]
+		if !ok {
+			return filter, "type", fmt.Errorf("type must be json, png, jpg, jpeg or zip, got %q", kind)
+		}
+		filter.ext = ext
+	}
+
+	for _, bound := range []struct {
+		field string
+		t     *time.Time
+	}{{"since", &filter.since}, {"until", &filter.until}} {
+		value := query.Get(bound.field)
+		if value == "" {
 			continue
 		}
-		info, err := entry.Info()
+		t, err := time.Parse(time.RFC3339, value)
 		if err != nil {
+			return filter, bound.field, fmt.Errorf("%s must be an RFC 3339 time, got %q", bound.field, value)
+		}
+		*bound.t = t
+	}
+	if !filter.since.IsZero() && !filter.until.IsZero() && filter.until.Before(filter.since) {
+		return filter, "until", errors.New("until must not be before since")
+	}
+	return filter, "", nil
+}
+
+// match reports whether obj passes the filter. since is inclusive, until
+// exclusive, so consecutive ranges never list a file twice.
+func (f resultFilter) match(obj storage.Object) bool {
+	switch {
+	case f.tenant != "" && !strings.HasPrefix(obj.Name, tenantPrefix(f.tenant)):
+		return false
+	case f.tenant == "" && !f.allTenants && !tenantOwns("", obj.Name):
+		return false
+	case f.ext != "" && filepath.Ext(obj.Name) != f.ext:
+		return false
+	case !f.since.IsZero() && obj.ModTime.Before(f.since):
+		return false
+	case !f.until.IsZero() && !obj.ModTime.Before(f.until):
+		return false
+	}
+	return true
+}
+
+// ListResults lists the result files, narrowed by tenant, type and
+// modification time
+func (h *Handler) ListResults(w http.ResponseWriter, r *http.Request) {
+	filter, field, err := parseResultFilter(r)
+	if err != nil {
+		h.respondFieldError(w, codeInvalidField, field, err.Error())
+		return
+	}
+
+	objects, err := h.storage.List(r.Context())
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, "Failed to list results")
+		return
+	}
+
+	files := make([]model.ResultFile, 0, len(objects))
+	var total int64
+	for _, obj := range objects {
+		if !filter.match(obj) {
 			continue
 		}
-		files = append(files, map[string]interface{}{
-			"name":     entry.Name(),
-			"size":     info.Size(),
-			"modified": info.ModTime().Format(time.RFC3339),
+		files = append(files, model.ResultFile{
+			Name:     obj.Name,
+			Size:     obj.Size,
+			Modified: obj.ModTime.Format(time.RFC3339),
 		})
+		total += obj.Size
 	}
 
-	h.respondJSON(w, http.StatusOK, map[string]interface{}{
-		"files": files,
-		"count": len(files),
+	h.respondJSON(w, http.StatusOK, model.ListResultsResponse{
+		Files:     files,
+		Count:     len(files),
+		TotalSize: total,
 	})
 }
 
-// Helper function to draw rectangle on image
+// ResultsUsage reports how many result files are stored and the bytes they
+// take, with the same filters as ListResults but without the file list.
+// Unlike the listing it counts the files of every tenant unless one is
+// given.
+func (h *Handler) ResultsUsage(w http.ResponseWriter, r *http.Request) {
+	filter, field, err := parseResultFilter(r)
+	if err != nil {
+		h.respondFieldError(w, codeInvalidField, field, err.Error())
+		return
+	}
+	// Without a tenant the usage covers the whole storage, for operators
+	filter.allTenants = filter.tenant == ""
+
+	objects, err := h.storage.List(r.Context())
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, "Failed to list results")
+		return
+	}
+
+	var usage model.ResultsUsageResponse
+	var oldest, newest time.Time
+	for _, obj := range objects {
+		if !filter.match(obj) {
+			continue
+		}
+		usage.Count++
+		usage.TotalSize += obj.Size
+		if oldest.IsZero() || obj.ModTime.Before(oldest) {
+			oldest = obj.ModTime
+		}
+		if obj.ModTime.After(newest) {
+			newest = obj.ModTime
+		}
+	}
+	if usage.Count > 0 {
+		usage.Oldest, usage.Newest = oldest.Format(time.RFC3339), newest.Format(time.RFC3339)
+	}
+
+	h.respondJSON(w, http.StatusOK, usage)
+}
+
+// Helper function to draw rectangle on image. The corners (x2, y2) are
+// inclusive. Edges are filled as rectangles rather than pixel by pixel;
+// rectangles are built as literals so inverted coordinates draw nothing
+// instead of being swapped by image.Rect.
 func drawRect(img *image.RGBA, x1, y1, x2, y2 int, c color.Color, thickness int) {
-	for t := 0; t < thickness; t++ {
-		// Top edge
-		for x := x1; x <= x2; x++ {
-			img.Set(x, y1+t, c)
+	src := image.NewUniform(c)
+	edges := [4]image.Rectangle{
+		{image.Pt(x1, y1), image.Pt(x2+1, y1+thickness)},     // Top edge
+		{image.Pt(x1, y2-thickness+1), image.Pt(x2+1, y2+1)}, // Bottom edge
+		{image.Pt(x1, y1), image.Pt(x1+thickness, y2+1)},     // Left edge
+		{image.Pt(x2-thickness+1, y1), image.Pt(x2+1, y2+1)}, // Right edge
+	}
+	for _, edge := range edges {
+		draw.Draw(img, edge, src, image.Point{}, draw.Src)
+	}
+}
+
+// Helper function to draw a closed polygon on image
+func drawPolygon(img *image.RGBA, points []ocr.Point, c color.Color, thickness int) {
+	for i, p := range points {
+		q := points[(i+1)%len(points)]
+		for t := 0; t < thickness; t++ {
+			drawLine(img, p.X+t, p.Y, q.X+t, q.Y, c)
+			drawLine(img, p.X, p.Y+t, q.X, q.Y+t, c)
 		}
-		// Bottom edge
-		for x := x1; x <= x2; x++ {
-			img.Set(x, y2-t, c)
+	}
+}
+
+// Helper function to draw a line on image using Bresenham's algorithm
+func drawLine(img *image.RGBA, x1, y1, x2, y2 int, c color.Color) {
+	dx, dy := abs(x2-x1), -abs(y2-y1)
+	sx, sy := 1, 1
+	if x1 > x2 {
+		sx = -1
+	}
+	if y1 > y2 {
+		sy = -1
+	}
+
+	for e := dx + dy; ; {
+		img.Set(x1, y1, c)
+		if x1 == x2 && y1 == y2 {
+			return
 		}
-		// Left edge
-		for y := y1; y <= y2; y++ {
-			img.Set(x1+t, y, c)
+		e2 := 2 * e
+		if e2 >= dy {
+			e += dy
+			x1 += sx
 		}
-		// Right edge
-		for y := y1; y <= y2; y++ {
-			img.Set(x2-t, y, c)
+		if e2 <= dx {
+			e += dx
+			y1 += sy
 		}
 	}
 }
 
+// abs returns the absolute value of n
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// boxLabel is the text drawn above a box
+func boxLabel(box ocr.TextBox) string {
+	return fmt.Sprintf("%s (%.0f%%)", box.Text, box.Confidence*100)
+}
+
 // Helper function to draw text on image
 func drawText(img *image.RGBA, x, y int, text string, c color.Color) {
 	point := fixed.Point26_6{
@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/username/ocr-go/internal/ocr"
+)
+
+// benchmarkBoxes returns a grid of word-sized boxes covering a 4K page
+func benchmarkBoxes() []image.Rectangle {
+	var boxes []image.Rectangle
+	for y := 20; y < 2100; y += 40 {
+		for x := 20; x < 3800; x += 160 {
+			boxes = append(boxes, image.Rect(x, y, x+140, y+30))
+		}
+	}
+	return boxes
+}
+
+// drawRectPerPixel is the original pixel-by-pixel drawRect, kept as the
+// reference for output and speed
+func drawRectPerPixel(img *image.RGBA, x1, y1, x2, y2 int, c color.Color, thickness int) {
+	for t := 0; t < thickness; t++ {
+		for x := x1; x <= x2; x++ {
+			img.Set(x, y1+t, c)
+			img.Set(x, y2-t, c)
+		}
+		for y := y1; y <= y2; y++ {
+			img.Set(x1+t, y, c)
+			img.Set(x2-t, y, c)
+		}
+	}
+}
+
+func TestDrawRectMatchesPerPixel(t *testing.T) {
+	rects := []struct{ x1, y1, x2, y2, thickness int }{
+		{10, 10, 50, 30, 2},
+		{10, 10, 11, 11, 3},
+		{-5, -5, 20, 20, 2},
+		{90, 90, 120, 120, 1},
+		{40, 40, 30, 60, 2},
+		{0, 0, 99, 99, 0},
+	}
+	green := color.RGBA{0, 255, 0, 255}
+
+	for _, r := range rects {
+		got := image.NewRGBA(image.Rect(0, 0, 100, 100))
+		want := image.NewRGBA(image.Rect(0, 0, 100, 100))
+
+		drawRect(got, r.x1, r.y1, r.x2, r.y2, green, r.thickness)
+		drawRectPerPixel(want, r.x1, r.y1, r.x2, r.y2, green, r.thickness)
+
+		for i := range want.Pix {
+			if got.Pix[i] != want.Pix[i] {
+				t.Errorf("drawRect%v differs from the per-pixel version", r)
+				break
+			}
+		}
+	}
+}
+
+func BenchmarkDrawRectPerPixel(b *testing.B) {
+	img := image.NewRGBA(image.Rect(0, 0, 3840, 2160))
+	boxes := benchmarkBoxes()
+	green := color.RGBA{0, 255, 0, 255}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		for _, box := range boxes {
+			drawRectPerPixel(img, box.Min.X, box.Min.Y, box.Max.X, box.Max.Y, green, 2)
+		}
+	}
+}
+
+func BenchmarkDrawRect(b *testing.B) {
+	img := image.NewRGBA(image.Rect(0, 0, 3840, 2160))
+	boxes := benchmarkBoxes()
+	green := color.RGBA{0, 255, 0, 255}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		for _, box := range boxes {
+			drawRect(img, box.Min.X, box.Min.Y, box.Max.X, box.Max.Y, green, 2)
+		}
+	}
+}
+
+func BenchmarkDrawText(b *testing.B) {
+	img := image.NewRGBA(image.Rect(0, 0, 3840, 2160))
+	boxes := benchmarkBoxes()
+	red := color.RGBA{255, 0, 0, 255}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		for _, box := range boxes {
+			drawText(img, box.Min.X, box.Min.Y-5, "Invoice (93%)", red)
+		}
+	}
+}
+
+func BenchmarkNewDrawable(b *testing.B) {
+	// JPEG scans decode to YCbCr, which always needs converting
+	img := image.NewYCbCr(image.Rect(0, 0, 2480, 3508), image.YCbCrSubsampleRatio420)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		_, release := newDrawable(img)
+		release()
+	}
+}
+
+func TestDrawBoxesPolygon(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 100, 100))
+	drawBoxes(img, []ocr.TextBox{{
+		Text:    "skewed",
+		Box:     ocr.BoundingBox{X: 10, Y: 40, Width: 60, Height: 30},
+		Polygon: []ocr.Point{{X: 10, Y: 40}, {X: 70, Y: 55}, {X: 70, Y: 70}, {X: 10, Y: 55}},
+	}}, false)
+
+	green := color.RGBA{0, 255, 0, 255}
+	// The sloped top edge is drawn, the rectangle corner it cuts off is not
+	if img.RGBAAt(40, 47) != green {
+		t.Errorf("pixel on the top edge = %v, want green", img.RGBAAt(40, 47))
+	}
+	if img.RGBAAt(69, 41) == green {
+		t.Error("rectangle corner outside the polygon is drawn")
+	}
+}
@@ -0,0 +1,157 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+const (
+	// watchSettleDelay is how long a watched file must go without writes
+	// before it is processed, so files still being copied in are not read
+	// half written
+	watchSettleDelay = 500 * time.Millisecond
+
+	// watchProcessedDir and watchFailedDir are the subdirectories of the
+	// watched directory that files are moved to once processed
+	watchProcessedDir = "processed"
+	watchFailedDir    = "failed"
+)
+
+// watchExtensions are the file extensions picked up from the watched
+// directory, other files are left alone
+var watchExtensions = map[string]bool{
+	".png":  true,
+	".jpg":  true,
+	".jpeg": true,
+	".gif":  true,
+}
+
+// WatchDir OCRs the image files dropped into dir until ctx is done, saving
+// each result like a batch file. Files already in dir are processed first,
+// so nothing dropped while the server was down is missed. A processed file
+// is moved to the processed subdirectory, or to failed when it could not
+// be read or recognized; a file of the same name already there is
+// replaced. Files are processed one at a time and take OCR slots like
+// requests do.
+func (h *Handler) WatchDir(ctx context.Context, dir string) error {
+	for _, sub := range []string{watchProcessedDir, watchFailedDir} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0755); err != nil {
+			return err
+		}
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+	if err := watcher.Add(dir); err != nil {
+		return err
+	}
+
+	// Every write restarts the file's timer, a file is handed over once
+	// its timer fires
+	pending := make(map[string]*time.Timer)
+	ready := make(chan string)
+	schedule := func(path string) {
+		if timer, ok := pending[path]; ok {
+			timer.Reset(watchSettleDelay)
+			return
+		}
+		pending[path] = time.AfterFunc(watchSettleDelay, func() {
+			select {
+			case ready <- path:
+			case <-ctx.Done():
+			}
+		})
+	}
+	defer func() {
+		for _, timer := range pending {
+			timer.Stop()
+		}
+	}()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() && watchable(entry.Name()) {
+			schedule(filepath.Join(dir, entry.Name()))
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if (event.Has(fsnotify.Create) || event.Has(fsnotify.Write)) && watchable(event.Name) {
+				schedule(event.Name)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			log.Printf("Watching %s: %v", dir, err)
+		case path := <-ready:
+			delete(pending, path)
+			h.processWatched(ctx, dir, path)
+		}
+	}
+}
+
+// watchable reports whether name has one of the watched image extensions
+func watchable(name string) bool {
+	return watchExtensions[strings.ToLower(filepath.Ext(name))]
+}
+
+// processWatched OCRs the watched file at path and moves it out of dir.
+// A file interrupted by shutdown stays in place to be processed on the
+// next start.
+func (h *Handler) processWatched(ctx context.Context, dir, path string) {
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) || (err == nil && info.IsDir()) {
+		// Already moved, or not a file after all
+		return
+	}
+	if err != nil {
+		log.Printf("Watched file %s: %v", path, err)
+		return
+	}
+
+	name := filepath.Base(path)
+	result := h.safeProcessFile(ctx, 0, batchFile{
+		name: name,
+		size: info.Size(),
+		open: func() (io.ReadSeekCloser, error) {
+			return os.Open(path)
+		},
+	}, batchOptions{})
+	if ctx.Err() != nil {
+		return
+	}
+
+	dest := watchFailedDir
+	if result.Success {
+		dest = watchProcessedDir
+		log.Printf("Watched file %s: saved %s", name, result.OutputFile)
+	} else {
+		log.Printf("Watched file %s: %s", name, result.Error)
+	}
+	if err := os.Rename(path, filepath.Join(dir, dest, name)); err != nil {
+		log.Printf("Watched file %s: failed to move to %s: %v", name, dest, err)
+	}
+}
@@ -0,0 +1,79 @@
+// Package imageinfo reads metadata that the standard image decoders discard
+package imageinfo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+)
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// DPI returns the horizontal resolution embedded in a PNG (pHYs chunk) or
+// JPEG (JFIF header) image, or 0 if the image carries none
+func DPI(data []byte) int {
+	switch {
+	case bytes.HasPrefix(data, pngSignature):
+		return pngDPI(data[len(pngSignature):])
+	case bytes.HasPrefix(data, []byte{0xFF, 0xD8}):
+		return jpegDPI(data[2:])
+	}
+	return 0
+}
+
+// pngDPI scans the chunks preceding the image data for pHYs
+func pngDPI(data []byte) int {
+	for len(data) >= 12 {
+		length := int(binary.BigEndian.Uint32(data[:4]))
+		kind := string(data[4:8])
+		if length < 0 || len(data) < 12+length {
+			return 0
+		}
+		chunk := data[8 : 8+length]
+
+		switch kind {
+		case "pHYs":
+			// Unit 1 is pixels per metre; 0 only gives the aspect ratio
+			if length < 9 || chunk[8] != 1 {
+				return 0
+			}
+			ppm := binary.BigEndian.Uint32(chunk[:4])
+			return int(math.Round(float64(ppm) * 0.0254))
+		case "IDAT", "IEND":
+			return 0
+		}
+		data = data[12+length:]
+	}
+	return 0
+}
+
+// jpegDPI scans the segments preceding the scan data for a JFIF APP0 header
+func jpegDPI(data []byte) int {
+	for len(data) >= 4 && data[0] == 0xFF {
+		marker := data[1]
+		length := int(binary.BigEndian.Uint16(data[2:4]))
+		if length < 2 || len(data) < 2+length {
+			return 0
+		}
+		segment := data[4 : 2+length]
+
+		switch marker {
+		case 0xE0:
+			if len(segment) < 12 || !bytes.HasPrefix(segment, []byte("JFIF\x00")) {
+				break
+			}
+			density := int(binary.BigEndian.Uint16(segment[8:10]))
+			switch segment[7] {
+			case 1: // dots per inch
+				return density
+			case 2: // dots per centimetre
+				return int(math.Round(float64(density) * 2.54))
+			}
+			return 0
+		case 0xDA:
+			return 0
+		}
+		data = data[2+length:]
+	}
+	return 0
+}
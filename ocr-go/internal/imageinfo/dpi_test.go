@@ -0,0 +1,53 @@
+package imageinfo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// pngWithChunk returns a PNG signature followed by a single chunk; the CRC
+// is left zero since DPI does not verify it
+func pngWithChunk(kind string, data []byte) []byte {
+	var buf bytes.Buffer
+	buf.Write(pngSignature)
+	binary.Write(&buf, binary.BigEndian, uint32(len(data)))
+	buf.WriteString(kind)
+	buf.Write(data)
+	buf.Write(make([]byte, 4))
+	return buf.Bytes()
+}
+
+func TestDPI(t *testing.T) {
+	phys := make([]byte, 9)
+	binary.BigEndian.PutUint32(phys[0:], 11811) // 300 dpi in pixels per metre
+	binary.BigEndian.PutUint32(phys[4:], 11811)
+	phys[8] = 1
+
+	jfif := []byte{
+		0xFF, 0xD8,
+		0xFF, 0xE0, 0x00, 0x10, 'J', 'F', 'I', 'F', 0x00, 0x01, 0x01,
+		0x01, 0x00, 0xC8, 0x00, 0xC8, 0x00, 0x00,
+		0xFF, 0xDA,
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+		want int
+	}{
+		{"png pHYs", pngWithChunk("pHYs", phys), 300},
+		{"png without pHYs", pngWithChunk("IDAT", nil), 0},
+		{"jpeg JFIF", jfif, 200},
+		{"unknown format", []byte("GIF89a"), 0},
+		{"truncated", pngSignature, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DPI(tt.data); got != tt.want {
+				t.Errorf("DPI() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
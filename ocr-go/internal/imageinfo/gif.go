@@ -0,0 +1,84 @@
+package imageinfo
+
+import (
+	"encoding/binary"
+	"errors"
+	"image"
+)
+
+// ErrInvalidGIF is returned for data that is not a well-formed GIF
+var ErrInvalidGIF = errors.New("invalid GIF block structure")
+
+// GIFFrame is one image of a GIF as laid out in the file
+type GIFFrame struct {
+	// Bounds is the frame rectangle on the logical screen
+	Bounds image.Rectangle
+	// End is the offset just past the frame's image data
+	End int
+}
+
+// GIFFrames walks the blocks of a GIF and returns its frames without
+// decompressing any of them, so the work of decoding can be bounded before
+// it is done
+func GIFFrames(data []byte) ([]GIFFrame, error) {
+	if len(data) < 13 || (string(data[:6]) != "GIF87a" && string(data[:6]) != "GIF89a") {
+		return nil, ErrInvalidGIF
+	}
+	pos := 13 + colorTableSize(data[10])
+
+	var frames []GIFFrame
+	for pos < len(data) {
+		switch data[pos] {
+		case 0x3B: // Trailer
+			return frames, nil
+		case 0x21: // Extension: label, then data sub-blocks
+			end, ok := skipSubBlocks(data, pos+2)
+			if !ok {
+				return nil, ErrInvalidGIF
+			}
+			pos = end
+		case 0x2C: // Image descriptor, color table, LZW code size, data
+			if pos+10 > len(data) {
+				return nil, ErrInvalidGIF
+			}
+			d := data[pos+1 : pos+10]
+			left, top := int(binary.LittleEndian.Uint16(d[0:])), int(binary.LittleEndian.Uint16(d[2:]))
+			width, height := int(binary.LittleEndian.Uint16(d[4:])), int(binary.LittleEndian.Uint16(d[6:]))
+			end, ok := skipSubBlocks(data, pos+10+colorTableSize(d[8])+1)
+			if !ok {
+				return nil, ErrInvalidGIF
+			}
+			frames = append(frames, GIFFrame{
+				Bounds: image.Rect(left, top, left+width, top+height),
+				End:    end,
+			})
+			pos = end
+		default:
+			return nil, ErrInvalidGIF
+		}
+	}
+	// A missing trailer is tolerated, like image/gif does
+	return frames, nil
+}
+
+// colorTableSize returns the size in bytes of the color table announced by
+// the packed fields of a screen or image descriptor
+func colorTableSize(packed byte) int {
+	if packed&0x80 == 0 {
+		return 0
+	}
+	return 3 << ((packed & 0x07) + 1)
+}
+
+// skipSubBlocks returns the offset past the sub-blocks starting at pos,
+// which end with an empty block
+func skipSubBlocks(data []byte, pos int) (int, bool) {
+	for pos < len(data) {
+		size := int(data[pos])
+		pos += 1 + size
+		if size == 0 {
+			return pos, pos <= len(data)
+		}
+	}
+	return 0, false
+}
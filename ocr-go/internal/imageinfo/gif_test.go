@@ -0,0 +1,48 @@
+package imageinfo
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"testing"
+)
+
+func TestGIFFrames(t *testing.T) {
+	palette := color.Palette{color.Black, color.White}
+	var buf bytes.Buffer
+	err := gif.EncodeAll(&buf, &gif.GIF{
+		Image: []*image.Paletted{
+			image.NewPaletted(image.Rect(0, 0, 16, 16), palette),
+			image.NewPaletted(image.Rect(4, 2, 8, 16), palette),
+		},
+		Delay: []int{10, 10},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	frames, err := GIFFrames(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(frames) != 2 || frames[0].Bounds != image.Rect(0, 0, 16, 16) || frames[1].Bounds != image.Rect(4, 2, 8, 16) {
+		t.Fatalf("GIFFrames() = %+v, want the two frame rectangles", frames)
+	}
+	if frames[1].End != len(data)-1 {
+		t.Errorf("last frame ends at %d, want %d before the trailer", frames[1].End, len(data)-1)
+	}
+
+	// Cutting after the first frame leaves a GIF of that frame alone
+	first, err := gif.DecodeAll(bytes.NewReader(append(data[:frames[0].End:frames[0].End], 0x3B)))
+	if err != nil || len(first.Image) != 1 {
+		t.Errorf("first frame alone: %d frames, %v", len(first.Image), err)
+	}
+
+	for _, bad := range [][]byte{nil, []byte("GIF89a"), []byte("PNG"), data[:len(data)-10]} {
+		if _, err := GIFFrames(bad); err == nil {
+			t.Errorf("GIFFrames(%q) succeeded, want an error", bad)
+		}
+	}
+}
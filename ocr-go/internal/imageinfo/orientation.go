@@ -0,0 +1,108 @@
+package imageinfo
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// exifOrientationTag is the TIFF tag holding the EXIF orientation
+const exifOrientationTag = 0x0112
+
+// Orientation returns the EXIF orientation (1-8) of a JPEG (APP1 segment)
+// or PNG (eXIf chunk) image, or 0 if the image carries none. Orientation 1
+// is upright; the others tell how the stored pixels must be rotated or
+// flipped for display, e.g. 6 for a phone held upright.
+func Orientation(data []byte) int {
+	switch {
+	case bytes.HasPrefix(data, pngSignature):
+		return pngOrientation(data[len(pngSignature):])
+	case bytes.HasPrefix(data, []byte{0xFF, 0xD8}):
+		return jpegOrientation(data[2:])
+	}
+	return 0
+}
+
+// jpegOrientation scans the segments preceding the scan data for an Exif
+// APP1 segment
+func jpegOrientation(data []byte) int {
+	for len(data) >= 4 && data[0] == 0xFF {
+		marker := data[1]
+		length := int(binary.BigEndian.Uint16(data[2:4]))
+		if length < 2 || len(data) < 2+length {
+			return 0
+		}
+		segment := data[4 : 2+length]
+
+		switch marker {
+		case 0xE1:
+			if bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+				return tiffOrientation(segment[6:])
+			}
+		case 0xDA:
+			return 0
+		}
+		data = data[2+length:]
+	}
+	return 0
+}
+
+// pngOrientation scans the chunks preceding the image data for eXIf
+func pngOrientation(data []byte) int {
+	for len(data) >= 12 {
+		length := int(binary.BigEndian.Uint32(data[:4]))
+		kind := string(data[4:8])
+		if length < 0 || len(data) < 12+length {
+			return 0
+		}
+
+		switch kind {
+		case "eXIf":
+			return tiffOrientation(data[8 : 8+length])
+		case "IDAT", "IEND":
+			return 0
+		}
+		data = data[12+length:]
+	}
+	return 0
+}
+
+// tiffOrientation reads the orientation tag from the first IFD of the
+// TIFF structure that holds EXIF data
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 0
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 0
+	}
+	if order.Uint16(tiff[2:4]) != 42 {
+		return 0
+	}
+
+	offset := int64(order.Uint32(tiff[4:8]))
+	if offset+2 > int64(len(tiff)) {
+		return 0
+	}
+	ifd := tiff[offset:]
+	entries := int(order.Uint16(ifd[:2]))
+	ifd = ifd[2:]
+	for i := 0; i < entries && len(ifd) >= 12; i++ {
+		entry := ifd[:12]
+		ifd = ifd[12:]
+		// The value is a single SHORT stored in the entry itself
+		if order.Uint16(entry[:2]) != exifOrientationTag || order.Uint16(entry[2:4]) != 3 {
+			continue
+		}
+		if value := int(order.Uint16(entry[8:10])); value >= 1 && value <= 8 {
+			return value
+		}
+		return 0
+	}
+	return 0
+}
@@ -0,0 +1,70 @@
+package imageinfo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// exifTIFF returns a TIFF structure whose first IFD holds a software tag
+// followed by the orientation tag
+func exifTIFF(order binary.ByteOrder, orientation uint16) []byte {
+	var buf bytes.Buffer
+	if order == binary.LittleEndian {
+		buf.WriteString("II")
+	} else {
+		buf.WriteString("MM")
+	}
+	binary.Write(&buf, order, uint16(42))
+	binary.Write(&buf, order, uint32(8))
+
+	binary.Write(&buf, order, uint16(2))
+	for _, entry := range [][]uint16{{0x0131, 2}, {exifOrientationTag, 3}} {
+		binary.Write(&buf, order, entry[0])
+		binary.Write(&buf, order, entry[1])
+		binary.Write(&buf, order, uint32(1))
+		value := make([]byte, 4)
+		if entry[0] == exifOrientationTag {
+			order.PutUint16(value, orientation)
+		}
+		buf.Write(value)
+	}
+	binary.Write(&buf, order, uint32(0))
+	return buf.Bytes()
+}
+
+// jpegWithExif returns a JPEG header with an Exif APP1 segment
+func jpegWithExif(tiff []byte) []byte {
+	segment := append([]byte("Exif\x00\x00"), tiff...)
+	var buf bytes.Buffer
+	buf.Write([]byte{0xFF, 0xD8, 0xFF, 0xE1})
+	binary.Write(&buf, binary.BigEndian, uint16(len(segment)+2))
+	buf.Write(segment)
+	buf.Write([]byte{0xFF, 0xDA})
+	return buf.Bytes()
+}
+
+func TestOrientation(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want int
+	}{
+		{"jpeg little endian", jpegWithExif(exifTIFF(binary.LittleEndian, 6)), 6},
+		{"jpeg big endian", jpegWithExif(exifTIFF(binary.BigEndian, 8)), 8},
+		{"jpeg out of range", jpegWithExif(exifTIFF(binary.LittleEndian, 9)), 0},
+		{"jpeg truncated tiff", jpegWithExif([]byte("II*\x00\xff\x00\x00\x00")), 0},
+		{"jpeg without exif", []byte{0xFF, 0xD8, 0xFF, 0xDA}, 0},
+		{"png eXIf", pngWithChunk("eXIf", exifTIFF(binary.BigEndian, 3)), 3},
+		{"png without eXIf", pngWithChunk("IDAT", nil), 0},
+		{"unknown format", []byte("GIF89a"), 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Orientation(tt.data); got != tt.want {
+				t.Errorf("Orientation() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package jobstore
+
+import (
+	"sync"
+	"time"
+
+	"github.com/username/ocr-go/internal/model"
+)
+
+// Store persists async job state
+type Store interface {
+	// Put creates or replaces a job
+	Put(job model.JobStatus)
+
+	// Get returns the job with the given ID
+	Get(id string) (model.JobStatus, bool)
+
+	// Update applies fn to the stored job, reporting whether it exists
+	Update(id string, fn func(*model.JobStatus)) bool
+}
+
+// MemoryStore is an in-memory Store that expires finished jobs
+type MemoryStore struct {
+	mu        sync.RWMutex
+	jobs      map[string]model.JobStatus
+	retention time.Duration
+}
+
+// NewMemoryStore creates a store that keeps finished jobs for retention
+func NewMemoryStore(retention time.Duration) *MemoryStore {
+	s := &MemoryStore{
+		jobs:      make(map[string]model.JobStatus),
+		retention: retention,
+	}
+	go s.cleanup()
+
+	return s
+}
+
+// Put creates or replaces a job
+func (s *MemoryStore) Put(job model.JobStatus) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.jobs[job.ID] = job
+}
+
+// Get returns the job with the given ID unless it has expired
+func (s *MemoryStore) Get(id string) (model.JobStatus, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	job, ok := s.jobs[id]
+	if !ok || s.expired(job, time.Now()) {
+		return model.JobStatus{}, false
+	}
+	return job, true
+}
+
+// Update applies fn to the stored job, reporting whether it exists
+func (s *MemoryStore) Update(id string, fn func(*model.JobStatus)) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return false
+	}
+	fn(&job)
+	job.UpdatedAt = time.Now()
+	s.jobs[id] = job
+
+	return true
+}
+
+// expired reports whether a finished job is past the retention period
+func (s *MemoryStore) expired(job model.JobStatus, now time.Time) bool {
+	if job.FinishedAt == nil {
+		return false
+	}
+	return now.Sub(*job.FinishedAt) > s.retention
+}
+
+// cleanup periodically removes expired jobs
+func (s *MemoryStore) cleanup() {
+	for {
+		time.Sleep(time.Minute)
+
+		now := time.Now()
+		s.mu.Lock()
+		for id, job := range s.jobs {
+			if s.expired(job, now) {
+				delete(s.jobs, id)
+			}
+		}
+		s.mu.Unlock()
+	}
+}
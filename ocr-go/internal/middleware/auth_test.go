@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAPIKeyAuth(t *testing.T) {
+	handler := APIKeyAuth([]string{"secret"})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	tests := []struct {
+		name    string
+		header  string
+		value   string
+		upgrade bool
+		want    int
+	}{
+		{"missing", "", "", false, http.StatusUnauthorized},
+		{"header", "X-API-Key", "secret", false, http.StatusNoContent},
+		{"bearer", "Authorization", "Bearer secret", false, http.StatusNoContent},
+		{"wrong key", "X-API-Key", "guess", false, http.StatusUnauthorized},
+		{"subprotocol", "Sec-WebSocket-Protocol", "ocr, bearer.secret", true, http.StatusNoContent},
+		{"wrong subprotocol", "Sec-WebSocket-Protocol", "ocr, bearer.guess", true, http.StatusUnauthorized},
+		{"subprotocol without upgrade", "Sec-WebSocket-Protocol", "bearer.secret", false, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		method := http.MethodPost
+		if tt.upgrade {
+			method = http.MethodGet
+		}
+		req := httptest.NewRequest(method, "/api/stream", nil)
+		if tt.header != "" {
+			req.Header.Set(tt.header, tt.value)
+		}
+		if tt.upgrade {
+			req.Header.Set("Connection", "keep-alive, Upgrade")
+			req.Header.Set("Upgrade", "websocket")
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, rec.Code, tt.want)
+		}
+	}
+}
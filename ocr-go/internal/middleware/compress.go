@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"net/http"
+
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+)
+
+// compressLevel balances CPU against size for large JSON responses
+const compressLevel = 5
+
+// compressibleTypes are the content types worth compressing. PNG and other
+// already-compressed downloads are left alone, as is text/event-stream so
+// progress events are not held back in the compressor.
+var compressibleTypes = []string{
+	"application/json",
+	"application/javascript",
+	"text/plain",
+	"text/csv",
+	"text/html",
+	"text/css",
+}
+
+// Compress gzip or deflate encodes compressible responses for clients that
+// accept it
+func Compress() func(http.Handler) http.Handler {
+	return chimiddleware.Compress(compressLevel, compressibleTypes...)
+}
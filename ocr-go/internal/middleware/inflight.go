@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+// InFlight tracks requests that are still being served so shutdown can
+// report how many were drained and which ones were cut off
+type InFlight struct {
+	mu     sync.Mutex
+	active map[string]int
+	count  int
+}
+
+// NewInFlight creates an empty in-flight request tracker
+func NewInFlight() *InFlight {
+	return &InFlight{active: make(map[string]int)}
+}
+
+// Middleware counts each request from when it starts until its handler returns
+func (f *InFlight) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		endpoint := r.Method + " " + r.URL.Path
+		f.add(endpoint, 1)
+		defer f.add(endpoint, -1)
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// add adjusts the in-flight count for endpoint by delta
+func (f *InFlight) add(endpoint string, delta int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.count += delta
+	f.active[endpoint] += delta
+	if f.active[endpoint] == 0 {
+		delete(f.active, endpoint)
+	}
+}
+
+// Count returns how many requests are currently in flight
+func (f *InFlight) Count() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.count
+}
+
+// Endpoints returns the in-flight endpoints, sorted, with a count suffix
+// when an endpoint has more than one request running
+func (f *InFlight) Endpoints() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	endpoints := make([]string, 0, len(f.active))
+	for endpoint, n := range f.active {
+		if n > 1 {
+			endpoint = fmt.Sprintf("%s (x%d)", endpoint, n)
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+	sort.Strings(endpoints)
+
+	return endpoints
+}
@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestInFlight(t *testing.T) {
+	inflight := NewInFlight()
+	release := make(chan struct{})
+	var started sync.WaitGroup
+	handler := inflight.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started.Done()
+		<-release
+	}))
+
+	paths := []string{"/api/batch", "/api/batch", "/api/extract"}
+	var done sync.WaitGroup
+	started.Add(len(paths))
+	for _, path := range paths {
+		done.Add(1)
+		go func(path string) {
+			defer done.Done()
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, path, nil))
+		}(path)
+	}
+	started.Wait()
+
+	if got := inflight.Count(); got != 3 {
+		t.Errorf("Count() = %d, want 3", got)
+	}
+	want := []string{"POST /api/batch (x2)", "POST /api/extract"}
+	if got := inflight.Endpoints(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Endpoints() = %v, want %v", got, want)
+	}
+
+	close(release)
+	done.Wait()
+
+	if got := inflight.Count(); got != 0 {
+		t.Errorf("Count() after drain = %d, want 0", got)
+	}
+	if got := inflight.Endpoints(); len(got) != 0 {
+		t.Errorf("Endpoints() after drain = %v, want none", got)
+	}
+}
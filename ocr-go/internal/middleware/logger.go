@@ -8,21 +8,31 @@ import (
 	"github.com/go-chi/chi/v5/middleware"
 )
 
-// Logger is a middleware that logs HTTP requests
-func Logger(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		start := time.Now()
+// Logger is a middleware that logs HTTP requests. Requests taking longer
+// than slowThreshold are logged again as a warning so slow OCR calls stand
+// out; zero disables the warning.
+func Logger(slowThreshold time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
 
-		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
-		next.ServeHTTP(ww, r)
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r)
 
-		log.Printf(
-			"%s %s %d %s %s",
-			r.Method,
-			r.RequestURI,
-			ww.Status(),
-			time.Since(start),
-			r.RemoteAddr,
-		)
-	})
+			duration := time.Since(start)
+			log.Printf(
+				"%s %s %d %s %s",
+				r.Method,
+				r.RequestURI,
+				ww.Status(),
+				duration,
+				r.RemoteAddr,
+			)
+
+			if slowThreshold > 0 && duration > slowThreshold {
+				log.Printf("WARN slow request: %s %s took %s (threshold %s)",
+					r.Method, r.URL.Path, duration, slowThreshold)
+			}
+		})
+	}
 }
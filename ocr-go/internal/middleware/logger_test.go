@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoggerSlowRequests(t *testing.T) {
+	tests := []struct {
+		name      string
+		threshold time.Duration
+		delay     time.Duration
+		wantWarn  bool
+	}{
+		{"slow", time.Millisecond, 20 * time.Millisecond, true},
+		{"fast", time.Minute, 0, false},
+		{"disabled", 0, 20 * time.Millisecond, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			log.SetOutput(&buf)
+			defer log.SetOutput(os.Stderr)
+
+			handler := Logger(tt.threshold)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				time.Sleep(tt.delay)
+			}))
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/api/extract", nil))
+
+			gotWarn := strings.Contains(buf.String(), "WARN slow request: POST /api/extract")
+			if gotWarn != tt.wantWarn {
+				t.Errorf("slow warning logged = %v, want %v; log:\n%s", gotWarn, tt.wantWarn, buf.String())
+			}
+		})
+	}
+}
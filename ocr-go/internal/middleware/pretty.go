@@ -0,0 +1,110 @@
+package middleware
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"mime"
+	"net"
+	"net/http"
+)
+
+// prettyIndent is the indentation of pretty-printed JSON responses
+const prettyIndent = "  "
+
+// PrettyJSON indents the JSON responses of requests with ?pretty=true so
+// they are easy to read in a terminal or browser. Other responses, such as
+// images and event streams, pass through untouched.
+func PrettyJSON(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("pretty") != "true" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		pw := &prettyWriter{ResponseWriter: w}
+		next.ServeHTTP(pw, r)
+		pw.finish()
+	})
+}
+
+// prettyWriter holds back a JSON response until it is complete, so it can
+// be indented as a whole
+type prettyWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+	buffering   bool
+	status      int
+	buf         bytes.Buffer
+}
+
+// WriteHeader starts buffering JSON responses and passes others through
+func (pw *prettyWriter) WriteHeader(status int) {
+	if pw.wroteHeader {
+		return
+	}
+	pw.wroteHeader = true
+
+	mediaType, _, _ := mime.ParseMediaType(pw.Header().Get("Content-Type"))
+	if mediaType == "application/json" {
+		pw.buffering = true
+		pw.status = status
+		pw.Header().Del("Content-Length")
+		return
+	}
+	pw.ResponseWriter.WriteHeader(status)
+}
+
+// Write buffers JSON and passes everything else through
+func (pw *prettyWriter) Write(p []byte) (int, error) {
+	if !pw.wroteHeader {
+		pw.WriteHeader(http.StatusOK)
+	}
+	if pw.buffering {
+		return pw.buf.Write(p)
+	}
+	return pw.ResponseWriter.Write(p)
+}
+
+// Flush passes through for streamed responses; a buffered JSON response is
+// only sent once complete
+func (pw *prettyWriter) Flush() {
+	if pw.buffering {
+		return
+	}
+	if flusher, ok := pw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack lets WebSocket upgrades through
+func (pw *prettyWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := pw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response does not support hijacking")
+	}
+	return hijacker.Hijack()
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController, so
+// handlers can still adjust their deadlines
+func (pw *prettyWriter) Unwrap() http.ResponseWriter {
+	return pw.ResponseWriter
+}
+
+// finish indents and sends a buffered JSON response. A body that is not
+// valid JSON is sent as it was written.
+func (pw *prettyWriter) finish() {
+	if !pw.buffering {
+		return
+	}
+
+	body := pw.buf.Bytes()
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, body, "", prettyIndent); err == nil {
+		body = indented.Bytes()
+	}
+	pw.ResponseWriter.WriteHeader(pw.status)
+	pw.ResponseWriter.Write(body)
+}
@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestPrettyJSON(t *testing.T) {
+	tests := []struct {
+		name        string
+		url         string
+		contentType string
+		body        string
+		want        string
+	}{
+		{"indents json", "/api/stats?pretty=true", "application/json", `{"a":1,"b":[2]}` + "\n", "{\n  \"a\": 1,\n  \"b\": [\n    2\n  ]\n}\n"},
+		{"compact by default", "/api/stats", "application/json", `{"a":1}`, `{"a":1}`},
+		{"other types untouched", "/api/results/x.csv?pretty=true", "text/csv", "a,b\n", "a,b\n"},
+		{"invalid json as is", "/api/stats?pretty=true", "application/json", `{"a":`, `{"a":`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := PrettyJSON(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tt.contentType)
+				w.WriteHeader(http.StatusCreated)
+				w.Write([]byte(tt.body))
+			}))
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.url, nil))
+
+			if rec.Code != http.StatusCreated {
+				t.Errorf("status = %d, want %d", rec.Code, http.StatusCreated)
+			}
+			if got := rec.Body.String(); got != tt.want {
+				t.Errorf("body = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrettyJSONResponseController(t *testing.T) {
+	srv := httptest.NewServer(PrettyJSON(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/api/batch?pretty=true")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		body, _ := io.ReadAll(resp.Body)
+		t.Errorf("status = %d (%s), want the deadline to be settable", resp.StatusCode, body)
+	}
+}
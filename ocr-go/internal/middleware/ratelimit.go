@@ -0,0 +1,105 @@
+package middleware
+
+import (
+	"encoding/json"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// visitorTTL is how long an idle client's limiter is kept in memory
+const visitorTTL = 10 * time.Minute
+
+// visitor holds the token bucket for a single client
+type visitor struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// rateLimiter tracks token buckets per client IP
+type rateLimiter struct {
+	mu       sync.Mutex
+	visitors map[string]*visitor
+	rps      rate.Limit
+	burst    int
+}
+
+// RateLimit is a middleware that limits requests per client IP using a
+// token bucket refilled at rps tokens per second with the given burst
+func RateLimit(rps float64, burst int) func(http.Handler) http.Handler {
+	rl := &rateLimiter{
+		visitors: make(map[string]*visitor),
+		rps:      rate.Limit(rps),
+		burst:    burst,
+	}
+	go rl.cleanup()
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			reservation := rl.limiter(clientIP(r)).Reserve()
+			if delay := reservation.Delay(); delay > 0 {
+				reservation.Cancel()
+				retryAfter := int(math.Ceil(delay.Seconds()))
+				w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusTooManyRequests)
+				json.NewEncoder(w).Encode(map[string]string{
+					"error": "Rate limit exceeded",
+				})
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// limiter returns the token bucket for ip, creating it on first use
+func (rl *rateLimiter) limiter(ip string) *rate.Limiter {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	v, ok := rl.visitors[ip]
+	if !ok {
+		v = &visitor{limiter: rate.NewLimiter(rl.rps, rl.burst)}
+		rl.visitors[ip] = v
+	}
+	v.lastSeen = time.Now()
+
+	return v.limiter
+}
+
+// cleanup periodically removes limiters for clients that went idle
+func (rl *rateLimiter) cleanup() {
+	for {
+		time.Sleep(time.Minute)
+		rl.removeIdle(time.Now())
+	}
+}
+
+// removeIdle drops the limiters of clients not seen for visitorTTL at now
+func (rl *rateLimiter) removeIdle(now time.Time) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	for ip, v := range rl.visitors {
+		if now.Sub(v.lastSeen) > visitorTTL {
+			delete(rl.visitors, ip)
+		}
+	}
+}
+
+// clientIP returns the client address without the port. RealIP should run
+// earlier in the chain so proxied requests are keyed by the original client.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// requestFrom sends a request from remoteAddr through handler
+func requestFrom(handler http.Handler, remoteAddr string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/api/extract", nil)
+	req.RemoteAddr = remoteAddr
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRateLimit(t *testing.T) {
+	handler := RateLimit(0.5, 2)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	// The burst passes, the next request has to wait for a token
+	for i := 0; i < 2; i++ {
+		if rec := requestFrom(handler, "192.0.2.1:1234"); rec.Code != http.StatusNoContent {
+			t.Fatalf("request %d: status = %d, want %d", i+1, rec.Code, http.StatusNoContent)
+		}
+	}
+	rec := requestFrom(handler, "192.0.2.1:5678")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("over the burst: status = %d, want %d", rec.Code, http.StatusTooManyRequests)
+	}
+	// One token every two seconds
+	retryAfter, err := strconv.Atoi(rec.Header().Get("Retry-After"))
+	if err != nil || retryAfter < 1 || retryAfter > 2 {
+		t.Errorf("Retry-After = %q, want 1 or 2 seconds", rec.Header().Get("Retry-After"))
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", ct)
+	}
+
+	// Other clients have buckets of their own
+	if rec := requestFrom(handler, "192.0.2.2:1234"); rec.Code != http.StatusNoContent {
+		t.Errorf("other client: status = %d, want %d", rec.Code, http.StatusNoContent)
+	}
+}
+
+func TestRateLimitRemovesIdleClients(t *testing.T) {
+	rl := &rateLimiter{
+		visitors: make(map[string]*visitor),
+		rps:      rate.Limit(1),
+		burst:    1,
+	}
+	rl.limiter("192.0.2.1")
+	rl.limiter("192.0.2.2")
+
+	now := time.Now()
+	rl.visitors["192.0.2.1"].lastSeen = now.Add(-visitorTTL - time.Second)
+	rl.removeIdle(now)
+
+	if _, ok := rl.visitors["192.0.2.1"]; ok {
+		t.Error("idle client was kept")
+	}
+	if _, ok := rl.visitors["192.0.2.2"]; !ok {
+		t.Error("active client was removed")
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	for remote, want := range map[string]string{
+		"192.0.2.1:1234":   "192.0.2.1",
+		"[2001:db8::1]:80": "2001:db8::1",
+		"192.0.2.1":        "192.0.2.1",
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = remote
+		if got := clientIP(req); got != want {
+			t.Errorf("clientIP(%q) = %q, want %q", remote, got, want)
+		}
+	}
+}
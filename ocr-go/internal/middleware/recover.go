@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/username/ocr-go/internal/model"
+)
+
+// Recoverer is a middleware that recovers from panics in later handlers,
+// logs the panic with the request ID and stack trace and responds with a
+// JSON 500 like every other API error
+func Recoverer(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			p := recover()
+			if p == nil {
+				return
+			}
+			// The server aborts the response silently on this sentinel
+			if p == http.ErrAbortHandler {
+				panic(p)
+			}
+
+			log.Printf("Panic serving %s %s (request %s): %v\n%s",
+				r.Method, r.URL.Path, middleware.GetReqID(r.Context()), p, debug.Stack())
+
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(model.ErrorResponse{
+				Error: "Internal server error",
+			})
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/username/ocr-go/internal/model"
+)
+
+func TestRecoverer(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	handler := middleware.RequestID(Recoverer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})))
+	req := httptest.NewRequest(http.MethodPost, "/api/extract", nil)
+	req.Header.Set(middleware.RequestIDHeader, "req-42")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusInternalServerError)
+	}
+	var got model.ErrorResponse
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil || got.Error == "" {
+		t.Errorf("body = %+v (%v), want a JSON error", got, err)
+	}
+	if logged := buf.String(); !strings.Contains(logged, "POST /api/extract (request req-42): boom") {
+		t.Errorf("log = %q, want the request and panic value", logged)
+	}
+}
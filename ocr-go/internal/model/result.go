@@ -2,13 +2,56 @@ package model
 
 import "time"
 
+// ExtractURLRequest represents a request to extract text from a remote image
+type ExtractURLRequest struct {
+	URL string `json:"url"`
+}
+
+// ExtractBase64Request represents a JSON request carrying a base64 image
+type ExtractBase64Request struct {
+	ImageBase64 string            `json:"image_base64"`
+	Filename    string            `json:"filename,omitempty"`
+	Lang        string            `json:"lang,omitempty"`
+	Variables   map[string]string `json:"variables,omitempty"`
+	Mask        []BBox            `json:"mask,omitempty"`
+}
+
 // ExtractTextResponse represents the text extraction response
 type ExtractTextResponse struct {
-	Filename    string                   `json:"filename"`
-	FullText    string                   `json:"full_text"`
-	Boxes       []map[string]interface{} `json:"boxes"`
-	TotalLines  int                      `json:"total_lines"`
-	ProcessedAt time.Time                `json:"processed_at"`
+	ID             string                   `json:"id"`
+	Filename       string                   `json:"filename"`
+	ContentHash    string                   `json:"content_hash,omitempty"`
+	ResultFile     string                   `json:"result_file,omitempty"`
+	SourceFile     string                   `json:"source_file,omitempty"`
+	ImageWidth     int                      `json:"image_width"`
+	ImageHeight    int                      `json:"image_height"`
+	Frames         int                      `json:"frames,omitempty"`
+	MaskedRegions  int                      `json:"masked_regions,omitempty"`
+	Codes          []DetectedCode           `json:"codes,omitempty"`
+	FullText       string                   `json:"full_text"`
+	TextFound      bool                     `json:"text_found"`
+	CorrectedText  string                   `json:"corrected_text,omitempty"`
+	Boxes          []map[string]interface{} `json:"boxes"`
+	TotalLines     int                      `json:"total_lines"`
+	MeanConfidence float64                  `json:"mean_confidence"`
+	Histogram      []int                    `json:"confidence_histogram"`
+	Language       string                   `json:"language"`
+	DPI            int                      `json:"dpi,omitempty"`
+	Scale          int                      `json:"scale,omitempty"`
+	DebugImageURL  string                   `json:"debug_image_url,omitempty"`
+	ThumbnailURL   string                   `json:"thumbnail_url,omitempty"`
+	Columns        int                      `json:"columns,omitempty"`
+	AutoDetected   bool                     `json:"language_auto_detected,omitempty"`
+	Cached         bool                     `json:"cached,omitempty"`
+	ProcessedAt    time.Time                `json:"processed_at"`
+}
+
+// ImageValidationResponse reports that an image passed the extract checks
+type ImageValidationResponse struct {
+	Valid  bool   `json:"valid"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+	Format string `json:"format"`
 }
 
 // VisualizeResponse represents the visualization response
@@ -19,31 +62,317 @@ type VisualizeResponse struct {
 	DownloadURL string `json:"download_url"`
 }
 
+// CropEntry describes one word crop in a crop export manifest
+type CropEntry struct {
+	Text       string  `json:"text"`
+	Confidence float64 `json:"confidence"`
+	BBox       BBox    `json:"bbox"`
+}
+
+// CropExportResponse represents the crop export response
+type CropExportResponse struct {
+	Filename    string `json:"filename"`
+	OutputFile  string `json:"output_file"`
+	TotalCrops  int    `json:"total_crops"`
+	DownloadURL string `json:"download_url"`
+}
+
+// WordBox is a recognized word with its bounding box
+type WordBox struct {
+	Text       string  `json:"text"`
+	Confidence float64 `json:"confidence"`
+	BBox       BBox    `json:"bbox"`
+}
+
+// StreamFrame is the result of one frame sent on the live OCR stream
+type StreamFrame struct {
+	Frame          int       `json:"frame"`
+	FullText       string    `json:"full_text"`
+	Boxes          []WordBox `json:"boxes"`
+	ProcessingTime string    `json:"processing_time,omitempty"`
+	// Dropped counts the frames skipped so far because the engine was busy
+	Dropped int64  `json:"dropped"`
+	Error   string `json:"error,omitempty"`
+}
+
+// LanguageCount is the number of documents processed in a language
+type LanguageCount struct {
+	Language string `json:"language"`
+	Count    int64  `json:"count"`
+}
+
+// StatsResponse summarizes the documents processed since Since
+type StatsResponse struct {
+	DocumentsProcessed    int64           `json:"documents_processed"`
+	AverageConfidence     float64         `json:"average_confidence"`
+	AverageProcessingTime string          `json:"average_processing_time,omitempty"`
+	TopLanguages          []LanguageCount `json:"top_languages"`
+	Since                 time.Time       `json:"since"`
+}
+
+// LanguageUploadResponse represents an installed custom language model
+type LanguageUploadResponse struct {
+	Language string `json:"language"`
+	Size     int64  `json:"size"`
+	Replaced bool   `json:"replaced"`
+}
+
+// ResultFile describes a stored result file
+type ResultFile struct {
+	Name     string `json:"name"`
+	Size     int64  `json:"size"`
+	Modified string `json:"modified"`
+}
+
+// ListResultsResponse represents the stored result files
+type ListResultsResponse struct {
+	Files     []ResultFile `json:"files"`
+	Count     int          `json:"count"`
+	TotalSize int64        `json:"total_size"`
+}
+
+// ResultsUsageResponse summarizes the storage taken by result files
+type ResultsUsageResponse struct {
+	Count     int    `json:"count"`
+	TotalSize int64  `json:"total_size"`
+	Oldest    string `json:"oldest,omitempty"`
+	Newest    string `json:"newest,omitempty"`
+}
+
 // BatchResult represents result for single file in batch processing
 type BatchResult struct {
-	Filename   string `json:"filename"`
-	Lines      int    `json:"lines"`
-	Success    bool   `json:"success"`
-	Error      string `json:"error,omitempty"`
-	Preview    string `json:"preview"`
-	OutputFile string `json:"output_file"`
+	Index    int    `json:"index"`
+	Filename string `json:"filename"`
+	Size     int64  `json:"size"`
+	// ContentHash is the hex SHA-256 of the uploaded file
+	ContentHash string `json:"content_hash,omitempty"`
+	SourceFile  string `json:"source_file,omitempty"`
+	Lines       int    `json:"lines"`
+	Success     bool   `json:"success"`
+	Error       string `json:"error,omitempty"`
+	Preview     string `json:"preview"`
+	OutputFile  string `json:"output_file"`
+	// AnnotatedFile is the image with its word boxes drawn on, saved
+	// when the batch asked for annotated images
+	AnnotatedFile string `json:"annotated_file,omitempty"`
 }
 
 // BatchProcessResponse represents batch processing response
 type BatchProcessResponse struct {
+	JobID          string        `json:"job_id,omitempty"`
 	TotalFiles     int           `json:"total_files"`
 	SuccessCount   int           `json:"success_count"`
 	FailureCount   int           `json:"failure_count"`
 	Results        []BatchResult `json:"results"`
 	ProcessingTime string        `json:"processing_time"`
+	// ArchiveFile is a ZIP of every result file, saved when the batch
+	// asked for one
+	ArchiveFile string `json:"archive_file,omitempty"`
+	DownloadURL string `json:"download_url,omitempty"`
+}
+
+// JobAcceptedResponse represents an accepted async batch job
+type JobAcceptedResponse struct {
+	JobID      string `json:"job_id"`
+	Status     string `json:"status"`
+	TotalFiles int    `json:"total_files"`
+	Callback   string `json:"callback,omitempty"`
 }
 
-// ErrorResponse represents an error response
+// JobStatus represents the state and progress of an async batch job
+type JobStatus struct {
+	ID         string                `json:"id"`
+	State      string                `json:"state"`
+	Completed  int                   `json:"completed"`
+	Total      int                   `json:"total"`
+	Error      string                `json:"error,omitempty"`
+	Result     *BatchProcessResponse `json:"result,omitempty"`
+	CreatedAt  time.Time             `json:"created_at"`
+	UpdatedAt  time.Time             `json:"updated_at"`
+	FinishedAt *time.Time            `json:"finished_at,omitempty"`
+}
+
+// BatchEvent represents a progress event of an async batch job
+type BatchEvent struct {
+	Type      string `json:"type"`
+	Index     int    `json:"index"`
+	Total     int    `json:"total"`
+	Completed int    `json:"completed"`
+	Filename  string `json:"filename"`
+	Success   bool   `json:"success"`
+	Error     string `json:"error,omitempty"`
+}
+
+// ErrorResponse represents an error response. Validation errors also
+// carry a machine-readable code and a message per offending field.
 type ErrorResponse struct {
-	Error string `json:"error"`
+	Error  string            `json:"error"`
+	Code   string            `json:"code,omitempty"`
+	Fields map[string]string `json:"fields,omitempty"`
+}
+
+// ConfigResponse reports the effective, non-secret runtime configuration
+type ConfigResponse struct {
+	Language         string       `json:"language"`
+	AutoLanguages    []string     `json:"auto_languages"`
+	MaxUploadSize    int64        `json:"max_upload_size"`
+	MaxImagePixels   int64        `json:"max_image_pixels"`
+	ThumbnailSize    int          `json:"thumbnail_size"`
+	StreamMaxFPS     float64      `json:"stream_max_fps"`
+	MaxBatchFiles    int          `json:"max_batch_files"`
+	BatchConcurrency int          `json:"batch_concurrency"`
+	MaxConcurrentOCR int          `json:"max_concurrent_ocr"`
+	OCRQueueSize     int          `json:"ocr_queue_size"`
+	OCRQueueWait     string       `json:"ocr_queue_wait"`
+	BatchFileTimeout string       `json:"batch_file_timeout"`
+	OutputDir        string       `json:"output_dir"`
+	UploadDir        string       `json:"upload_dir"`
+	DictionaryDir    string       `json:"dictionary_dir"`
+	Server           ServerConfig `json:"server"`
+}
+
+// ServerConfig holds settings applied outside the handlers, such as
+// timeouts and middleware. Secrets are reduced to whether they are set.
+type ServerConfig struct {
+	Port                 string   `json:"port,omitempty"`
+	OCREngine            string   `json:"ocr_engine,omitempty"`
+	TessdataPrefix       string   `json:"tessdata_prefix,omitempty"`
+	FallbackEngine       string   `json:"fallback_engine,omitempty"`
+	CacheSize            int      `json:"cache_size"`
+	LanguageEngines      int      `json:"language_engines"`
+	StorageBackend       string   `json:"storage_backend,omitempty"`
+	RequestTimeout       string   `json:"request_timeout,omitempty"`
+	BulkRequestTimeout   string   `json:"bulk_request_timeout,omitempty"`
+	ShutdownTimeout      string   `json:"shutdown_timeout,omitempty"`
+	SlowRequestThreshold string   `json:"slow_request_threshold,omitempty"`
+	JobRetention         string   `json:"job_retention,omitempty"`
+	IdempotencyTTL       string   `json:"idempotency_ttl,omitempty"`
+	IdempotencyMaxBytes  int64    `json:"idempotency_max_bytes,omitempty"`
+	RateLimitRPS         float64  `json:"rate_limit_rps"`
+	RateLimitBurst       int      `json:"rate_limit_burst"`
+	AuthEnabled          bool     `json:"auth_enabled"`
+	AdminEnabled         bool     `json:"admin_enabled"`
+	PrettyResults        bool     `json:"pretty_results"`
+	CORSOrigins          []string `json:"cors_origins,omitempty"`
+	WatchDir             string   `json:"watch_dir,omitempty"`
 }
 
 // HealthResponse represents health check response
 type HealthResponse struct {
-	Status string `json:"status"`
+	Status   string `json:"status"`
+	Language string `json:"language,omitempty"`
+	Version  string `json:"version,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// BBox represents a bounding box in pixel coordinates
+type BBox struct {
+	X      int `json:"x"`
+	Y      int `json:"y"`
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// DetectedCode is a QR code or barcode decoded alongside the text. BBox
+// is in pixels of the image.
+type DetectedCode struct {
+	Format string `json:"format"`
+	Value  string `json:"value"`
+	BBox   BBox   `json:"bbox"`
+}
+
+// SearchMatch represents a single word matching a search query
+type SearchMatch struct {
+	Text       string  `json:"text"`
+	Confidence float64 `json:"confidence"`
+	BBox       BBox    `json:"bbox"`
+	Distance   *int    `json:"distance,omitempty"`
+}
+
+// TableResponse represents the detected table of an image. Cells and
+// CellBounds are indexed by row, then column.
+type TableResponse struct {
+	Filename   string     `json:"filename"`
+	Rows       int        `json:"rows"`
+	Columns    int        `json:"columns"`
+	Cells      [][]string `json:"cells"`
+	CellBounds [][]BBox   `json:"cell_bounds"`
+}
+
+// KeyValuePair is a form label and its value with their boxes
+type KeyValuePair struct {
+	Key             string  `json:"key"`
+	Value           string  `json:"value"`
+	KeyConfidence   float64 `json:"key_confidence"`
+	ValueConfidence float64 `json:"value_confidence"`
+	KeyBBox         BBox    `json:"key_bbox"`
+	ValueBBox       BBox    `json:"value_bbox"`
+}
+
+// KeyValueResponse represents the key-value extraction response
+type KeyValueResponse struct {
+	Filename   string         `json:"filename"`
+	Pairs      []KeyValuePair `json:"pairs"`
+	TotalPairs int            `json:"total_pairs"`
+}
+
+// SearchResponse represents the word search response
+type SearchResponse struct {
+	Filename     string        `json:"filename"`
+	Query        string        `json:"query"`
+	Regex        bool          `json:"regex"`
+	Fuzzy        bool          `json:"fuzzy"`
+	MaxDistance  *int          `json:"max_distance,omitempty"`
+	Matches      []SearchMatch `json:"matches"`
+	TotalMatches int           `json:"total_matches"`
+}
+
+// VersionResponse represents engine and build version information
+type VersionResponse struct {
+	EngineVersion string `json:"engine_version"`
+	GoVersion     string `json:"go_version"`
+	Module        string `json:"module,omitempty"`
+	ModuleVersion string `json:"module_version,omitempty"`
+	Revision      string `json:"revision,omitempty"`
+	BuildTime     string `json:"build_time,omitempty"`
+}
+
+// DiffRequest compares an OCR hypothesis against a reference transcript.
+// The hypothesis is either given as text or loaded from a saved result.
+type DiffRequest struct {
+	Reference  string `json:"reference"`
+	Hypothesis string `json:"hypothesis,omitempty"`
+	ResultID   string `json:"result_id,omitempty"`
+}
+
+// DiffEdit is one word-level edit from the reference to the hypothesis.
+// Indexes are word positions, -1 when the word is absent on that side.
+type DiffEdit struct {
+	Op         string `json:"op"`
+	RefIndex   int    `json:"ref_index"`
+	HypIndex   int    `json:"hyp_index"`
+	Reference  string `json:"reference,omitempty"`
+	Hypothesis string `json:"hypothesis,omitempty"`
+}
+
+// DiffResponse represents a word-level diff with error rates
+type DiffResponse struct {
+	Edits           []DiffEdit `json:"edits"`
+	ReferenceWords  int        `json:"reference_words"`
+	HypothesisWords int        `json:"hypothesis_words"`
+	Substitutions   int        `json:"substitutions"`
+	Deletions       int        `json:"deletions"`
+	Insertions      int        `json:"insertions"`
+	CER             float64    `json:"cer"`
+	WER             float64    `json:"wer"`
+}
+
+// EvaluateResponse reports OCR accuracy against a ground-truth transcript
+type EvaluateResponse struct {
+	Filename       string  `json:"filename"`
+	Language       string  `json:"language"`
+	PageSegMode    int     `json:"psm,omitempty"`
+	FullText       string  `json:"full_text"`
+	MeanConfidence float64 `json:"mean_confidence"`
+	DiffResponse
 }
@@ -0,0 +1,232 @@
+package ocr
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"hash"
+	"image"
+	"sync"
+)
+
+// CacheStats reports the effectiveness of a CachedEngine
+type CacheStats struct {
+	Hits     uint64
+	Misses   uint64
+	Entries  int
+	Capacity int
+}
+
+// CachedEngine keeps the most recently used results of another engine in
+// memory, keyed by a hash of the image pixels and the options, so repeated
+// images are not recognized again
+type CachedEngine struct {
+	Engine
+
+	mu       sync.Mutex
+	capacity int
+	order    *list.List
+	entries  map[[sha256.Size]byte]*list.Element
+	hits     uint64
+	misses   uint64
+}
+
+// cacheEntry is a cached result and its key
+type cacheEntry struct {
+	key    [sha256.Size]byte
+	result *DetailedResult
+}
+
+// NewCachedEngine wraps engine with an LRU cache holding up to capacity
+// results
+func NewCachedEngine(engine Engine, capacity int) *CachedEngine {
+	return &CachedEngine{
+		Engine:   engine,
+		capacity: capacity,
+		order:    list.New(),
+		entries:  make(map[[sha256.Size]byte]*list.Element),
+	}
+}
+
+// ExtractTextWithBoxes extracts text with bounding boxes, using the cache
+func (e *CachedEngine) ExtractTextWithBoxes(ctx context.Context, img image.Image) (*DetailedResult, error) {
+	return e.ExtractWithOptions(ctx, img, Options{})
+}
+
+// ExtractWithOptions returns a cached result for the same image and
+// options, or runs the wrapped engine and caches its result
+func (e *CachedEngine) ExtractWithOptions(ctx context.Context, img image.Image, opts Options) (*DetailedResult, error) {
+	key := cacheKey(img, opts)
+
+	if result, ok := e.get(key); ok {
+		return result, nil
+	}
+
+	result, err := e.Engine.ExtractWithOptions(ctx, img, opts)
+	if err != nil {
+		return nil, err
+	}
+	e.put(key, result)
+	return copyResult(result), nil
+}
+
+// ExtractTextFromBytes implements BytesExtractor, caching by the encoded
+// bytes. The wrapped engine decodes them itself if it can.
+func (e *CachedEngine) ExtractTextFromBytes(ctx context.Context, data []byte) (*DetailedResult, error) {
+	key := bytesCacheKey(data)
+
+	if result, ok := e.get(key); ok {
+		return result, nil
+	}
+
+	result, err := ExtractFromBytes(ctx, e.Engine, data)
+	if err != nil {
+		return nil, err
+	}
+	e.put(key, result)
+	return copyResult(result), nil
+}
+
+// Stats returns the cache counters
+func (e *CachedEngine) Stats() CacheStats {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return CacheStats{
+		Hits:     e.hits,
+		Misses:   e.misses,
+		Entries:  e.order.Len(),
+		Capacity: e.capacity,
+	}
+}
+
+// ReloadLanguage implements LanguageReloader. Results are not keyed by
+// language, so the whole cache is dropped.
+func (e *CachedEngine) ReloadLanguage(lang string) {
+	e.mu.Lock()
+	e.order.Init()
+	clear(e.entries)
+	e.mu.Unlock()
+
+	ReloadLanguage(e.Engine, lang)
+}
+
+// get returns a copy of the cached result for key and marks it as recently
+// used
+func (e *CachedEngine) get(key [sha256.Size]byte) (*DetailedResult, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	elem, ok := e.entries[key]
+	if !ok {
+		e.misses++
+		return nil, false
+	}
+	e.hits++
+	e.order.MoveToFront(elem)
+	return copyResult(elem.Value.(*cacheEntry).result), true
+}
+
+// put stores result under key, evicting the least recently used entry when
+// the cache is full
+func (e *CachedEngine) put(key [sha256.Size]byte, result *DetailedResult) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if elem, ok := e.entries[key]; ok {
+		elem.Value.(*cacheEntry).result = result
+		e.order.MoveToFront(elem)
+		return
+	}
+
+	e.entries[key] = e.order.PushFront(&cacheEntry{key: key, result: result})
+	for e.order.Len() > e.capacity {
+		oldest := e.order.Back()
+		e.order.Remove(oldest)
+		delete(e.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+// copyResult deep-copies result so callers may modify it freely
+func copyResult(result *DetailedResult) *DetailedResult {
+	c := *result
+	c.Boxes = make([]TextBox, len(result.Boxes))
+	for i, box := range result.Boxes {
+		c.Boxes[i] = box
+		if box.Polygon != nil {
+			c.Boxes[i].Polygon = append([]Point(nil), box.Polygon...)
+		}
+	}
+	if result.ConfidenceHistogram != nil {
+		c.ConfidenceHistogram = append([]int(nil), result.ConfidenceHistogram...)
+	}
+	return &c
+}
+
+// cacheKey hashes the decoded pixels of img, so the same picture encoded
+// differently shares a key, together with the options
+func cacheKey(img image.Image, opts Options) [sha256.Size]byte {
+	h := sha256.New()
+	hashImage(h, img)
+
+	// encoding/json sorts map keys, so the options encode deterministically
+	options, _ := json.Marshal(opts)
+	h.Write(options)
+
+	var key [sha256.Size]byte
+	h.Sum(key[:0])
+	return key
+}
+
+// bytesCacheKey hashes encoded image data. The prefix keeps these keys
+// apart from the pixel keys of cacheKey.
+func bytesCacheKey(data []byte) [sha256.Size]byte {
+	h := sha256.New()
+	h.Write([]byte("bytes:"))
+	h.Write(data)
+
+	var key [sha256.Size]byte
+	h.Sum(key[:0])
+	return key
+}
+
+// hashImage writes the bounds and pixels of img to h, reading the pixel
+// buffers directly for the common decoded types
+func hashImage(h hash.Hash, img image.Image) {
+	bounds := img.Bounds()
+	binary.Write(h, binary.LittleEndian, [4]int64{
+		int64(bounds.Min.X), int64(bounds.Min.Y), int64(bounds.Max.X), int64(bounds.Max.Y),
+	})
+
+	switch img := img.(type) {
+	case *image.Gray:
+		h.Write([]byte{'G'})
+		h.Write(img.Pix)
+	case *image.RGBA:
+		h.Write([]byte{'R'})
+		h.Write(img.Pix)
+	case *image.NRGBA:
+		h.Write([]byte{'N'})
+		h.Write(img.Pix)
+	case *image.YCbCr:
+		h.Write([]byte{'Y', byte(img.SubsampleRatio)})
+		h.Write(img.Y)
+		h.Write(img.Cb)
+		h.Write(img.Cr)
+	default:
+		h.Write([]byte{'*'})
+		var buf [8]byte
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				r, g, b, a := img.At(x, y).RGBA()
+				binary.LittleEndian.PutUint16(buf[0:], uint16(r))
+				binary.LittleEndian.PutUint16(buf[2:], uint16(g))
+				binary.LittleEndian.PutUint16(buf[4:], uint16(b))
+				binary.LittleEndian.PutUint16(buf[6:], uint16(a))
+				h.Write(buf[:])
+			}
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package ocr
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"testing"
+)
+
+func TestCachedEngine(t *testing.T) {
+	fake := NewFakeEngine(TextBox{Text: "cached", Confidence: 0.9})
+	engine := NewCachedEngine(fake, 2)
+	ctx := context.Background()
+
+	a := image.NewGray(image.Rect(0, 0, 2, 2))
+	b := image.NewGray(image.Rect(0, 0, 2, 2))
+	b.Pix[0] = 1
+	c := image.NewGray(image.Rect(0, 0, 3, 3))
+
+	first, _ := engine.ExtractTextWithBoxes(ctx, a)
+	first.Boxes[0].Text = "modified by caller"
+	second, _ := engine.ExtractTextWithBoxes(ctx, image.NewGray(image.Rect(0, 0, 2, 2)))
+
+	if second.Boxes[0].Text != "cached" {
+		t.Errorf("cached text = %q, want the result unaffected by callers", second.Boxes[0].Text)
+	}
+	if calls := fake.Calls(); calls != 1 {
+		t.Fatalf("engine called %d times for the same pixels, want 1", calls)
+	}
+
+	// Different options are cached separately
+	engine.ExtractWithOptions(ctx, a, Options{PageSegMode: 6})
+	if calls := fake.Calls(); calls != 2 {
+		t.Fatalf("engine called %d times, want 2", calls)
+	}
+
+	// Adding b and c evicts the least recently used entries
+	engine.ExtractTextWithBoxes(ctx, b)
+	engine.ExtractTextWithBoxes(ctx, c)
+	engine.ExtractTextWithBoxes(ctx, a)
+	if calls := fake.Calls(); calls != 5 {
+		t.Errorf("engine called %d times, want 5 after eviction", calls)
+	}
+
+	stats := engine.Stats()
+	if stats.Hits != 1 || stats.Misses != 5 || stats.Entries != 2 {
+		t.Errorf("stats = %+v, want 1 hit, 5 misses, 2 entries", stats)
+	}
+}
+
+func TestCachedEngineBytes(t *testing.T) {
+	fake := NewFakeEngine(TextBox{Text: "cached", Confidence: 0.9})
+	engine := NewCachedEngine(fake, 2)
+	ctx := context.Background()
+
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewGray(image.Rect(0, 0, 2, 2)))
+
+	// The fake engine takes no bytes, so they are decoded for it
+	for i := 0; i < 2; i++ {
+		result, err := engine.ExtractTextFromBytes(ctx, buf.Bytes())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.FullText != "cached" {
+			t.Errorf("text = %q, want %q", result.FullText, "cached")
+		}
+	}
+	if calls := fake.Calls(); calls != 1 {
+		t.Errorf("engine called %d times for the same bytes, want 1", calls)
+	}
+
+	if _, err := engine.ExtractTextFromBytes(ctx, []byte("not an image")); err == nil {
+		t.Error("expected an error for undecodable bytes")
+	}
+}
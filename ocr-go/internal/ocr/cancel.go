@@ -0,0 +1,38 @@
+package ocr
+
+import "context"
+
+// runCancelable runs fn in its own goroutine and returns as soon as either
+// fn finishes or ctx is done, whichever comes first.
+//
+// Tesseract calls go through CGo and cannot be interrupted, so on
+// cancellation fn keeps running in the background until the native call
+// returns and its result is discarded. This frees the caller (and the HTTP
+// worker serving a disconnected client) immediately; the engine itself stays
+// busy until the native call completes.
+func runCancelable[T any](ctx context.Context, fn func() (T, error)) (T, error) {
+	if err := ctx.Err(); err != nil {
+		var zero T
+		return zero, err
+	}
+
+	type outcome struct {
+		value T
+		err   error
+	}
+	// Buffered so the goroutine never blocks after the caller has left
+	done := make(chan outcome, 1)
+
+	go func() {
+		value, err := fn()
+		done <- outcome{value: value, err: err}
+	}()
+
+	select {
+	case out := <-done:
+		return out.value, out.err
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
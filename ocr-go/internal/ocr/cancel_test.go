@@ -0,0 +1,56 @@
+package ocr
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRunCancelableReturnsResult(t *testing.T) {
+	got, err := runCancelable(context.Background(), func() (string, error) {
+		return "text", nil
+	})
+	if err != nil || got != "text" {
+		t.Fatalf("got %q, %v; want %q, nil", got, err, "text")
+	}
+}
+
+func TestRunCancelableReturnsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	release := make(chan struct{})
+	defer close(release)
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+
+	start := time.Now()
+	_, err := runCancelable(ctx, func() (string, error) {
+		<-release // simulates a long native call
+		return "late", nil
+	})
+
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("returned after %v, want prompt return on cancel", elapsed)
+	}
+}
+
+func TestRunCancelableSkipsCanceledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	called := false
+	_, err := runCancelable(ctx, func() (string, error) {
+		called = true
+		return "", nil
+	})
+
+	if !errors.Is(err, context.Canceled) || called {
+		t.Fatalf("err = %v, called = %v; want context.Canceled without calling fn", err, called)
+	}
+}
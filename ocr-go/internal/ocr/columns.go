@@ -0,0 +1,58 @@
+package ocr
+
+import (
+	"sort"
+	"strings"
+)
+
+// columnGutter is the minimum horizontal gap between columns, relative to
+// the median word height; ordinary word spacing is well below one height
+const columnGutter = 2
+
+// Columns splits boxes into columns separated by vertical gutters that no
+// box crosses, ordered left to right
+func Columns(boxes []TextBox) [][]TextBox {
+	if len(boxes) == 0 {
+		return nil
+	}
+
+	sorted := make([]TextBox, len(boxes))
+	copy(sorted, boxes)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Box.X < sorted[j].Box.X
+	})
+	minGap := columnGutter * medianHeight(boxes)
+
+	columns := [][]TextBox{{sorted[0]}}
+	right := sorted[0].Box.X + sorted[0].Box.Width
+	for _, box := range sorted[1:] {
+		if box.Box.X-right > minGap {
+			columns = append(columns, nil)
+		}
+		columns[len(columns)-1] = append(columns[len(columns)-1], box)
+		right = max(right, box.Box.X+box.Box.Width)
+	}
+	return columns
+}
+
+// ColumnLayout lays out each column in turn, separating them with a blank
+// line, and returns the text and the number of columns detected
+func ColumnLayout(boxes []TextBox) (string, int) {
+	columns := Columns(boxes)
+
+	parts := make([]string, len(columns))
+	for i, column := range columns {
+		parts[i] = Layout(column)
+	}
+	return strings.Join(parts, "\n\n"), len(columns)
+}
+
+// medianHeight returns the median box height
+func medianHeight(boxes []TextBox) int {
+	heights := make([]int, len(boxes))
+	for i, box := range boxes {
+		heights[i] = box.Box.Height
+	}
+	sort.Ints(heights)
+	return heights[len(heights)/2]
+}
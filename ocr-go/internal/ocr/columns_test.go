@@ -0,0 +1,29 @@
+package ocr
+
+import "testing"
+
+func TestColumnLayout(t *testing.T) {
+	// Tesseract order interleaves the two columns line by line
+	boxes := []TextBox{
+		word("Left", 0, 0), word("one", 45, 0), word("Right", 300, 0), word("one", 345, 0),
+		word("Left", 0, 15), word("two", 45, 15), word("Right", 300, 15), word("two", 345, 15),
+	}
+
+	text, columns := ColumnLayout(boxes)
+
+	if columns != 2 {
+		t.Errorf("columns = %d, want 2", columns)
+	}
+	want := "Left one\nLeft two\n\nRight one\nRight two"
+	if text != want {
+		t.Errorf("text = %q, want %q", text, want)
+	}
+}
+
+func TestColumnLayoutSingleColumn(t *testing.T) {
+	boxes := []TextBox{word("Main", 0, 0), word("Street", 45, 0), word("Springfield", 0, 15)}
+
+	if _, columns := ColumnLayout(boxes); columns != 1 {
+		t.Errorf("columns = %d, want 1", columns)
+	}
+}
@@ -0,0 +1,47 @@
+package ocr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+)
+
+// AutoLanguage requests language detection instead of a fixed language
+const AutoLanguage = "auto"
+
+// DefaultAutoLanguages are the candidates probed when none are configured
+var DefaultAutoLanguages = []string{"eng", "spa", "fra", "deu"}
+
+// DetectLanguage runs OCR on img once per candidate language and returns
+// the most confident result; its Language field names the winner.
+// Candidates that fail, e.g. because their traineddata is missing, are
+// skipped. An error is returned only if every candidate fails.
+func DetectLanguage(ctx context.Context, engine Engine, img image.Image, opts Options, candidates []string) (*DetailedResult, error) {
+	if len(candidates) == 0 {
+		return nil, errors.New("no candidate languages configured")
+	}
+
+	var best *DetailedResult
+	var errs []error
+	for _, lang := range candidates {
+		opts.Language = lang
+		result, err := engine.ExtractWithOptions(ctx, img, opts)
+		if err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return nil, ctxErr
+			}
+			errs = append(errs, fmt.Errorf("%s: %w", lang, err))
+			continue
+		}
+		result.Language = lang
+		if best == nil || result.MeanConfidence > best.MeanConfidence {
+			best = result
+		}
+	}
+
+	if best == nil {
+		return nil, fmt.Errorf("language detection failed: %w", errors.Join(errs...))
+	}
+	return best, nil
+}
@@ -0,0 +1,40 @@
+package ocr
+
+import (
+	"context"
+	"errors"
+	"image"
+	"testing"
+)
+
+func TestDetectLanguagePicksMostConfident(t *testing.T) {
+	confidence := map[string]float64{"eng": 0.55, "spa": 0.91, "fra": 0.70}
+	engine := &FakeEngine{
+		ExtractFunc: func(_ context.Context, _ image.Image, opts Options) (*DetailedResult, error) {
+			c, ok := confidence[opts.Language]
+			if !ok {
+				return nil, errors.New("traineddata not found")
+			}
+			return &DetailedResult{FullText: opts.Language, MeanConfidence: c}, nil
+		},
+	}
+
+	result, err := DetectLanguage(context.Background(), engine, image.NewGray(image.Rect(0, 0, 1, 1)),
+		Options{}, []string{"eng", "deu", "spa", "fra"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Language != "spa" {
+		t.Errorf("language = %q, want %q", result.Language, "spa")
+	}
+}
+
+func TestDetectLanguageAllFail(t *testing.T) {
+	engine := &FakeEngine{Err: errors.New("traineddata not found")}
+
+	_, err := DetectLanguage(context.Background(), engine, image.NewGray(image.Rect(0, 0, 1, 1)),
+		Options{}, []string{"eng", "spa"})
+	if err == nil {
+		t.Fatal("expected an error when every candidate fails")
+	}
+}
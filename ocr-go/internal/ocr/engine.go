@@ -1,8 +1,11 @@
 package ocr
 
 import (
+	"bytes"
 	"context"
+	"fmt"
 	"image"
+	"unicode/utf8"
 )
 
 // Engine defines the OCR engine interface
@@ -13,10 +16,87 @@ type Engine interface {
 	// ExtractTextWithBoxes extracts text with bounding box information
 	ExtractTextWithBoxes(ctx context.Context, img image.Image) (*DetailedResult, error)
 
+	// ExtractWithOptions extracts text with bounding boxes using per-call options
+	ExtractWithOptions(ctx context.Context, img image.Image, opts Options) (*DetailedResult, error)
+
+	// Language returns the configured recognition language
+	Language() string
+
+	// Version returns the underlying OCR engine version
+	Version() string
+
 	// Close releases engine resources
 	Close() error
 }
 
+// BytesExtractor is implemented by engines that can recognize an encoded
+// image, such as a PNG or JPEG file, without it being decoded first
+type BytesExtractor interface {
+	// ExtractTextFromBytes extracts text with bounding boxes from data
+	ExtractTextFromBytes(ctx context.Context, data []byte) (*DetailedResult, error)
+}
+
+// ExtractFromBytes recognizes the encoded image data with engine, passing
+// the bytes straight through when engine is a BytesExtractor and decoding
+// them for it otherwise
+func ExtractFromBytes(ctx context.Context, engine Engine, data []byte) (*DetailedResult, error) {
+	if extractor, ok := engine.(BytesExtractor); ok {
+		return extractor.ExtractTextFromBytes(ctx, data)
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+	return engine.ExtractTextWithBoxes(ctx, img)
+}
+
+// LanguageReloader is implemented by engines that can pick up replaced
+// traineddata for a language without a restart
+type LanguageReloader interface {
+	// ReloadLanguage makes the next call using lang load its data afresh
+	ReloadLanguage(lang string)
+}
+
+// ReloadLanguage asks engine to reload lang if it supports doing so
+func ReloadLanguage(engine Engine, lang string) {
+	if reloader, ok := engine.(LanguageReloader); ok {
+		reloader.ReloadLanguage(lang)
+	}
+}
+
+// Valid range for Options.DPI, as accepted by Tesseract
+const (
+	MinDPI = 70
+	MaxDPI = 2400
+)
+
+// Options overrides engine settings for a single extraction.
+// Zero values keep the engine defaults.
+type Options struct {
+	// Language is the Tesseract language, e.g. "eng" or "spa+eng"
+	Language string
+
+	// PageSegMode is the Tesseract page segmentation mode (1-13)
+	PageSegMode int
+
+	// Whitelist restricts recognition to these characters
+	Whitelist string
+
+	// Blacklist excludes these characters from recognition
+	Blacklist string
+
+	// DPI is the source resolution passed to Tesseract as user_defined_dpi
+	DPI int
+
+	// Variables sets additional Tesseract variables; only the names
+	// returned by AllowedVariables are accepted
+	Variables map[string]string
+
+	// Polygons asks for word outlines following the text baseline. On
+	// Tesseract this reads the page layout a second time.
+	Polygons bool
+}
+
 // Result represents basic OCR result
 type Result struct {
 	Text       string  `json:"text"`
@@ -31,17 +111,62 @@ type BoundingBox struct {
 	Height int `json:"height"`
 }
 
+// Point is a pixel position
+type Point struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
 // TextBox represents detected text with its location
 type TextBox struct {
 	Text       string      `json:"text"`
 	Confidence float64     `json:"confidence"`
 	Box        BoundingBox `json:"box"`
+
+	// Polygon is the tighter word outline, a quad along the baseline of
+	// skewed text, when Options.Polygons asked for it. Box always
+	// encloses it.
+	Polygon []Point `json:"polygon,omitempty"`
 }
 
 // DetailedResult represents OCR result with boxes
 type DetailedResult struct {
-	FullText   string    `json:"full_text"`
-	Boxes      []TextBox `json:"boxes"`
-	TotalLines int       `json:"total_lines"`
-	Language   string    `json:"language"`
+	FullText       string    `json:"full_text"`
+	Boxes          []TextBox `json:"boxes"`
+	TotalLines     int       `json:"total_lines"`
+	Language       string    `json:"language"`
+	MeanConfidence float64   `json:"mean_confidence"`
+
+	// ConfidenceHistogram counts words per 10% confidence bucket
+	ConfidenceHistogram []int `json:"confidence_histogram"`
+}
+
+// MeanConfidence returns the average box confidence weighted by word length
+func MeanConfidence(boxes []TextBox) float64 {
+	var sum float64
+	var weight int
+	for _, box := range boxes {
+		n := utf8.RuneCountInString(box.Text)
+		sum += box.Confidence * float64(n)
+		weight += n
+	}
+	if weight == 0 {
+		return 0
+	}
+	return sum / float64(weight)
+}
+
+// HistogramBuckets is the number of buckets in a confidence histogram
+const HistogramBuckets = 10
+
+// ConfidenceHistogram counts boxes per confidence bucket: bucket i holds
+// confidences in [i/10, (i+1)/10), with 1.0 counted in the last bucket
+func ConfidenceHistogram(boxes []TextBox) []int {
+	histogram := make([]int, HistogramBuckets)
+	for _, box := range boxes {
+		bucket := int(box.Confidence * HistogramBuckets)
+		bucket = max(0, min(bucket, HistogramBuckets-1))
+		histogram[bucket]++
+	}
+	return histogram
 }
@@ -0,0 +1,24 @@
+package ocr
+
+import "testing"
+
+func TestConfidenceHistogram(t *testing.T) {
+	boxes := []TextBox{
+		{Text: "a", Confidence: 0},
+		{Text: "b", Confidence: 0.15},
+		{Text: "c", Confidence: 0.95},
+		{Text: "d", Confidence: 1},
+	}
+
+	got := ConfidenceHistogram(boxes)
+
+	want := []int{1, 1, 0, 0, 0, 0, 0, 0, 0, 2}
+	if len(got) != len(want) {
+		t.Fatalf("histogram = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("histogram = %v, want %v", got, want)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package ocr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+)
+
+// ensembleOverlap is the intersection over union above which boxes from
+// different passes are considered the same word
+const ensembleOverlap = 0.5
+
+// EnsemblePass is one recognition pass of an EnsembleEngine
+type EnsemblePass struct {
+	Engine Engine
+
+	// Options override the per-call options where set
+	Options Options
+}
+
+// EnsembleEngine runs several passes over the same image, for instance the
+// same engine at different page segmentation modes, and merges the words:
+// where boxes from different passes overlap the more confident word wins.
+type EnsembleEngine struct {
+	passes []EnsemblePass
+}
+
+// NewEnsembleEngine creates an engine that merges the given passes. The
+// first pass provides the language and version.
+func NewEnsembleEngine(passes ...EnsemblePass) *EnsembleEngine {
+	return &EnsembleEngine{passes: passes}
+}
+
+// ExtractText extracts the merged text and its mean confidence
+func (e *EnsembleEngine) ExtractText(ctx context.Context, img image.Image) (*Result, error) {
+	result, err := e.ExtractWithOptions(ctx, img, Options{})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Result{
+		Text:       result.FullText,
+		Confidence: result.MeanConfidence,
+	}, nil
+}
+
+// ExtractTextWithBoxes extracts the merged text with bounding boxes
+func (e *EnsembleEngine) ExtractTextWithBoxes(ctx context.Context, img image.Image) (*DetailedResult, error) {
+	return e.ExtractWithOptions(ctx, img, Options{})
+}
+
+// ExtractWithOptions runs every pass and merges the results. Failed passes
+// are skipped; an error is returned only if all of them fail.
+func (e *EnsembleEngine) ExtractWithOptions(ctx context.Context, img image.Image, opts Options) (*DetailedResult, error) {
+	if len(e.passes) == 0 {
+		return nil, errors.New("ensemble has no passes")
+	}
+
+	var merged *DetailedResult
+	var errs []error
+	for i, pass := range e.passes {
+		result, err := pass.Engine.ExtractWithOptions(ctx, img, overrideOptions(opts, pass.Options))
+		if err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return nil, ctxErr
+			}
+			errs = append(errs, fmt.Errorf("pass %d: %w", i+1, err))
+			continue
+		}
+
+		if merged == nil {
+			merged = &DetailedResult{Language: result.Language}
+		}
+		merged.Boxes = mergeBoxes(merged.Boxes, result.Boxes)
+	}
+
+	if merged == nil {
+		return nil, fmt.Errorf("all ensemble passes failed: %w", errors.Join(errs...))
+	}
+	merged.FullText = Layout(merged.Boxes)
+	merged.TotalLines = len(merged.Boxes)
+	merged.MeanConfidence = MeanConfidence(merged.Boxes)
+	merged.ConfidenceHistogram = ConfidenceHistogram(merged.Boxes)
+	return merged, nil
+}
+
+// Language returns the language of the first pass
+func (e *EnsembleEngine) Language() string {
+	return e.passes[0].Engine.Language()
+}
+
+// Version returns the version of the first pass
+func (e *EnsembleEngine) Version() string {
+	return e.passes[0].Engine.Version()
+}
+
+// ReloadLanguage implements LanguageReloader for every distinct engine
+// used by the passes
+func (e *EnsembleEngine) ReloadLanguage(lang string) {
+	reloaded := make(map[Engine]bool)
+	for _, pass := range e.passes {
+		if !reloaded[pass.Engine] {
+			reloaded[pass.Engine] = true
+			ReloadLanguage(pass.Engine, lang)
+		}
+	}
+}
+
+// Close releases every distinct engine used by the passes
+func (e *EnsembleEngine) Close() error {
+	closed := make(map[Engine]bool)
+	var errs []error
+	for _, pass := range e.passes {
+		if closed[pass.Engine] {
+			continue
+		}
+		closed[pass.Engine] = true
+		errs = append(errs, pass.Engine.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// overrideOptions returns base with the non-zero fields of override applied
+func overrideOptions(base, override Options) Options {
+	if override.Language != "" {
+		base.Language = override.Language
+	}
+	if override.PageSegMode != 0 {
+		base.PageSegMode = override.PageSegMode
+	}
+	if override.Whitelist != "" {
+		base.Whitelist = override.Whitelist
+	}
+	if override.Blacklist != "" {
+		base.Blacklist = override.Blacklist
+	}
+	if override.DPI != 0 {
+		base.DPI = override.DPI
+	}
+	if len(override.Variables) > 0 {
+		vars := make(map[string]string, len(base.Variables)+len(override.Variables))
+		for k, v := range base.Variables {
+			vars[k] = v
+		}
+		for k, v := range override.Variables {
+			vars[k] = v
+		}
+		base.Variables = vars
+	}
+	return base
+}
+
+// mergeBoxes adds boxes to merged, replacing an overlapping word when the
+// new one is more confident and appending words found only by this pass
+func mergeBoxes(merged, boxes []TextBox) []TextBox {
+	for _, box := range boxes {
+		match := -1
+		for i, existing := range merged {
+			if intersectionOverUnion(existing.Box, box.Box) > ensembleOverlap {
+				match = i
+				break
+			}
+		}
+
+		switch {
+		case match < 0:
+			merged = append(merged, box)
+		case box.Confidence > merged[match].Confidence:
+			merged[match] = box
+		}
+	}
+	return merged
+}
+
+// intersectionOverUnion returns the overlap of a and b relative to the area
+// they cover together
+func intersectionOverUnion(a, b BoundingBox) float64 {
+	ra := image.Rect(a.X, a.Y, a.X+a.Width, a.Y+a.Height)
+	rb := image.Rect(b.X, b.Y, b.X+b.Width, b.Y+b.Height)
+
+	inter := ra.Intersect(rb)
+	if inter.Empty() {
+		return 0
+	}
+	interArea := inter.Dx() * inter.Dy()
+	union := ra.Dx()*ra.Dy() + rb.Dx()*rb.Dy() - interArea
+	return float64(interArea) / float64(union)
+}
@@ -0,0 +1,38 @@
+package ocr
+
+import (
+	"context"
+	"image"
+	"testing"
+)
+
+func TestEnsembleEngineKeepsMoreConfidentWords(t *testing.T) {
+	first := NewFakeEngine(
+		TextBox{Text: "Invoice", Confidence: 0.95, Box: BoundingBox{X: 0, Y: 0, Width: 70, Height: 12}},
+		TextBox{Text: "T0tal", Confidence: 0.40, Box: BoundingBox{X: 0, Y: 20, Width: 50, Height: 12}},
+	)
+	second := NewFakeEngine(
+		TextBox{Text: "lnvoice", Confidence: 0.60, Box: BoundingBox{X: 1, Y: 0, Width: 70, Height: 12}},
+		TextBox{Text: "Total", Confidence: 0.90, Box: BoundingBox{X: 1, Y: 21, Width: 50, Height: 12}},
+		TextBox{Text: "42", Confidence: 0.85, Box: BoundingBox{X: 60, Y: 20, Width: 20, Height: 12}},
+	)
+	engine := NewEnsembleEngine(
+		EnsemblePass{Engine: first, Options: Options{PageSegMode: 3}},
+		EnsemblePass{Engine: second, Options: Options{PageSegMode: 6}},
+	)
+
+	result, err := engine.ExtractTextWithBoxes(context.Background(), image.NewGray(image.Rect(0, 0, 1, 1)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if want := "Invoice\nTotal 42"; result.FullText != want {
+		t.Errorf("full text = %q, want %q", result.FullText, want)
+	}
+	if result.TotalLines != 3 {
+		t.Errorf("total lines = %d, want 3", result.TotalLines)
+	}
+	if psm := second.Options()[0].PageSegMode; psm != 6 {
+		t.Errorf("second pass page segmentation mode = %d, want 6", psm)
+	}
+}
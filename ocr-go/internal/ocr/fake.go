@@ -0,0 +1,129 @@
+package ocr
+
+import (
+	"context"
+	"image"
+	"strings"
+	"sync"
+)
+
+// FakeEngine implements Engine with canned results and no Tesseract
+// dependency. It is meant for tests and demos.
+type FakeEngine struct {
+	// Detailed is returned by ExtractTextWithBoxes and ExtractWithOptions
+	Detailed *DetailedResult
+
+	// Err, when set, is returned by every extraction call
+	Err error
+
+	// ExtractFunc, when set, overrides Detailed and Err per call
+	ExtractFunc func(ctx context.Context, img image.Image, opts Options) (*DetailedResult, error)
+
+	// Lang and Ver are reported by Language and Version
+	Lang string
+	Ver  string
+
+	mu      sync.Mutex
+	calls   int
+	options []Options
+}
+
+// NewFakeEngine creates a fake engine that returns a result built from boxes
+func NewFakeEngine(boxes ...TextBox) *FakeEngine {
+	words := make([]string, len(boxes))
+	for i, box := range boxes {
+		words[i] = box.Text
+	}
+
+	return &FakeEngine{
+		Detailed: &DetailedResult{
+			FullText:       strings.Join(words, " "),
+			Boxes:          boxes,
+			TotalLines:     len(boxes),
+			Language:       "eng",
+			MeanConfidence: MeanConfidence(boxes),
+
+			ConfidenceHistogram: ConfidenceHistogram(boxes),
+		},
+		Lang: "eng",
+		Ver:  "fake",
+	}
+}
+
+// ExtractText returns the canned full text and mean confidence
+func (f *FakeEngine) ExtractText(ctx context.Context, img image.Image) (*Result, error) {
+	result, err := f.ExtractWithOptions(ctx, img, Options{})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Result{
+		Text:       result.FullText,
+		Confidence: result.MeanConfidence,
+	}, nil
+}
+
+// ExtractTextWithBoxes returns the canned detailed result
+func (f *FakeEngine) ExtractTextWithBoxes(ctx context.Context, img image.Image) (*DetailedResult, error) {
+	return f.ExtractWithOptions(ctx, img, Options{})
+}
+
+// ExtractWithOptions records the call and returns the canned detailed result
+func (f *FakeEngine) ExtractWithOptions(ctx context.Context, img image.Image, opts Options) (*DetailedResult, error) {
+	f.mu.Lock()
+	f.calls++
+	f.options = append(f.options, opts)
+	f.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if f.ExtractFunc != nil {
+		return f.ExtractFunc(ctx, img, opts)
+	}
+	if f.Err != nil {
+		return nil, f.Err
+	}
+	if f.Detailed == nil {
+		return &DetailedResult{Language: f.Language()}, nil
+	}
+
+	// Return a copy so callers can't modify the canned result
+	result := *f.Detailed
+	result.Boxes = append([]TextBox(nil), f.Detailed.Boxes...)
+	if opts.Language != "" {
+		result.Language = opts.Language
+	}
+	return &result, nil
+}
+
+// Language returns the configured language
+func (f *FakeEngine) Language() string {
+	return f.Lang
+}
+
+// Version returns the configured version
+func (f *FakeEngine) Version() string {
+	return f.Ver
+}
+
+// Close does nothing
+func (f *FakeEngine) Close() error {
+	return nil
+}
+
+// Calls returns how many extractions have been requested
+func (f *FakeEngine) Calls() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.calls
+}
+
+// Options returns the options passed to each extraction call, in order
+func (f *FakeEngine) Options() []Options {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]Options(nil), f.options...)
+}
@@ -0,0 +1,98 @@
+package ocr
+
+import (
+	"context"
+	"errors"
+	"image"
+)
+
+// FallbackEngine runs a primary engine and consults a secondary one, such
+// as a cloud provider, only when the primary result is not confident
+// enough. The more confident of the two results is returned.
+type FallbackEngine struct {
+	primary   Engine
+	secondary Engine
+	threshold float64
+}
+
+// NewFallbackEngine creates an engine that falls back to secondary when
+// the primary mean confidence is below threshold (0-1)
+func NewFallbackEngine(primary, secondary Engine, threshold float64) *FallbackEngine {
+	return &FallbackEngine{
+		primary:   primary,
+		secondary: secondary,
+		threshold: threshold,
+	}
+}
+
+// ExtractText extracts text, falling back on low confidence
+func (e *FallbackEngine) ExtractText(ctx context.Context, img image.Image) (*Result, error) {
+	result, err := e.primary.ExtractText(ctx, img)
+	if err == nil && result.Confidence >= e.threshold {
+		return result, nil
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	fallback, fallbackErr := e.secondary.ExtractText(ctx, img)
+	switch {
+	case fallbackErr != nil && err != nil:
+		return nil, errors.Join(err, fallbackErr)
+	case fallbackErr != nil:
+		return result, nil
+	case err != nil || fallback.Confidence > result.Confidence:
+		return fallback, nil
+	}
+	return result, nil
+}
+
+// ExtractTextWithBoxes extracts text with bounding boxes, falling back on
+// low confidence
+func (e *FallbackEngine) ExtractTextWithBoxes(ctx context.Context, img image.Image) (*DetailedResult, error) {
+	return e.ExtractWithOptions(ctx, img, Options{})
+}
+
+// ExtractWithOptions extracts text with bounding boxes using per-call
+// options, falling back on low confidence
+func (e *FallbackEngine) ExtractWithOptions(ctx context.Context, img image.Image, opts Options) (*DetailedResult, error) {
+	result, err := e.primary.ExtractWithOptions(ctx, img, opts)
+	if err == nil && result.MeanConfidence >= e.threshold {
+		return result, nil
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	fallback, fallbackErr := e.secondary.ExtractWithOptions(ctx, img, opts)
+	switch {
+	case fallbackErr != nil && err != nil:
+		return nil, errors.Join(err, fallbackErr)
+	case fallbackErr != nil:
+		return result, nil
+	case err != nil || fallback.MeanConfidence > result.MeanConfidence:
+		return fallback, nil
+	}
+	return result, nil
+}
+
+// Language returns the primary engine language
+func (e *FallbackEngine) Language() string {
+	return e.primary.Language()
+}
+
+// Version returns the primary engine version
+func (e *FallbackEngine) Version() string {
+	return e.primary.Version()
+}
+
+// ReloadLanguage implements LanguageReloader for both engines
+func (e *FallbackEngine) ReloadLanguage(lang string) {
+	ReloadLanguage(e.primary, lang)
+	ReloadLanguage(e.secondary, lang)
+}
+
+// Close releases both engines
+func (e *FallbackEngine) Close() error {
+	return errors.Join(e.primary.Close(), e.secondary.Close())
+}
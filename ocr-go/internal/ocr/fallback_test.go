@@ -0,0 +1,55 @@
+package ocr
+
+import (
+	"context"
+	"errors"
+	"image"
+	"testing"
+)
+
+func fakeWithConfidence(confidence float64) *FakeEngine {
+	return NewFakeEngine(TextBox{Text: "word", Confidence: confidence})
+}
+
+func TestFallbackEngine(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 1, 1))
+
+	tests := []struct {
+		name           string
+		primary        *FakeEngine
+		secondary      *FakeEngine
+		want           float64
+		secondaryCalls int
+	}{
+		{"confident primary", fakeWithConfidence(0.9), fakeWithConfidence(0.95), 0.9, 0},
+		{"better secondary", fakeWithConfidence(0.4), fakeWithConfidence(0.8), 0.8, 1},
+		{"worse secondary", fakeWithConfidence(0.4), fakeWithConfidence(0.3), 0.4, 1},
+		{"failing secondary", fakeWithConfidence(0.4), &FakeEngine{Err: errors.New("unavailable")}, 0.4, 1},
+		{"failing primary", &FakeEngine{Err: errors.New("crashed")}, fakeWithConfidence(0.3), 0.3, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := NewFallbackEngine(tt.primary, tt.secondary, 0.6)
+
+			result, err := engine.ExtractTextWithBoxes(context.Background(), img)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.MeanConfidence != tt.want {
+				t.Errorf("confidence = %v, want %v", result.MeanConfidence, tt.want)
+			}
+			if calls := tt.secondary.Calls(); calls != tt.secondaryCalls {
+				t.Errorf("secondary called %d times, want %d", calls, tt.secondaryCalls)
+			}
+		})
+	}
+}
+
+func TestFallbackEngineBothFail(t *testing.T) {
+	engine := NewFallbackEngine(&FakeEngine{Err: errors.New("crashed")}, &FakeEngine{Err: errors.New("unavailable")}, 0.6)
+
+	if _, err := engine.ExtractTextWithBoxes(context.Background(), image.NewGray(image.Rect(0, 0, 1, 1))); err == nil {
+		t.Fatal("expected an error when both engines fail")
+	}
+}
@@ -0,0 +1,71 @@
+package ocr
+
+import (
+	"image"
+	"math"
+	"regexp"
+	"strconv"
+)
+
+// hocrLinePattern matches the text lines of Tesseract's hOCR output with
+// their bounding box and baseline. Captions, headings and floating text
+// are lines too.
+var hocrLinePattern = regexp.MustCompile(`class='ocr_(?:line|caption|header|textfloat)'[^>]*title="bbox (\d+) (\d+) (\d+) (\d+); baseline (-?[0-9.]+) `)
+
+// hocrLine is a text line and the slope of its baseline, in pixels down
+// per pixel right
+type hocrLine struct {
+	bounds image.Rectangle
+	slope  float64
+}
+
+// parseHOCRLines returns the lines of hOCR output that carry a baseline
+func parseHOCRLines(hocr string) []hocrLine {
+	var lines []hocrLine
+	for _, m := range hocrLinePattern.FindAllStringSubmatch(hocr, -1) {
+		var c [4]int
+		for i := range c {
+			c[i], _ = strconv.Atoi(m[i+1])
+		}
+		slope, err := strconv.ParseFloat(m[5], 64)
+		if err != nil {
+			continue
+		}
+		lines = append(lines, hocrLine{bounds: image.Rect(c[0], c[1], c[2], c[3]), slope: slope})
+	}
+	return lines
+}
+
+// addPolygons gives each box on a skewed line the quad its baseline
+// outlines. Boxes are matched to lines by their center; boxes on straight
+// lines, or on none, keep no polygon.
+func addPolygons(boxes []TextBox, lines []hocrLine) {
+	for i := range boxes {
+		b := boxes[i].Box
+		center := image.Pt(b.X+b.Width/2, b.Y+b.Height/2)
+		for _, line := range lines {
+			if center.In(line.bounds) {
+				boxes[i].Polygon = wordPolygon(b, line.slope)
+				break
+			}
+		}
+	}
+}
+
+// wordPolygon returns the parallelogram of a word written along a
+// baseline of the given slope that fills box, corners clockwise from the
+// top left. The rise across the word is taken off the opposite corners;
+// nil is returned when it is under a pixel or leaves no height.
+func wordPolygon(box BoundingBox, slope float64) []Point {
+	rise := int(math.Round(math.Abs(slope) * float64(box.Width)))
+	if rise < 1 || rise >= box.Height {
+		return nil
+	}
+	left, right := box.X, box.X+box.Width
+	top, bottom := box.Y, box.Y+box.Height
+	if slope > 0 {
+		// Descending to the right
+		return []Point{{left, top}, {right, top + rise}, {right, bottom}, {left, bottom - rise}}
+	}
+	return []Point{{left, top + rise}, {right, top}, {right, bottom - rise}, {left, bottom}}
+}
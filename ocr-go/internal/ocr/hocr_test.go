@@ -0,0 +1,73 @@
+package ocr
+
+import (
+	"context"
+	"image"
+	"reflect"
+	"testing"
+)
+
+// skewedHOCR has one line descending to the right and one straight line
+const skewedHOCR = `<div class='ocr_carea' id='block_1_1' title="bbox 0 0 200 100">
+ <p class='ocr_par' id='par_1_1' lang='eng' title="bbox 0 0 200 100">
+  <span class='ocr_line' id='line_1_1' title="bbox 0 0 200 40; baseline 0.1 -2; x_size 20; x_descenders 4; x_ascenders 5">
+   <span class='ocrx_word' id='word_1_1' title='bbox 0 0 100 30; x_wconf 90'>hello</span>
+  </span>
+  <span class='ocr_line' id='line_1_2' title="bbox 0 60 200 80; baseline 0 -3; x_size 20; x_descenders 4; x_ascenders 5">
+   <span class='ocrx_word' id='word_1_2' title='bbox 0 60 80 80; x_wconf 90'>world</span>
+  </span>
+ </p>
+</div>`
+
+func TestParseHOCRLines(t *testing.T) {
+	want := []hocrLine{
+		{bounds: image.Rect(0, 0, 200, 40), slope: 0.1},
+		{bounds: image.Rect(0, 60, 200, 80), slope: 0},
+	}
+	if got := parseHOCRLines(skewedHOCR); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseHOCRLines() = %+v, want %+v", got, want)
+	}
+}
+
+func TestWordPolygon(t *testing.T) {
+	box := BoundingBox{X: 10, Y: 20, Width: 100, Height: 30}
+	tests := []struct {
+		slope float64
+		want  []Point
+	}{
+		{0.1, []Point{{10, 20}, {110, 30}, {110, 50}, {10, 40}}},
+		{-0.1, []Point{{10, 30}, {110, 20}, {110, 40}, {10, 50}}},
+		{0.001, nil}, // under a pixel across the word
+		{0.5, nil},   // the rise would take the whole height
+	}
+	for _, tt := range tests {
+		if got := wordPolygon(box, tt.slope); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("wordPolygon(%v) = %v, want %v", tt.slope, got, tt.want)
+		}
+	}
+}
+
+func TestExtractPolygons(t *testing.T) {
+	engine, client := newFlakyEngine(0)
+	client.hocr = skewedHOCR
+	img := image.NewGray(image.Rect(0, 0, 1, 1))
+
+	result, err := engine.ExtractWithOptions(context.Background(), img, Options{Polygons: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The client's word, 10x5 at the origin, lies on the skewed line
+	want := []Point{{0, 0}, {10, 1}, {10, 5}, {0, 4}}
+	if got := result.Boxes[0].Polygon; !reflect.DeepEqual(got, want) {
+		t.Errorf("polygon = %v, want %v", got, want)
+	}
+
+	client.hocrCalls = 0
+	result, err = engine.ExtractWithOptions(context.Background(), img, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Boxes[0].Polygon != nil || client.hocrCalls != 0 {
+		t.Errorf("polygon = %v after %d hOCR calls, want none without Polygons", result.Boxes[0].Polygon, client.hocrCalls)
+	}
+}
@@ -0,0 +1,192 @@
+package ocr
+
+import "strings"
+
+// valueBelowGap is the largest vertical gap between a label and a value on
+// the next line, relative to the label's line height
+const valueBelowGap = 1.0
+
+// KeyValue is a form label paired with its value, such as "Total" and
+// "$12.50" from "Total: $12.50". Key holds the label without its colon.
+type KeyValue struct {
+	Key   TextBox
+	Value TextBox
+}
+
+// formLine is a line of words with the label and value assignment of each
+type formLine struct {
+	textLine
+
+	// keyStart maps the index of each word ending a label to the index of
+	// the label's first word
+	keyStart map[int]int
+
+	// key marks the words belonging to a label, used the words already
+	// taken as a value
+	key  []bool
+	used []bool
+
+	// pairs maps the index of each word ending a label to its pair
+	pairs map[int]KeyValue
+}
+
+// KeyValues pairs the labels of a form with their values. A label is a
+// phrase ending in ":", its value is the phrase to its right on the same
+// line or, when there is none, the phrase just below that overlaps it
+// horizontally. Phrases break at gaps wider than PhraseGap word heights.
+// A value always takes the first word after a label, so "No: 123 Date: 5"
+// reads as No=123 and Date=5, while a value running straight into the
+// next label without a gap may lose its last words to that label. Labels
+// without a value are left out.
+func KeyValues(boxes []TextBox) []KeyValue {
+	if len(boxes) == 0 {
+		return nil
+	}
+	maxGap := PhraseGap * averageHeight(boxes)
+
+	lines := make([]formLine, 0)
+	for _, line := range groupLines(boxes) {
+		lines = append(lines, newFormLine(line, maxGap))
+	}
+
+	// Values to the right win over values below, so every line is paired
+	// on its own before looking across lines
+	pending := make([][]int, len(lines))
+	for i := range lines {
+		line := &lines[i]
+		for j := range line.boxes {
+			start, ok := line.keyStart[j]
+			if !ok {
+				continue
+			}
+			end := j + 1
+			for end < len(line.boxes) && !line.key[end] && (end == j+1 || line.gap(end) <= maxGap) {
+				end++
+			}
+			if end == j+1 {
+				pending[i] = append(pending[i], j)
+				continue
+			}
+			if pair, ok := newKeyValue(line.boxes[start:j+1], line.boxes[j+1:end]); ok {
+				line.pairs[j] = pair
+				line.take(j+1, end)
+			}
+		}
+	}
+
+	for i := range lines {
+		if i+1 == len(lines) {
+			break
+		}
+		line, below := &lines[i], &lines[i+1]
+		height := float64(line.bottom - line.top)
+		if float64(below.top-line.bottom) > height*valueBelowGap {
+			continue
+		}
+		for _, j := range pending[i] {
+			key := line.boxes[line.keyStart[j] : j+1]
+			start, end, ok := below.valueUnder(mergePhrase(key).Box, maxGap)
+			if !ok {
+				continue
+			}
+			if pair, ok := newKeyValue(key, below.boxes[start:end]); ok {
+				line.pairs[j] = pair
+				below.take(start, end)
+			}
+		}
+	}
+
+	// Report the pairs in the reading order of their labels
+	var pairs []KeyValue
+	for _, line := range lines {
+		for j := range line.boxes {
+			if pair, ok := line.pairs[j]; ok {
+				pairs = append(pairs, pair)
+			}
+		}
+	}
+	return pairs
+}
+
+// newFormLine finds the labels of line. A label runs back from a word
+// ending in ":" over the words of its phrase, but never over the word
+// right after another label, which is that label's value.
+func newFormLine(line textLine, maxGap float64) formLine {
+	f := formLine{
+		textLine: line,
+		keyStart: make(map[int]int),
+		key:      make([]bool, len(line.boxes)),
+		used:     make([]bool, len(line.boxes)),
+		pairs:    make(map[int]KeyValue),
+	}
+	for j, box := range line.boxes {
+		if !strings.HasSuffix(box.Text, ":") {
+			continue
+		}
+		k := j
+		for k > 0 && !f.key[k-1] && f.gap(k) <= maxGap && !(k > 1 && f.key[k-2]) {
+			k--
+		}
+		f.keyStart[j] = k
+		for m := k; m <= j; m++ {
+			f.key[m] = true
+		}
+	}
+	return f
+}
+
+// gap returns the horizontal gap between word i and the word before it
+func (f *formLine) gap(i int) float64 {
+	prev := f.boxes[i-1].Box
+	return float64(f.boxes[i].Box.X - (prev.X + prev.Width))
+}
+
+// free reports whether word i can still become part of a value
+func (f *formLine) free(i int) bool {
+	return !f.key[i] && !f.used[i]
+}
+
+// take marks words start to end (exclusive) as used by a value
+func (f *formLine) take(start, end int) {
+	for i := start; i < end; i++ {
+		f.used[i] = true
+	}
+}
+
+// valueUnder returns the phrase of free words on this line that overlaps
+// the label box horizontally, as the range start to end (exclusive)
+func (f *formLine) valueUnder(label BoundingBox, maxGap float64) (int, int, bool) {
+	for i, box := range f.boxes {
+		overlap := min(label.X+label.Width, box.Box.X+box.Box.Width) - max(label.X, box.Box.X)
+		if overlap <= 0 || !f.free(i) {
+			continue
+		}
+		start, end := i, i+1
+		for start > 0 && f.free(start-1) && f.gap(start) <= maxGap {
+			start--
+		}
+		for end < len(f.boxes) && f.free(end) && f.gap(end) <= maxGap {
+			end++
+		}
+		return start, end, true
+	}
+	return 0, 0, false
+}
+
+// newKeyValue builds a pair from the label and value words, reporting
+// false when the label has no text besides its colon
+func newKeyValue(key, value []TextBox) (KeyValue, bool) {
+	pair := KeyValue{Key: mergePhrase(key), Value: mergePhrase(value)}
+	pair.Key.Text = strings.TrimSpace(strings.TrimSuffix(pair.Key.Text, ":"))
+	pair.Key.Polygon, pair.Value.Polygon = nil, nil
+	return pair, pair.Key.Text != ""
+}
+
+// averageHeight returns the mean box height
+func averageHeight(boxes []TextBox) float64 {
+	var height int
+	for _, box := range boxes {
+		height += box.Box.Height
+	}
+	return float64(height) / float64(len(boxes))
+}
@@ -0,0 +1,106 @@
+package ocr
+
+import (
+	"reflect"
+	"testing"
+)
+
+// pairTexts flattens pairs to key=value strings
+func pairTexts(pairs []KeyValue) []string {
+	texts := make([]string, len(pairs))
+	for i, pair := range pairs {
+		texts[i] = pair.Key.Text + "=" + pair.Value.Text
+	}
+	return texts
+}
+
+// wordAt returns a 10 pixel high word as wide as its text
+func wordAt(text string, x, y int) TextBox {
+	return TextBox{Text: text, Confidence: 0.9, Box: BoundingBox{X: x, Y: y, Width: 8 * len(text), Height: 10}}
+}
+
+func TestKeyValues(t *testing.T) {
+	tests := []struct {
+		name  string
+		boxes []TextBox
+		want  []string
+	}{
+		{
+			name: "receipt with values to the right",
+			boxes: []TextBox{
+				wordAt("Subtotal:", 0, 0), wordAt("$10.00", 200, 0),
+				wordAt("Tax:", 0, 20), wordAt("$2.50", 200, 21),
+				wordAt("Total:", 0, 40), wordAt("$12.50", 56, 40),
+			},
+			want: []string{"Subtotal=$10.00", "Tax=$2.50", "Total=$12.50"},
+		},
+		{
+			name: "form with values below",
+			boxes: []TextBox{
+				wordAt("Full", 0, 0), wordAt("Name:", 40, 0), wordAt("Date:", 200, 0),
+				wordAt("Jane", 0, 15), wordAt("Doe", 40, 15), wordAt("2024-01-31", 200, 15),
+			},
+			want: []string{"Full Name=Jane Doe", "Date=2024-01-31"},
+		},
+		{
+			name: "several fields on one line",
+			boxes: []TextBox{
+				wordAt("Invoice", 0, 0), wordAt("No:", 64, 0), wordAt("123", 96, 0),
+				wordAt("Date:", 128, 0), wordAt("5", 172, 0),
+			},
+			want: []string{"Invoice No=123", "Date=5"},
+		},
+		{
+			name: "colon as a separate word",
+			boxes: []TextBox{
+				wordAt("Total", 0, 0), wordAt(":", 44, 0), wordAt("7", 100, 0),
+			},
+			want: []string{"Total=7"},
+		},
+		{
+			name: "right value wins over the line below",
+			boxes: []TextBox{
+				wordAt("Name:", 0, 0),
+				wordAt("Ref:", 0, 15), wordAt("A1", 40, 15),
+			},
+			want: []string{"Ref=A1"},
+		},
+		{
+			name: "no labels",
+			boxes: []TextBox{
+				wordAt("Thank", 0, 0), wordAt("you", 48, 0),
+			},
+			want: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := pairTexts(KeyValues(tt.boxes)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("KeyValues() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeyValuesBoxes(t *testing.T) {
+	pairs := KeyValues([]TextBox{
+		{Text: "Total:", Confidence: 0.9, Box: BoundingBox{X: 0, Y: 0, Width: 48, Height: 10}},
+		{Text: "12.50", Confidence: 0.6, Box: BoundingBox{X: 100, Y: 0, Width: 40, Height: 10}},
+		{Text: "EUR", Confidence: 0.8, Box: BoundingBox{X: 145, Y: 0, Width: 24, Height: 10}},
+	})
+	if len(pairs) != 1 {
+		t.Fatalf("got %d pairs, want 1", len(pairs))
+	}
+
+	value := pairs[0].Value
+	if value.Text != "12.50 EUR" || value.Box != (BoundingBox{X: 100, Y: 0, Width: 69, Height: 10}) {
+		t.Errorf("value = %q %+v", value.Text, value.Box)
+	}
+	if value.Confidence < 0.699 || value.Confidence > 0.701 {
+		t.Errorf("value confidence = %v, want 0.7", value.Confidence)
+	}
+	if key := pairs[0].Key; key.Text != "Total" || key.Confidence != 0.9 {
+		t.Errorf("key = %q (%v), want Total (0.9)", key.Text, key.Confidence)
+	}
+}
@@ -0,0 +1,159 @@
+package ocr
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"sync"
+)
+
+// LanguageFactory creates an engine whose default language is lang
+type LanguageFactory func(lang string) (Engine, error)
+
+// LanguageEngine routes calls asking for another language than the
+// default to an engine dedicated to that language. Switching the language
+// of a Tesseract client reloads its models, so each language gets its own
+// engine, created on first use and kept warm. At most capacity languages
+// are kept besides the default; the least recently used one is closed
+// once it is idle.
+type LanguageEngine struct {
+	Engine
+
+	factory  LanguageFactory
+	capacity int
+
+	mu      sync.Mutex
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+// languageEntry is the engine of one language and its pending calls
+type languageEntry struct {
+	lang    string
+	engine  Engine
+	users   int
+	evicted bool
+}
+
+// NewLanguageEngine wraps engine, which serves its own language and calls
+// without one, creating engines for other languages with factory
+func NewLanguageEngine(engine Engine, factory LanguageFactory, capacity int) *LanguageEngine {
+	return &LanguageEngine{
+		Engine:   engine,
+		factory:  factory,
+		capacity: capacity,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+}
+
+// ExtractTextWithBoxes extracts text with bounding boxes in the default
+// language
+func (e *LanguageEngine) ExtractTextWithBoxes(ctx context.Context, img image.Image) (*DetailedResult, error) {
+	return e.ExtractWithOptions(ctx, img, Options{})
+}
+
+// ExtractWithOptions runs the engine of opts.Language, creating it if
+// needed
+func (e *LanguageEngine) ExtractWithOptions(ctx context.Context, img image.Image, opts Options) (*DetailedResult, error) {
+	if opts.Language == "" || opts.Language == e.Engine.Language() {
+		return e.Engine.ExtractWithOptions(ctx, img, opts)
+	}
+
+	entry, err := e.acquire(opts.Language)
+	if err != nil {
+		return nil, err
+	}
+	defer e.release(entry)
+	return entry.engine.ExtractWithOptions(ctx, img, opts)
+}
+
+// ExtractTextFromBytes implements BytesExtractor in the default language
+func (e *LanguageEngine) ExtractTextFromBytes(ctx context.Context, data []byte) (*DetailedResult, error) {
+	return ExtractFromBytes(ctx, e.Engine, data)
+}
+
+// Languages returns the languages with a warm engine besides the default,
+// most recently used first
+func (e *LanguageEngine) Languages() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	langs := make([]string, 0, e.order.Len())
+	for elem := e.order.Front(); elem != nil; elem = elem.Next() {
+		langs = append(langs, elem.Value.(*languageEntry).lang)
+	}
+	return langs
+}
+
+// ReloadLanguage implements LanguageReloader for the default engine and
+// every warm one
+func (e *LanguageEngine) ReloadLanguage(lang string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	ReloadLanguage(e.Engine, lang)
+	for elem := e.order.Front(); elem != nil; elem = elem.Next() {
+		ReloadLanguage(elem.Value.(*languageEntry).engine, lang)
+	}
+}
+
+// Close releases the default engine and every warm one
+func (e *LanguageEngine) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	errs := []error{e.Engine.Close()}
+	for elem := e.order.Front(); elem != nil; elem = elem.Next() {
+		errs = append(errs, elem.Value.(*languageEntry).engine.Close())
+	}
+	e.order.Init()
+	clear(e.entries)
+	return errors.Join(errs...)
+}
+
+// acquire returns the engine of lang, creating it and evicting the least
+// recently used language when over capacity. The engine stays open until
+// the caller releases it.
+func (e *LanguageEngine) acquire(lang string) (*languageEntry, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if elem, ok := e.entries[lang]; ok {
+		e.order.MoveToFront(elem)
+		entry := elem.Value.(*languageEntry)
+		entry.users++
+		return entry, nil
+	}
+
+	engine, err := e.factory(lang)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s engine: %w", lang, err)
+	}
+	entry := &languageEntry{lang: lang, engine: engine, users: 1}
+	e.entries[lang] = e.order.PushFront(entry)
+
+	for e.order.Len() > e.capacity {
+		oldest := e.order.Remove(e.order.Back()).(*languageEntry)
+		delete(e.entries, oldest.lang)
+		oldest.evicted = true
+		if oldest.users == 0 {
+			oldest.engine.Close()
+		}
+	}
+	return entry, nil
+}
+
+// release ends a call on entry, closing its engine if it was evicted
+// meanwhile
+func (e *LanguageEngine) release(entry *languageEntry) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	entry.users--
+	if entry.evicted && entry.users == 0 {
+		entry.engine.Close()
+	}
+}
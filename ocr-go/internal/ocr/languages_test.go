@@ -0,0 +1,95 @@
+package ocr
+
+import (
+	"context"
+	"image"
+	"reflect"
+	"testing"
+)
+
+// closeTracker is a fake engine recording whether it was closed
+type closeTracker struct {
+	*FakeEngine
+	closed bool
+}
+
+func (c *closeTracker) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestLanguageEngine(t *testing.T) {
+	ctx := context.Background()
+	img := image.NewGray(image.Rect(0, 0, 1, 1))
+
+	primary := &FakeEngine{Lang: "eng"}
+	created := make(map[string]*closeTracker)
+	factory := func(lang string) (Engine, error) {
+		engine := &closeTracker{FakeEngine: &FakeEngine{Lang: lang}}
+		created[lang] = engine
+		return engine, nil
+	}
+	engine := NewLanguageEngine(primary, factory, 2)
+
+	for _, lang := range []string{"", "eng", "fra", "spa", "fra"} {
+		result, err := engine.ExtractWithOptions(ctx, img, Options{Language: lang})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := lang
+		if want == "" {
+			want = "eng"
+		}
+		if result.Language != want {
+			t.Errorf("language = %q, want %q", result.Language, want)
+		}
+	}
+	if calls := primary.Calls(); calls != 2 {
+		t.Errorf("default engine called %d times, want 2", calls)
+	}
+	if len(created) != 2 || created["fra"].Calls() != 2 {
+		t.Errorf("created %v, want one warm engine per language", created)
+	}
+
+	// A third language evicts the least recently used one
+	if _, err := engine.ExtractWithOptions(ctx, img, Options{Language: "deu"}); err != nil {
+		t.Fatal(err)
+	}
+	if !created["spa"].closed || created["fra"].closed {
+		t.Errorf("spa closed = %v, fra closed = %v; want only spa evicted", created["spa"].closed, created["fra"].closed)
+	}
+	if got, want := engine.Languages(), []string{"deu", "fra"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Languages() = %v, want %v", got, want)
+	}
+}
+
+func TestLanguageEngineEvictsIdleOnly(t *testing.T) {
+	ctx := context.Background()
+	img := image.NewGray(image.Rect(0, 0, 1, 1))
+
+	created := make(map[string]*closeTracker)
+	var engine *LanguageEngine
+	factory := func(lang string) (Engine, error) {
+		fake := &FakeEngine{Lang: lang}
+		if lang == "fra" {
+			// Another language is needed while this call is running
+			fake.ExtractFunc = func(ctx context.Context, img image.Image, opts Options) (*DetailedResult, error) {
+				engine.ExtractWithOptions(ctx, img, Options{Language: "spa"})
+				if created["fra"].closed {
+					t.Error("fra closed while in use")
+				}
+				return &DetailedResult{Language: "fra"}, nil
+			}
+		}
+		created[lang] = &closeTracker{FakeEngine: fake}
+		return created[lang], nil
+	}
+	engine = NewLanguageEngine(&FakeEngine{Lang: "eng"}, factory, 1)
+
+	if _, err := engine.ExtractWithOptions(ctx, img, Options{Language: "fra"}); err != nil {
+		t.Fatal(err)
+	}
+	if !created["fra"].closed {
+		t.Error("evicted fra not closed after its call")
+	}
+}
@@ -0,0 +1,118 @@
+package ocr
+
+import (
+	"sort"
+	"strings"
+)
+
+// paragraphGap is the vertical gap between lines, relative to the line
+// height, above which a new paragraph starts
+const paragraphGap = 1.0
+
+// textLine is a group of boxes sharing a baseline
+type textLine struct {
+	boxes  []TextBox
+	top    int
+	bottom int
+}
+
+// Layout reconstructs the text of boxes with its line structure: boxes
+// that overlap vertically form a line read left to right, lines are
+// separated by newlines and paragraphs by a blank line
+func Layout(boxes []TextBox) string {
+	lines := groupLines(boxes)
+
+	var sb strings.Builder
+	for i, line := range lines {
+		if i > 0 {
+			sb.WriteByte('\n')
+			prev := lines[i-1]
+			height := float64(line.bottom-line.top+prev.bottom-prev.top) / 2
+			if float64(line.top-prev.bottom) > height*paragraphGap {
+				sb.WriteByte('\n')
+			}
+		}
+		sb.WriteString(JoinWords(line.boxes))
+	}
+	return sb.String()
+}
+
+// SortReadingOrder returns boxes in reading order: lines top to bottom,
+// each left to right. Boxes whose vertical extents overlap by at least half
+// the shorter height share a line, so words on a slightly slanted or
+// uneven baseline stay together.
+func SortReadingOrder(boxes []TextBox) []TextBox {
+	sorted := make([]TextBox, 0, len(boxes))
+	for _, line := range groupLines(boxes) {
+		sorted = append(sorted, line.boxes...)
+	}
+	return sorted
+}
+
+// JoinWords joins the text of boxes with single spaces, in the given order
+func JoinWords(boxes []TextBox) string {
+	words := make([]string, len(boxes))
+	for i, box := range boxes {
+		words[i] = box.Text
+	}
+	return strings.Join(words, " ")
+}
+
+// JoinLines joins the text of boxes in the given order, with a newline
+// where the next box starts more than a box height above or below the
+// previous one and a space otherwise. Unlike Layout it keeps the order and
+// does not rebuild paragraphs, so lists and addresses keep their line
+// breaks at little cost.
+func JoinLines(boxes []TextBox) string {
+	var text strings.Builder
+	for i, box := range boxes {
+		if i > 0 {
+			prev := boxes[i-1].Box
+			if gap := box.Box.Y - prev.Y; gap > prev.Height || -gap > prev.Height {
+				text.WriteByte('\n')
+			} else {
+				text.WriteByte(' ')
+			}
+		}
+		text.WriteString(box.Text)
+	}
+	return text.String()
+}
+
+// groupLines clusters boxes into lines sorted top to bottom, each sorted
+// left to right
+func groupLines(boxes []TextBox) []textLine {
+	sorted := make([]TextBox, len(boxes))
+	copy(sorted, boxes)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Box.Y < sorted[j].Box.Y
+	})
+
+	var lines []textLine
+	for _, box := range sorted {
+		top, bottom := box.Box.Y, box.Box.Y+box.Box.Height
+		if n := len(lines); n > 0 && overlapsLine(lines[n-1], top, bottom) {
+			line := &lines[n-1]
+			line.boxes = append(line.boxes, box)
+			line.top = min(line.top, top)
+			line.bottom = max(line.bottom, bottom)
+			continue
+		}
+		lines = append(lines, textLine{boxes: []TextBox{box}, top: top, bottom: bottom})
+	}
+
+	for _, line := range lines {
+		sort.SliceStable(line.boxes, func(i, j int) bool {
+			return line.boxes[i].Box.X < line.boxes[j].Box.X
+		})
+	}
+	return lines
+}
+
+// overlapsLine reports whether a box spanning top to bottom overlaps line
+// by at least half of the shorter of the two heights
+func overlapsLine(line textLine, top, bottom int) bool {
+	overlap := min(line.bottom, bottom) - max(line.top, top)
+	shorter := min(line.bottom-line.top, bottom-top)
+	return overlap > 0 && overlap*2 >= shorter
+}
@@ -0,0 +1,67 @@
+package ocr
+
+import (
+	"strings"
+	"testing"
+)
+
+func word(text string, x, y int) TextBox {
+	return TextBox{Text: text, Box: BoundingBox{X: x, Y: y, Width: 40, Height: 10}}
+}
+
+func TestLayout(t *testing.T) {
+	boxes := []TextBox{
+		word("Street", 50, 21),
+		word("Main", 0, 20),
+		word("Springfield", 0, 35),
+		word("Dear", 0, 80),
+		word("Sir", 50, 79),
+	}
+
+	want := "Main Street\nSpringfield\n\nDear Sir"
+	if got := Layout(boxes); got != want {
+		t.Errorf("Layout() = %q, want %q", got, want)
+	}
+}
+
+func TestLayoutEmpty(t *testing.T) {
+	if got := Layout(nil); got != "" {
+		t.Errorf("Layout(nil) = %q, want empty", got)
+	}
+}
+
+func TestSortReadingOrder(t *testing.T) {
+	boxes := []TextBox{
+		word("Springfield", 0, 35),
+		word("Street", 50, 22),
+		word("Main", 0, 20),
+		word("Sir", 50, 79),
+		word("Dear", 0, 80),
+	}
+
+	var got []string
+	for _, box := range SortReadingOrder(boxes) {
+		got = append(got, box.Text)
+	}
+	want := []string{"Main", "Street", "Springfield", "Dear", "Sir"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("SortReadingOrder() = %v, want %v", got, want)
+	}
+}
+
+func TestJoinLines(t *testing.T) {
+	// Street sits a pixel lower than Main and Springfield a line below, the
+	// boxes keep their engine order
+	boxes := []TextBox{word("Main", 0, 20), word("Street", 50, 21), word("Springfield", 0, 35), word("IL", 50, 20)}
+	want := "Main Street\nSpringfield\nIL"
+	if got := JoinLines(boxes); got != want {
+		t.Errorf("JoinLines() = %q, want %q", got, want)
+	}
+}
+
+func TestJoinWords(t *testing.T) {
+	boxes := []TextBox{word("Main", 0, 20), word("Springfield", 0, 35)}
+	if got := JoinWords(boxes); got != "Main Springfield" {
+		t.Errorf("JoinWords() = %q, want %q", got, "Main Springfield")
+	}
+}
@@ -0,0 +1,65 @@
+package ocr
+
+import "strings"
+
+// PhraseGap is the default horizontal gap between words, relative to the
+// average word height, up to which adjacent words form one phrase. Word
+// spacing is usually well below a word height, while the space between a
+// label and its value in forms and tables is wider.
+const PhraseGap = 1.0
+
+// GroupPhrases merges words on the same line whose horizontal gap is at
+// most gap times the average word height into phrase boxes. A phrase box
+// encloses its words, joins their text with spaces and carries their mean
+// confidence. Phrases are returned in reading order. The grouping only
+// depends on the box geometry, so boxes with the same geometry but
+// different text group the same way.
+func GroupPhrases(boxes []TextBox, gap float64) []TextBox {
+	if len(boxes) == 0 {
+		return nil
+	}
+
+	maxGap := gap * averageHeight(boxes)
+
+	var phrases []TextBox
+	for _, line := range groupLines(boxes) {
+		start := 0
+		for i := 1; i <= len(line.boxes); i++ {
+			if i < len(line.boxes) {
+				prev, next := line.boxes[i-1].Box, line.boxes[i].Box
+				if float64(next.X-(prev.X+prev.Width)) <= maxGap {
+					continue
+				}
+			}
+			phrases = append(phrases, mergePhrase(line.boxes[start:i]))
+			start = i
+		}
+	}
+	return phrases
+}
+
+// mergePhrase combines the words of one phrase into a single box
+func mergePhrase(words []TextBox) TextBox {
+	if len(words) == 1 {
+		return words[0]
+	}
+
+	texts := make([]string, len(words))
+	var confidence float64
+	left, top := words[0].Box.X, words[0].Box.Y
+	right, bottom := left+words[0].Box.Width, top+words[0].Box.Height
+	for i, word := range words {
+		texts[i] = word.Text
+		confidence += word.Confidence
+		left = min(left, word.Box.X)
+		top = min(top, word.Box.Y)
+		right = max(right, word.Box.X+word.Box.Width)
+		bottom = max(bottom, word.Box.Y+word.Box.Height)
+	}
+
+	return TextBox{
+		Text:       strings.Join(texts, " "),
+		Confidence: confidence / float64(len(words)),
+		Box:        BoundingBox{X: left, Y: top, Width: right - left, Height: bottom - top},
+	}
+}
@@ -0,0 +1,35 @@
+package ocr
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGroupPhrases(t *testing.T) {
+	// Words are 40x10, so phrases break at gaps wider than 10 pixels
+	boxes := []TextBox{
+		{Text: "Number", Confidence: 0.8, Box: BoundingBox{X: 45, Y: 1, Width: 40, Height: 10}},
+		{Text: "Invoice", Confidence: 0.9, Box: BoundingBox{X: 0, Y: 0, Width: 40, Height: 10}},
+		{Text: "12345", Confidence: 0.7, Box: BoundingBox{X: 200, Y: 0, Width: 40, Height: 10}},
+		{Text: "Total", Confidence: 0.6, Box: BoundingBox{X: 0, Y: 30, Width: 40, Height: 10}},
+	}
+
+	want := []TextBox{
+		{Text: "Invoice Number", Confidence: 0.85, Box: BoundingBox{X: 0, Y: 0, Width: 85, Height: 11}},
+		{Text: "12345", Confidence: 0.7, Box: BoundingBox{X: 200, Y: 0, Width: 40, Height: 10}},
+		{Text: "Total", Confidence: 0.6, Box: BoundingBox{X: 0, Y: 30, Width: 40, Height: 10}},
+	}
+	got := GroupPhrases(boxes, PhraseGap)
+	for i := range got {
+		got[i].Confidence = float64(int(got[i].Confidence*100+0.5)) / 100
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GroupPhrases() = %+v, want %+v", got, want)
+	}
+}
+
+func TestGroupPhrasesEmpty(t *testing.T) {
+	if got := GroupPhrases(nil, PhraseGap); got != nil {
+		t.Errorf("GroupPhrases(nil) = %v, want nil", got)
+	}
+}
@@ -0,0 +1,84 @@
+package ocr
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+)
+
+// RetryPolicy controls retries of transient engine failures
+type RetryPolicy struct {
+	// Attempts is the total number of tries, including the first one
+	Attempts int
+
+	// Backoff is the wait before the first retry; it doubles on each retry
+	Backoff time.Duration
+}
+
+// DefaultRetryPolicy retries a transient failure twice with a short backoff
+var DefaultRetryPolicy = RetryPolicy{Attempts: 3, Backoff: 50 * time.Millisecond}
+
+// transientError marks a failure that may succeed when retried, such as
+// setting the image right after the client has been reinitialized
+type transientError struct {
+	err error
+}
+
+func (e *transientError) Error() string { return e.err.Error() }
+func (e *transientError) Unwrap() error { return e.err }
+
+// transient marks err as retryable
+func transient(err error) error {
+	return &transientError{err: err}
+}
+
+// transientMessages are the gosseract failures seen when the client is used
+// right after being reinitialized, which go away when the call is repeated.
+// Anything else, such as empty image data or a missing API, fails the same
+// way every time.
+var transientMessages = []string{
+	"failed to initialize TessBaseAPI",
+	"PixImage is not set",
+}
+
+// classify marks err as retryable when it is one of the known transient
+// gosseract failures and returns it unchanged otherwise
+func classify(err error) error {
+	for _, msg := range transientMessages {
+		if strings.Contains(err.Error(), msg) {
+			return transient(err)
+		}
+	}
+	return err
+}
+
+// isTransient reports whether err is worth retrying. Context cancellation
+// and deadlines are never retried, nor is anything not marked transient.
+func isTransient(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var te *transientError
+	return errors.As(err, &te)
+}
+
+// withRetry calls fn until it succeeds, fails permanently, the attempts are
+// exhausted or ctx is done
+func withRetry[T any](ctx context.Context, policy RetryPolicy, fn func() (T, error)) (T, error) {
+	backoff := policy.Backoff
+
+	for attempt := 1; ; attempt++ {
+		value, err := fn()
+		if err == nil || attempt >= policy.Attempts || !isTransient(err) {
+			return value, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return value, ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
@@ -0,0 +1,127 @@
+package ocr
+
+import "sort"
+
+// Table is a grid of cells detected from word positions
+type Table struct {
+	// Cells holds one row per detected line, each with one cell per
+	// detected column; cells without words have empty text
+	Cells [][]TableCell
+}
+
+// TableCell is one cell of a detected table. Box spans the cell's row and
+// column, so cells of a row share their top and bottom and cells of a
+// column their left and right edges.
+type TableCell struct {
+	Text       string
+	Confidence float64
+	Box        BoundingBox
+}
+
+// span is a horizontal extent, from start up to end
+type span struct {
+	start, end int
+}
+
+// DetectTable lays boxes out as a table: phrases on the same line form a
+// row, and the columns are the horizontal bands separated by gaps that no
+// phrase on a multi-phrase line crosses. Each phrase lands in the column it
+// overlaps the most; phrases sharing a cell are joined with spaces and
+// their confidence averaged.
+func DetectTable(boxes []TextBox) Table {
+	phrases := GroupPhrases(boxes, PhraseGap)
+	if len(phrases) == 0 {
+		return Table{}
+	}
+
+	// Titles and notes spanning the table would merge all its columns, so
+	// lines with a single phrase only count when there is nothing else
+	lines := groupLines(phrases)
+	var gridded []TextBox
+	for _, line := range lines {
+		if len(line.boxes) > 1 {
+			gridded = append(gridded, line.boxes...)
+		}
+	}
+	if len(gridded) == 0 {
+		gridded = phrases
+	}
+	columns := columnSpans(gridded)
+
+	cells := make([][]TableCell, len(lines))
+	for i, line := range lines {
+		words := make([][]TextBox, len(columns))
+		for _, phrase := range line.boxes {
+			c := columnOf(columns, phrase.Box)
+			words[c] = append(words[c], phrase)
+		}
+
+		cells[i] = make([]TableCell, len(columns))
+		for c, column := range columns {
+			cell := TableCell{Box: BoundingBox{
+				X:      column.start,
+				Y:      line.top,
+				Width:  column.end - column.start,
+				Height: line.bottom - line.top,
+			}}
+			if len(words[c]) > 0 {
+				merged := mergePhrase(words[c])
+				cell.Text, cell.Confidence = merged.Text, merged.Confidence
+			}
+			cells[i][c] = cell
+		}
+	}
+	return Table{Cells: cells}
+}
+
+// Texts returns the text of every cell, row by row
+func (t Table) Texts() [][]string {
+	texts := make([][]string, len(t.Cells))
+	for i, row := range t.Cells {
+		texts[i] = make([]string, len(row))
+		for j, cell := range row {
+			texts[i][j] = cell.Text
+		}
+	}
+	return texts
+}
+
+// columnSpans merges the horizontal extents of boxes into the disjoint
+// bands they cover, ordered left to right
+func columnSpans(boxes []TextBox) []span {
+	extents := make([]span, len(boxes))
+	for i, box := range boxes {
+		extents[i] = span{box.Box.X, box.Box.X + box.Box.Width}
+	}
+	sort.Slice(extents, func(i, j int) bool {
+		return extents[i].start < extents[j].start
+	})
+
+	spans := []span{extents[0]}
+	for _, extent := range extents[1:] {
+		last := &spans[len(spans)-1]
+		if extent.start <= last.end {
+			last.end = max(last.end, extent.end)
+			continue
+		}
+		spans = append(spans, extent)
+	}
+	return spans
+}
+
+// columnOf returns the index of the column overlapping box the most, or
+// of the nearest column when it overlaps none
+func columnOf(columns []span, box BoundingBox) int {
+	best, bestOverlap, bestDistance := 0, 0, -1
+	for i, column := range columns {
+		overlap := min(column.end, box.X+box.Width) - max(column.start, box.X)
+		distance := max(column.start-(box.X+box.Width), box.X-column.end)
+		switch {
+		case overlap > bestOverlap:
+			best, bestOverlap = i, overlap
+		case bestOverlap == 0 && (bestDistance < 0 || distance < bestDistance):
+			best, bestDistance = i, distance
+		}
+	}
+	return best
+}
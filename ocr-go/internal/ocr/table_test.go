@@ -0,0 +1,38 @@
+package ocr
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDetectTable(t *testing.T) {
+	boxes := []TextBox{
+		word("Quarterly", 0, 0), word("Report", 45, 0),
+		word("Item", 0, 20), word("Unit", 150, 20), word("Price", 195, 20), word("Qty", 300, 20),
+		word("Apples", 0, 40), word("1.20", 150, 41), word("3", 300, 40),
+		word("Pears", 0, 60), word("2", 300, 60),
+	}
+
+	table := DetectTable(boxes)
+	want := [][]string{
+		{"Quarterly Report", "", ""},
+		{"Item", "Unit Price", "Qty"},
+		{"Apples", "1.20", "3"},
+		{"Pears", "", "2"},
+	}
+	if got := table.Texts(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Texts() = %q, want %q", got, want)
+	}
+
+	// Empty cells still get the bounds of their row and column
+	empty := table.Cells[3][1].Box
+	if want := (BoundingBox{X: 150, Y: 60, Width: 85, Height: 10}); empty != want {
+		t.Errorf("empty cell box = %+v, want %+v", empty, want)
+	}
+}
+
+func TestDetectTableEmpty(t *testing.T) {
+	if got := DetectTable(nil); got.Cells != nil {
+		t.Errorf("DetectTable(nil) = %v, want no cells", got.Cells)
+	}
+}
@@ -4,45 +4,179 @@ import (
 	"context"
 	"fmt"
 	"image"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/otiai10/gosseract/v2"
 )
 
-// TesseractEngine implements Engine using Tesseract OCR
+// tesseractClient is the subset of gosseract.Client used by the engine
+type tesseractClient interface {
+	SetLanguage(langs ...string) error
+	SetPageSegMode(mode gosseract.PageSegMode) error
+	SetVariable(key gosseract.SettableVariable, value string) error
+	SetImageFromImage(img image.Image) error
+	SetImageFromBytes(data []byte) error
+	Text() (string, error)
+	GetMeanConfidence() (int, error)
+	GetBoundingBoxes(level gosseract.PageIteratorLevel) ([]gosseract.BoundingBox, error)
+	HOCRText() (string, error)
+	Version() string
+	Close() error
+}
+
+// TesseractEngine implements Engine using Tesseract OCR. The underlying
+// client is not safe for concurrent use, so calls are serialized.
 type TesseractEngine struct {
-	client *gosseract.Client
+	mu     sync.Mutex
+	client tesseractClient
 	lang   string
+	retry  RetryPolicy
+
+	// active is the language currently loaded in the client
+	active string
+
+	// installed lists the languages with traineddata, checked before a
+	// language is loaded since the client only fails once it recognizes
+	installed func() ([]string, error)
+}
+
+// MissingLanguageError reports configured languages whose traineddata is
+// not installed
+type MissingLanguageError struct {
+	Missing   []string
+	Installed []string
+}
+
+func (e *MissingLanguageError) Error() string {
+	installed := strings.Join(e.Installed, ", ")
+	if installed == "" {
+		installed = "none"
+	}
+	return fmt.Sprintf("tesseract language data not installed for %s (installed: %s)",
+		strings.Join(e.Missing, "+"), installed)
+}
+
+// checkLanguages verifies that every language of lang, such as "eng+spa",
+// is one of installed. Tesseract only loads the data on first use, so
+// without this a missing language fails the first OCR call instead of
+// startup.
+func checkLanguages(lang string, installed []string) error {
+	var missing []string
+	for _, l := range strings.Split(lang, "+") {
+		if !slices.Contains(installed, l) {
+			missing = append(missing, l)
+		}
+	}
+	if len(missing) > 0 {
+		slices.Sort(installed)
+		return &MissingLanguageError{Missing: missing, Installed: installed}
+	}
+	return nil
+}
+
+// TessdataLanguages lists the languages with traineddata in dir. It fails
+// if dir does not exist or holds no .traineddata files.
+func TessdataLanguages(dir string) ([]string, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("tessdata directory: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("tessdata directory %s is not a directory", dir)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.traineddata"))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("tessdata directory %s has no .traineddata files", dir)
+	}
+
+	langs := make([]string, len(files))
+	for i, file := range files {
+		langs[i] = strings.TrimSuffix(filepath.Base(file), ".traineddata")
+	}
+	return langs, nil
 }
 
-// NewTesseractEngine creates a new Tesseract OCR engine
-func NewTesseractEngine(lang string) (*TesseractEngine, error) {
+// NewTesseractEngine creates a new Tesseract OCR engine loading its
+// traineddata from tessdataDir, or from Tesseract's default location when
+// empty. It fails with a *MissingLanguageError if lang is not installed.
+func NewTesseractEngine(lang, tessdataDir string) (*TesseractEngine, error) {
+	listInstalled := gosseract.GetAvailableLanguages
+	if tessdataDir != "" {
+		listInstalled = func() ([]string, error) { return TessdataLanguages(tessdataDir) }
+	}
+	installed, err := listInstalled()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list installed languages: %w", err)
+	}
+	if err := checkLanguages(lang, installed); err != nil {
+		return nil, err
+	}
+
 	client := gosseract.NewClient()
+	if tessdataDir != "" {
+		if err := client.SetTessdataPrefix(tessdataDir); err != nil {
+			client.Close()
+			return nil, fmt.Errorf("failed to set tessdata directory: %w", err)
+		}
+	}
 	if err := client.SetLanguage(lang); err != nil {
+		client.Close()
 		return nil, fmt.Errorf("failed to set language: %w", err)
 	}
 
 	return &TesseractEngine{
-		client: client,
-		lang:   lang,
+		client:    client,
+		lang:      lang,
+		retry:     DefaultRetryPolicy,
+		active:    lang,
+		installed: listInstalled,
 	}, nil
 }
 
+// SetRetryPolicy configures retries for transient Tesseract failures
+func (e *TesseractEngine) SetRetryPolicy(policy RetryPolicy) {
+	e.retry = policy
+}
+
 // ExtractText extracts text from image
 func (e *TesseractEngine) ExtractText(ctx context.Context, img image.Image) (*Result, error) {
-	select {
-	case <-ctx.Done():
-		return nil, ctx.Err()
-	default:
+	return runCancelable(ctx, func() (*Result, error) {
+		return withRetry(ctx, e.retry, func() (*Result, error) {
+			return e.extractText(ctx, img)
+		})
+	})
+}
+
+// extractText runs ExtractText while holding the client lock
+func (e *TesseractEngine) extractText(ctx context.Context, img image.Image) (*Result, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	// The request may have been canceled while waiting for the lock
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := e.useLanguage(e.lang); err != nil {
+		return nil, fmt.Errorf("failed to set language: %w", err)
 	}
 
 	if err := e.client.SetImageFromImage(img); err != nil {
-		return nil, fmt.Errorf("failed to set image: %w", err)
+		return nil, classify(fmt.Errorf("failed to set image: %w", err))
 	}
 
 	text, err := e.client.Text()
 	if err != nil {
-		return nil, fmt.Errorf("failed to extract text: %w", err)
+		return nil, classify(fmt.Errorf("failed to extract text: %w", err))
 	}
 
 	confidence, err := e.client.GetMeanConfidence()
@@ -58,24 +192,138 @@ func (e *TesseractEngine) ExtractText(ctx context.Context, img image.Image) (*Re
 
 // ExtractTextWithBoxes extracts text with bounding boxes
 func (e *TesseractEngine) ExtractTextWithBoxes(ctx context.Context, img image.Image) (*DetailedResult, error) {
-	select {
-	case <-ctx.Done():
-		return nil, ctx.Err()
-	default:
+	return e.ExtractWithOptions(ctx, img, Options{})
+}
+
+// ExtractWithOptions extracts text with bounding boxes, temporarily applying
+// the given options to the client and restoring the defaults afterwards
+func (e *TesseractEngine) ExtractWithOptions(ctx context.Context, img image.Image, opts Options) (*DetailedResult, error) {
+	return runCancelable(ctx, func() (*DetailedResult, error) {
+		return withRetry(ctx, e.retry, func() (*DetailedResult, error) {
+			return e.extractWithOptions(ctx, img, opts)
+		})
+	})
+}
+
+// extractWithOptions runs ExtractWithOptions while holding the client lock
+func (e *TesseractEngine) extractWithOptions(ctx context.Context, img image.Image, opts Options) (*DetailedResult, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	// The request may have been canceled while waiting for the lock
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// A per-call language is swapped in while the lock is held and the
+	// default restored afterwards. A failed restore is retried by the next
+	// call, which always checks the loaded language first.
+	lang := e.lang
+	if opts.Language != "" {
+		lang = opts.Language
+	}
+	if err := e.useLanguage(lang); err != nil {
+		return nil, fmt.Errorf("failed to set language: %w", err)
+	}
+	if lang != e.lang {
+		defer e.useLanguage(e.lang)
+	}
+
+	if opts.PageSegMode != 0 {
+		if err := e.client.SetPageSegMode(gosseract.PageSegMode(opts.PageSegMode)); err != nil {
+			return nil, fmt.Errorf("failed to set page segmentation mode: %w", err)
+		}
+		defer e.client.SetPageSegMode(gosseract.PSM_AUTO)
+	}
+
+	// Character filters must be cleared afterwards so they don't leak into
+	// the next call on this client
+	if opts.Whitelist != "" {
+		if err := e.client.SetVariable("tessedit_char_whitelist", opts.Whitelist); err != nil {
+			return nil, fmt.Errorf("failed to set whitelist: %w", err)
+		}
+		defer e.client.SetVariable("tessedit_char_whitelist", "")
+	}
+	if opts.Blacklist != "" {
+		if err := e.client.SetVariable("tessedit_char_blacklist", opts.Blacklist); err != nil {
+			return nil, fmt.Errorf("failed to set blacklist: %w", err)
+		}
+		defer e.client.SetVariable("tessedit_char_blacklist", "")
+	}
+
+	if opts.DPI != 0 {
+		if err := e.client.SetVariable("user_defined_dpi", strconv.Itoa(opts.DPI)); err != nil {
+			return nil, fmt.Errorf("failed to set dpi: %w", err)
+		}
+		defer e.client.SetVariable("user_defined_dpi", variableDefaults["user_defined_dpi"])
+	}
+
+	if err := ValidateVariables(opts.Variables); err != nil {
+		return nil, err
+	}
+	for name, value := range opts.Variables {
+		if err := e.client.SetVariable(gosseract.SettableVariable(name), value); err != nil {
+			return nil, fmt.Errorf("failed to set variable %s: %w", name, err)
+		}
+		defer e.client.SetVariable(gosseract.SettableVariable(name), variableDefaults[name])
 	}
 
 	if err := e.client.SetImageFromImage(img); err != nil {
+		return nil, classify(fmt.Errorf("failed to set image: %w", err))
+	}
+	result, err := e.recognizeWords(lang)
+	if err != nil || !opts.Polygons {
+		return result, err
+	}
+
+	// Word boxes carry no baseline, the hOCR lines do. Polygons are an
+	// extra, so a failure leaves the boxes without them.
+	if hocr, err := e.client.HOCRText(); err == nil {
+		addPolygons(result.Boxes, parseHOCRLines(hocr))
+	}
+	return result, nil
+}
+
+// ExtractTextFromBytes implements BytesExtractor. The encoded image is
+// handed to Tesseract as is and decoded by Leptonica, skipping the Go
+// decode and the re-encode that SetImageFromImage needs.
+func (e *TesseractEngine) ExtractTextFromBytes(ctx context.Context, data []byte) (*DetailedResult, error) {
+	return runCancelable(ctx, func() (*DetailedResult, error) {
+		return withRetry(ctx, e.retry, func() (*DetailedResult, error) {
+			return e.extractFromBytes(ctx, data)
+		})
+	})
+}
+
+// extractFromBytes runs ExtractTextFromBytes while holding the client lock
+func (e *TesseractEngine) extractFromBytes(ctx context.Context, data []byte) (*DetailedResult, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	// The request may have been canceled while waiting for the lock
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := e.useLanguage(e.lang); err != nil {
+		return nil, fmt.Errorf("failed to set language: %w", err)
+	}
+	if err := e.client.SetImageFromBytes(data); err != nil {
 		return nil, fmt.Errorf("failed to set image: %w", err)
 	}
+	return e.recognizeWords(e.lang)
+}
 
+// recognizeWords recognizes the image set on the client and collects its
+// words. The caller must hold the client lock.
+func (e *TesseractEngine) recognizeWords(lang string) (*DetailedResult, error) {
 	// Get bounding boxes at word level
 	boxes, err := e.client.GetBoundingBoxes(gosseract.RIL_WORD)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get bounding boxes: %w", err)
+		return nil, classify(fmt.Errorf("failed to get bounding boxes: %w", err))
 	}
 
 	var textBoxes []TextBox
-	var fullTextParts []string
 
 	for _, box := range boxes {
 		word := strings.TrimSpace(box.Word)
@@ -93,18 +341,65 @@ func (e *TesseractEngine) ExtractTextWithBoxes(ctx context.Context, img image.Im
 				Height: box.Box.Max.Y - box.Box.Min.Y,
 			},
 		})
-
-		fullTextParts = append(fullTextParts, word)
 	}
 
 	return &DetailedResult{
-		FullText:   strings.Join(fullTextParts, " "),
-		Boxes:      textBoxes,
-		TotalLines: len(textBoxes),
-		Language:   e.lang,
+		FullText:       Layout(textBoxes),
+		Boxes:          textBoxes,
+		TotalLines:     len(textBoxes),
+		Language:       lang,
+		MeanConfidence: MeanConfidence(textBoxes),
+
+		ConfidenceHistogram: ConfidenceHistogram(textBoxes),
 	}, nil
 }
 
+// useLanguage loads lang into the client unless it is already loaded. A
+// language without traineddata fails with a *MissingLanguageError. The
+// installed languages are listed on every switch, since models may be
+// added at runtime. The caller must hold the client lock.
+func (e *TesseractEngine) useLanguage(lang string) error {
+	if lang == e.active {
+		return nil
+	}
+	if e.installed != nil {
+		installed, err := e.installed()
+		if err != nil {
+			return fmt.Errorf("failed to list installed languages: %w", err)
+		}
+		if err := checkLanguages(lang, installed); err != nil {
+			return err
+		}
+	}
+	if err := e.client.SetLanguage(lang); err != nil {
+		return err
+	}
+	e.active = lang
+	return nil
+}
+
+// ReloadLanguage implements LanguageReloader. If lang is part of the
+// loaded languages, the client is set up again on the next call, which
+// makes Tesseract read the traineddata anew.
+func (e *TesseractEngine) ReloadLanguage(lang string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if slices.Contains(strings.Split(e.active, "+"), lang) {
+		e.active = ""
+	}
+}
+
+// Language returns the configured recognition language
+func (e *TesseractEngine) Language() string {
+	return e.lang
+}
+
+// Version returns the version of the Tesseract library used by the client
+func (e *TesseractEngine) Version() string {
+	return e.client.Version()
+}
+
 // Close releases resources
 func (e *TesseractEngine) Close() error {
 	return e.client.Close()
@@ -0,0 +1,117 @@
+//go:build tesseract
+
+package ocr
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"testing"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// BenchmarkTesseractExtract measures real Tesseract throughput. It needs
+// Tesseract with English traineddata installed:
+//
+//	go test -tags tesseract -bench Tesseract ./internal/ocr/
+func BenchmarkTesseractExtract(b *testing.B) {
+	engine, err := NewTesseractEngine("eng", "")
+	if err != nil {
+		b.Skipf("tesseract unavailable: %v", err)
+	}
+	defer engine.Close()
+
+	img := image.NewRGBA(image.Rect(0, 0, 600, 200))
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	d := &font.Drawer{
+		Dst:  img,
+		Src:  image.NewUniform(color.Black),
+		Face: basicfont.Face7x13,
+	}
+	for i, line := range []string{"Invoice 2024-0117", "Total due 1,234.56", "Thank you for your business"} {
+		d.Dot = fixed.P(20, 40+i*40)
+		d.DrawString(line)
+	}
+
+	ctx := context.Background()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := engine.ExtractTextWithBoxes(ctx, img); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// largeJPEG encodes an A4 page at 300 DPI with a few lines of text
+func largeJPEG(b *testing.B) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, 2480, 3508))
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	d := &font.Drawer{
+		Dst:  img,
+		Src:  image.NewUniform(color.Black),
+		Face: basicfont.Face7x13,
+	}
+	for i := 0; i < 40; i++ {
+		d.Dot = fixed.P(200, 300+i*60)
+		d.DrawString("Invoice 2024-0117 Total due 1,234.56 Thank you for your business")
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 90}); err != nil {
+		b.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// BenchmarkTesseractJPEGDecoded decodes a large JPEG in Go and passes the
+// image to Tesseract, as /api/extract does by default
+func BenchmarkTesseractJPEGDecoded(b *testing.B) {
+	engine, err := NewTesseractEngine("eng", "")
+	if err != nil {
+		b.Skipf("tesseract unavailable: %v", err)
+	}
+	defer engine.Close()
+
+	data := largeJPEG(b)
+	ctx := context.Background()
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		img, err := jpeg.Decode(bytes.NewReader(data))
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err := engine.ExtractTextWithBoxes(ctx, img); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkTesseractJPEGBytes passes the same JPEG to Tesseract undecoded,
+// as /api/extract?raw=true does
+func BenchmarkTesseractJPEGBytes(b *testing.B) {
+	engine, err := NewTesseractEngine("eng", "")
+	if err != nil {
+		b.Skipf("tesseract unavailable: %v", err)
+	}
+	defer engine.Close()
+
+	data := largeJPEG(b)
+	ctx := context.Background()
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := engine.ExtractTextFromBytes(ctx, data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
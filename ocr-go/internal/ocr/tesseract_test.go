@@ -0,0 +1,323 @@
+package ocr
+
+import (
+	"context"
+	"errors"
+	"image"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/otiai10/gosseract/v2"
+)
+
+// flakyClient fails SetImageFromImage a fixed number of times
+type flakyClient struct {
+	failures int
+	setCalls int
+	imageErr error
+	vars     map[string]string
+	langs    []string
+	langErr  error
+	bytes    []byte
+
+	hocr      string
+	hocrCalls int
+}
+
+func (c *flakyClient) SetLanguage(langs ...string) error {
+	if c.langErr != nil {
+		return c.langErr
+	}
+	c.langs = append(c.langs, langs...)
+	return nil
+}
+
+func (c *flakyClient) SetPageSegMode(gosseract.PageSegMode) error { return nil }
+func (c *flakyClient) Text() (string, error)                      { return "hello", nil }
+func (c *flakyClient) GetMeanConfidence() (int, error)            { return 90, nil }
+func (c *flakyClient) Version() string                            { return "flaky" }
+func (c *flakyClient) Close() error                               { return nil }
+
+func (c *flakyClient) SetVariable(key gosseract.SettableVariable, value string) error {
+	if c.vars == nil {
+		c.vars = make(map[string]string)
+	}
+	c.vars[string(key)] = value
+	return nil
+}
+
+func (c *flakyClient) SetImageFromImage(image.Image) error {
+	c.setCalls++
+	if c.setCalls <= c.failures {
+		return c.imageErr
+	}
+	return nil
+}
+
+func (c *flakyClient) HOCRText() (string, error) {
+	c.hocrCalls++
+	return c.hocr, nil
+}
+
+func (c *flakyClient) SetImageFromBytes(data []byte) error {
+	c.bytes = data
+	return nil
+}
+
+func (c *flakyClient) GetBoundingBoxes(gosseract.PageIteratorLevel) ([]gosseract.BoundingBox, error) {
+	return []gosseract.BoundingBox{
+		{Box: image.Rect(0, 0, 10, 5), Word: "hello", Confidence: 90},
+	}, nil
+}
+
+// newFlakyEngine returns an engine whose client fails the first n images
+func newFlakyEngine(n int) (*TesseractEngine, *flakyClient) {
+	client := &flakyClient{failures: n, imageErr: errors.New("PixImage is not set, use SetImage or SetImageFromBytes before Text or HOCRText")}
+	engine := &TesseractEngine{
+		client: client,
+		lang:   "eng",
+		retry:  RetryPolicy{Attempts: 3, Backoff: time.Millisecond},
+		active: "eng",
+	}
+	return engine, client
+}
+
+func TestExtractRetriesTransientFailures(t *testing.T) {
+	engine, client := newFlakyEngine(2)
+
+	result, err := engine.ExtractTextWithBoxes(context.Background(), image.NewGray(image.Rect(0, 0, 1, 1)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.FullText != "hello" {
+		t.Errorf("full text = %q, want %q", result.FullText, "hello")
+	}
+	if client.setCalls != 3 {
+		t.Errorf("SetImageFromImage called %d times, want 3", client.setCalls)
+	}
+}
+
+func TestExtractGivesUpAfterAttempts(t *testing.T) {
+	engine, client := newFlakyEngine(5)
+
+	_, err := engine.ExtractText(context.Background(), image.NewGray(image.Rect(0, 0, 1, 1)))
+	if err == nil {
+		t.Fatal("expected an error after exhausting retries")
+	}
+	if client.setCalls != 3 {
+		t.Errorf("SetImageFromImage called %d times, want 3", client.setCalls)
+	}
+}
+
+func TestExtractSkipsPermanentFailures(t *testing.T) {
+	engine, client := newFlakyEngine(5)
+	client.imageErr = errors.New("image data cannot be empty")
+
+	_, err := engine.ExtractTextWithBoxes(context.Background(), image.NewGray(image.Rect(0, 0, 1, 1)))
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if client.setCalls != 1 {
+		t.Errorf("SetImageFromImage called %d times, want 1", client.setCalls)
+	}
+}
+
+func TestWithRetrySkipsPermanentErrors(t *testing.T) {
+	calls := 0
+	permanent := errors.New("unsupported language")
+
+	_, err := withRetry(context.Background(), RetryPolicy{Attempts: 3}, func() (int, error) {
+		calls++
+		return 0, permanent
+	})
+
+	if !errors.Is(err, permanent) || calls != 1 {
+		t.Fatalf("err = %v after %d calls, want permanent error after 1 call", err, calls)
+	}
+}
+
+func TestWithRetrySkipsContextErrors(t *testing.T) {
+	calls := 0
+
+	_, err := withRetry(context.Background(), RetryPolicy{Attempts: 3}, func() (int, error) {
+		calls++
+		return 0, transient(context.Canceled)
+	})
+
+	if !errors.Is(err, context.Canceled) || calls != 1 {
+		t.Fatalf("err = %v after %d calls, want context.Canceled after 1 call", err, calls)
+	}
+}
+
+func TestExtractResetsVariables(t *testing.T) {
+	engine, client := newFlakyEngine(0)
+
+	_, err := engine.ExtractWithOptions(context.Background(), image.NewGray(image.Rect(0, 0, 1, 1)), Options{
+		Whitelist: "0123456789",
+		Variables: map[string]string{"preserve_interword_spaces": "1"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := client.vars["tessedit_char_whitelist"]; got != "" {
+		t.Errorf("whitelist = %q after extraction, want it cleared", got)
+	}
+	if got := client.vars["preserve_interword_spaces"]; got != "0" {
+		t.Errorf("preserve_interword_spaces = %q after extraction, want default %q", got, "0")
+	}
+}
+
+func TestExtractRejectsUnknownVariables(t *testing.T) {
+	engine, client := newFlakyEngine(0)
+
+	_, err := engine.ExtractWithOptions(context.Background(), image.NewGray(image.Rect(0, 0, 1, 1)), Options{
+		Variables: map[string]string{"tessedit_write_images": "1"},
+	})
+	if err == nil {
+		t.Fatal("expected an error for a variable outside the allowlist")
+	}
+	if client.setCalls != 0 {
+		t.Errorf("SetImageFromImage called %d times, want 0", client.setCalls)
+	}
+}
+
+func TestExtractPerCallLanguage(t *testing.T) {
+	engine, client := newFlakyEngine(0)
+	img := image.NewGray(image.Rect(0, 0, 1, 1))
+
+	result, err := engine.ExtractWithOptions(context.Background(), img, Options{Language: "spa"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Language != "spa" {
+		t.Errorf("language = %q, want %q", result.Language, "spa")
+	}
+	if _, err := engine.ExtractTextWithBoxes(context.Background(), img); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []string{"spa", "eng"}
+	if len(client.langs) != len(want) || client.langs[0] != want[0] || client.langs[1] != want[1] {
+		t.Errorf("languages set = %v, want %v", client.langs, want)
+	}
+}
+
+func TestExtractMissingLanguage(t *testing.T) {
+	engine, client := newFlakyEngine(0)
+	engine.installed = func() ([]string, error) { return []string{"eng", "spa"}, nil }
+	img := image.NewGray(image.Rect(0, 0, 1, 1))
+
+	_, err := engine.ExtractWithOptions(context.Background(), img, Options{Language: "spa+deu"})
+	var missing *MissingLanguageError
+	if !errors.As(err, &missing) || !slices.Equal(missing.Missing, []string{"deu"}) {
+		t.Fatalf("error = %v, want deu missing", err)
+	}
+	if len(client.langs) != 0 || client.setCalls != 0 {
+		t.Errorf("languages set = %v, images set = %d; want the call stopped first", client.langs, client.setCalls)
+	}
+}
+
+func TestExtractRetriesFailedLanguageRestore(t *testing.T) {
+	engine, client := newFlakyEngine(0)
+	img := image.NewGray(image.Rect(0, 0, 1, 1))
+
+	// Loading spa succeeds but restoring eng afterwards fails
+	engine.active = "spa"
+	client.langErr = errors.New("restore failed")
+	if _, err := engine.ExtractText(context.Background(), img); err == nil {
+		t.Fatal("expected an error while the default language cannot be loaded")
+	}
+
+	client.langErr = nil
+	if _, err := engine.ExtractText(context.Background(), img); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if engine.active != "eng" {
+		t.Errorf("active language = %q, want %q", engine.active, "eng")
+	}
+}
+
+func TestReloadLanguage(t *testing.T) {
+	engine, client := newFlakyEngine(0)
+	img := image.NewGray(image.Rect(0, 0, 1, 1))
+
+	// Other languages leave the loaded data alone
+	engine.ReloadLanguage("deu")
+	if _, err := engine.ExtractText(context.Background(), img); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(client.langs) != 0 {
+		t.Errorf("languages set = %v, want none", client.langs)
+	}
+
+	engine.ReloadLanguage("eng")
+	if _, err := engine.ExtractText(context.Background(), img); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(client.langs) != 1 || client.langs[0] != "eng" {
+		t.Errorf("languages set = %v, want [eng]", client.langs)
+	}
+}
+
+func TestExtractTextFromBytes(t *testing.T) {
+	engine, client := newFlakyEngine(0)
+	data := []byte("\x89PNG encoded image")
+
+	result, err := engine.ExtractTextFromBytes(context.Background(), data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(client.bytes) != string(data) {
+		t.Errorf("client got %q, want the bytes as sent", client.bytes)
+	}
+	if client.setCalls != 0 {
+		t.Errorf("SetImageFromImage called %d times, want 0", client.setCalls)
+	}
+	if result.FullText != "hello" || len(result.Boxes) != 1 {
+		t.Errorf("result = %+v, want one word hello", result)
+	}
+}
+
+func TestCheckLanguages(t *testing.T) {
+	installed := []string{"osd", "eng", "deu"}
+	if err := checkLanguages("eng+deu", installed); err != nil {
+		t.Errorf("installed languages: %v", err)
+	}
+
+	err := checkLanguages("eng+spa+fra", installed)
+	var missing *MissingLanguageError
+	if !errors.As(err, &missing) {
+		t.Fatalf("err = %v, want *MissingLanguageError", err)
+	}
+	want := "tesseract language data not installed for spa+fra (installed: deu, eng, osd)"
+	if err.Error() != want {
+		t.Errorf("err = %q, want %q", err, want)
+	}
+}
+
+func TestTessdataLanguages(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := TessdataLanguages(dir); err == nil {
+		t.Error("empty directory should be rejected")
+	}
+	if _, err := TessdataLanguages(filepath.Join(dir, "missing")); err == nil {
+		t.Error("missing directory should be rejected")
+	}
+
+	for _, name := range []string{"eng.traineddata", "spa.traineddata", "README"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	langs, err := TessdataLanguages(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"eng", "spa"}; !slices.Equal(langs, want) {
+		t.Errorf("languages = %v, want %v", langs, want)
+	}
+}
@@ -0,0 +1,52 @@
+package ocr
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// maxTessdataEntries bounds the component count in a traineddata header.
+// Tesseract 4 and 5 write 24 entries; older versions fewer.
+const maxTessdataEntries = 64
+
+// ValidateTraineddata checks that r, of the given size, looks like a
+// Tesseract traineddata file: a little-endian int32 entry count followed
+// by one int64 offset per component, each either -1 for an absent
+// component or pointing, in increasing order, into the file.
+func ValidateTraineddata(r io.ReaderAt, size int64) error {
+	var count int32
+	if err := binary.Read(io.NewSectionReader(r, 0, 4), binary.LittleEndian, &count); err != nil {
+		return errors.New("not a traineddata file: too short")
+	}
+	if count < 1 || count > maxTessdataEntries {
+		return fmt.Errorf("not a traineddata file: bad entry count %d", count)
+	}
+
+	header := int64(4 + 8*count)
+	if size <= header {
+		return errors.New("not a traineddata file: too short")
+	}
+	offsets := make([]int64, count)
+	if err := binary.Read(io.NewSectionReader(r, 4, 8*int64(count)), binary.LittleEndian, offsets); err != nil {
+		return errors.New("not a traineddata file: truncated header")
+	}
+
+	present := 0
+	last := header - 1
+	for i, offset := range offsets {
+		if offset == -1 {
+			continue
+		}
+		if offset <= last || offset >= size {
+			return fmt.Errorf("not a traineddata file: bad offset for component %d", i)
+		}
+		last = offset
+		present++
+	}
+	if present == 0 {
+		return errors.New("not a traineddata file: no components")
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package ocr
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// traineddata builds a file with the given component offsets followed by
+// size bytes of data
+func traineddata(offsets []int64, size int) []byte {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, int32(len(offsets)))
+	binary.Write(&buf, binary.LittleEndian, offsets)
+	buf.Write(make([]byte, size))
+	return buf.Bytes()
+}
+
+func TestValidateTraineddata(t *testing.T) {
+	// Header of 3 entries is 4 + 24 = 28 bytes
+	tests := []struct {
+		name  string
+		data  []byte
+		valid bool
+	}{
+		{"valid", traineddata([]int64{28, -1, 40}, 20), true},
+		{"empty", nil, false},
+		{"text file", []byte("this is not a model at all"), false},
+		{"no components", traineddata([]int64{-1, -1}, 10), false},
+		{"offset in header", traineddata([]int64{8, 40}, 20), false},
+		{"offset past end", traineddata([]int64{28, 400}, 20), false},
+		{"decreasing offsets", traineddata([]int64{40, 28}, 20), false},
+		{"header only", traineddata([]int64{28}, 0), false},
+	}
+	for _, tt := range tests {
+		err := ValidateTraineddata(bytes.NewReader(tt.data), int64(len(tt.data)))
+		if (err == nil) != tt.valid {
+			t.Errorf("%s: err = %v, want valid %v", tt.name, err, tt.valid)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package ocr
+
+import (
+	"fmt"
+	"sort"
+)
+
+// variableDefaults lists the Tesseract variables callers may set through
+// Options.Variables, together with the value restored after each call
+var variableDefaults = map[string]string{
+	"preserve_interword_spaces": "0",
+	"tessedit_char_whitelist":   "",
+	"tessedit_char_blacklist":   "",
+	"tessedit_do_invert":        "1",
+	"user_defined_dpi":          "0",
+	"classify_bln_numeric_mode": "0",
+}
+
+// AllowedVariables returns the sorted names of the settable variables
+func AllowedVariables() []string {
+	names := make([]string, 0, len(variableDefaults))
+	for name := range variableDefaults {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ValidateVariables returns an error if any variable is not allowed
+func ValidateVariables(vars map[string]string) error {
+	for name := range vars {
+		if _, ok := variableDefaults[name]; !ok {
+			return fmt.Errorf("variable %q is not allowed", name)
+		}
+	}
+	return nil
+}
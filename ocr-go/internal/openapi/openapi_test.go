@@ -0,0 +1,69 @@
+package openapi
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type inner struct {
+	Name string `json:"name"`
+}
+
+type embedded struct {
+	Rate float64 `json:"rate"`
+}
+
+type outer struct {
+	ID       string            `json:"id"`
+	Note     string            `json:"note,omitempty"`
+	Count    *int              `json:"count,omitempty"`
+	When     time.Time         `json:"when"`
+	Tags     []string          `json:"tags"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	Child    inner             `json:"child"`
+	Children []inner           `json:"children"`
+	Any      interface{}       `json:"any,omitempty"`
+	Skipped  string            `json:"-"`
+	hidden   string
+	embedded
+}
+
+func TestSchemaOf(t *testing.T) {
+	doc := New("test", "1")
+
+	ref := doc.SchemaOf(outer{})
+	if ref.Ref != "#/components/schemas/outer" {
+		t.Fatalf("ref = %q, want the outer component", ref.Ref)
+	}
+
+	s := doc.Components.Schemas["outer"]
+	want := map[string]Schema{
+		"id":       {Type: "string"},
+		"note":     {Type: "string"},
+		"count":    {Type: "integer", Format: "int32"},
+		"when":     {Type: "string", Format: "date-time"},
+		"tags":     {Type: "array", Items: &Schema{Type: "string"}},
+		"labels":   {Type: "object", AdditionalProperties: &Schema{Type: "string"}},
+		"child":    {Ref: "#/components/schemas/inner"},
+		"children": {Type: "array", Items: &Schema{Ref: "#/components/schemas/inner"}},
+		"any":      {},
+		"rate":     {Type: "number", Format: "double"},
+	}
+	if len(s.Properties) != len(want) {
+		t.Errorf("properties = %d, want %d", len(s.Properties), len(want))
+	}
+	for name, w := range want {
+		if got := s.Properties[name]; got == nil || !reflect.DeepEqual(*got, w) {
+			t.Errorf("property %s = %+v, want %+v", name, got, w)
+		}
+	}
+
+	wantRequired := []string{"id", "when", "tags", "child", "children", "rate"}
+	if !reflect.DeepEqual(s.Required, wantRequired) {
+		t.Errorf("required = %v, want %v", s.Required, wantRequired)
+	}
+	if _, ok := doc.Components.Schemas["inner"]; !ok {
+		t.Error("inner schema not registered")
+	}
+}
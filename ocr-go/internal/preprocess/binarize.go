@@ -0,0 +1,58 @@
+package preprocess
+
+import "image"
+
+// Binarize converts img to black and white using Otsu's threshold
+func Binarize(img image.Image) *image.Gray {
+	gray := toGray(img)
+	threshold := otsuThreshold(gray)
+
+	for i, v := range gray.Pix {
+		if v > threshold {
+			gray.Pix[i] = 255
+		} else {
+			gray.Pix[i] = 0
+		}
+	}
+	return gray
+}
+
+// otsuThreshold returns the intensity that best separates the histogram of
+// gray into foreground and background
+func otsuThreshold(gray *image.Gray) uint8 {
+	var hist [256]int
+	for _, v := range gray.Pix {
+		hist[v]++
+	}
+
+	total := len(gray.Pix)
+	sum := 0
+	for v, n := range hist {
+		sum += v * n
+	}
+
+	var best uint8
+	var bestVariance float64
+	sumBackground, weightBackground := 0, 0
+	for v, n := range hist {
+		weightBackground += n
+		if weightBackground == 0 {
+			continue
+		}
+		weightForeground := total - weightBackground
+		if weightForeground == 0 {
+			break
+		}
+		sumBackground += v * n
+
+		meanBackground := float64(sumBackground) / float64(weightBackground)
+		meanForeground := float64(sum-sumBackground) / float64(weightForeground)
+		diff := meanBackground - meanForeground
+		variance := float64(weightBackground) * float64(weightForeground) * diff * diff
+		if variance > bestVariance {
+			bestVariance = variance
+			best = uint8(v)
+		}
+	}
+	return best
+}
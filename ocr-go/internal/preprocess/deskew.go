@@ -0,0 +1,95 @@
+package preprocess
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/math/f64"
+)
+
+// Deskew search parameters
+const (
+	maxSkewDegrees  = 10.0
+	skewStepDegrees = 0.5
+
+	// maxSkewSamples bounds the number of ink pixels scored per angle
+	maxSkewSamples = 20000
+)
+
+// Deskew straightens text lines by rotating img by the angle at which the
+// horizontal projection of dark pixels is sharpest. The result is grayscale.
+func Deskew(img image.Image) *image.Gray {
+	gray := toGray(img)
+
+	angle := skewAngle(gray)
+	if math.Abs(angle) < skewStepDegrees/2 {
+		return gray
+	}
+	return rotate(gray, angle*math.Pi/180)
+}
+
+// skewAngle estimates the text skew of gray in degrees; positive values mean
+// lines slope downwards from left to right
+func skewAngle(gray *image.Gray) float64 {
+	threshold := otsuThreshold(gray)
+	bounds := gray.Bounds()
+
+	var ink []image.Point
+	for y := 0; y < bounds.Dy(); y++ {
+		row := gray.Pix[y*gray.Stride : y*gray.Stride+bounds.Dx()]
+		for x, v := range row {
+			if v <= threshold {
+				ink = append(ink, image.Point{X: x, Y: y})
+			}
+		}
+	}
+	if len(ink) == 0 {
+		return 0
+	}
+	stride := len(ink)/maxSkewSamples + 1
+
+	var bestAngle, bestScore float64
+	bins := make([]int, bounds.Dy()*3)
+	offset := bounds.Dy()
+	for angle := -maxSkewDegrees; angle <= maxSkewDegrees; angle += skewStepDegrees {
+		tan := math.Tan(angle * math.Pi / 180)
+		for i := range bins {
+			bins[i] = 0
+		}
+		for i := 0; i < len(ink); i += stride {
+			p := ink[i]
+			row := int(math.Round(float64(p.Y)-float64(p.X)*tan)) + offset
+			if row >= 0 && row < len(bins) {
+				bins[row]++
+			}
+		}
+
+		var score float64
+		for _, n := range bins {
+			score += float64(n) * float64(n)
+		}
+		if score > bestScore {
+			bestScore, bestAngle = score, angle
+		}
+	}
+	return bestAngle
+}
+
+// rotate turns gray by -radians around its centre, filling uncovered areas
+// with white
+func rotate(gray *image.Gray, radians float64) *image.Gray {
+	bounds := gray.Bounds()
+	dst := image.NewGray(bounds)
+	draw.Draw(dst, bounds, image.NewUniform(color.White), image.Point{}, draw.Src)
+
+	sin, cos := math.Sincos(radians)
+	cx, cy := float64(bounds.Dx())/2, float64(bounds.Dy())/2
+	m := f64.Aff3{
+		cos, sin, cx - cos*cx - sin*cy,
+		-sin, cos, cy + sin*cx - cos*cy,
+	}
+	draw.BiLinear.Transform(dst, m, gray, bounds, draw.Over, nil)
+	return dst
+}
@@ -0,0 +1,24 @@
+package preprocess
+
+import (
+	"image"
+	"image/draw"
+)
+
+// Mask returns a copy of img with every rectangle filled white, hiding
+// logos, stamps or watermarks that would otherwise be read as text.
+// Rectangles are relative to the top-left corner of img and clipped to
+// it. Without rectangles img is returned unchanged.
+func Mask(img image.Image, rects []image.Rectangle) image.Image {
+	if len(rects) == 0 {
+		return img
+	}
+
+	bounds := img.Bounds()
+	dst := image.NewRGBA(bounds)
+	draw.Draw(dst, bounds, img, bounds.Min, draw.Src)
+	for _, rect := range rects {
+		draw.Draw(dst, rect.Add(bounds.Min).Intersect(bounds), image.White, image.Point{}, draw.Src)
+	}
+	return dst
+}
@@ -0,0 +1,38 @@
+package preprocess
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestMask(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 4, 3))
+
+	masked := Mask(img, []image.Rectangle{image.Rect(1, 0, 3, 2), image.Rect(3, 2, 9, 9)})
+
+	// Row by row, 1 marks a masked pixel
+	want := [][]int{
+		{0, 1, 1, 0},
+		{0, 1, 1, 0},
+		{0, 0, 0, 1},
+	}
+	for y, row := range want {
+		for x, white := range row {
+			got := color.GrayModel.Convert(masked.At(x, y)).(color.Gray).Y
+			if (got == 255) != (white == 1) {
+				t.Errorf("pixel (%d, %d) = %d, want masked %v", x, y, got, white == 1)
+			}
+		}
+	}
+	if img.Pix[1] != 0 {
+		t.Error("Mask modified its input")
+	}
+}
+
+func TestMaskNone(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 2, 2))
+	if got := Mask(img, nil); got != image.Image(img) {
+		t.Error("Mask without rectangles should return the image unchanged")
+	}
+}
@@ -0,0 +1,48 @@
+package preprocess
+
+import (
+	"image"
+	"image/color"
+)
+
+// Normalize converts img to grayscale and stretches its intensities so the
+// darkest pixel becomes black and the brightest white. Uniform images are
+// returned as plain grayscale.
+func Normalize(img image.Image) *image.Gray {
+	gray := toGray(img)
+
+	lo, hi := uint8(255), uint8(0)
+	for _, v := range gray.Pix {
+		if v < lo {
+			lo = v
+		}
+		if v > hi {
+			hi = v
+		}
+	}
+	if hi <= lo || (lo == 0 && hi == 255) {
+		return gray
+	}
+
+	var lut [256]uint8
+	span := int(hi) - int(lo)
+	for v := int(lo); v <= int(hi); v++ {
+		lut[v] = uint8((v - int(lo)) * 255 / span)
+	}
+	for i, v := range gray.Pix {
+		gray.Pix[i] = lut[v]
+	}
+	return gray
+}
+
+// toGray returns a grayscale copy of img with bounds starting at the origin
+func toGray(img image.Image) *image.Gray {
+	bounds := img.Bounds()
+	gray := image.NewGray(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			gray.Set(x, y, color.GrayModel.Convert(img.At(bounds.Min.X+x, bounds.Min.Y+y)))
+		}
+	}
+	return gray
+}
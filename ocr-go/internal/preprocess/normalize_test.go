@@ -0,0 +1,45 @@
+package preprocess
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestNormalizeStretchesLowContrast(t *testing.T) {
+	// Horizontal gradient confined to 100-150, like a faint carbon copy
+	img := image.NewGray(image.Rect(0, 0, 51, 4))
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 51; x++ {
+			img.SetGray(x, y, color.Gray{Y: uint8(100 + x)})
+		}
+	}
+
+	out := Normalize(img)
+
+	lo, hi := uint8(255), uint8(0)
+	for _, v := range out.Pix {
+		lo = min(lo, v)
+		hi = max(hi, v)
+	}
+	if lo != 0 || hi != 255 {
+		t.Errorf("output spans %d-%d, want 0-255", lo, hi)
+	}
+	if out.GrayAt(0, 0).Y >= out.GrayAt(50, 0).Y {
+		t.Error("normalization did not preserve the gradient direction")
+	}
+}
+
+func TestNormalizeUniformImage(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 3, 3))
+	for i := range img.Pix {
+		img.Pix[i] = 128
+	}
+
+	out := Normalize(img)
+	for _, v := range out.Pix {
+		if v != 128 {
+			t.Fatalf("uniform pixel changed to %d, want 128", v)
+		}
+	}
+}
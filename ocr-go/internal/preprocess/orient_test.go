@@ -0,0 +1,47 @@
+package preprocess
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestOrient(t *testing.T) {
+	// A 3x2 image whose pixels are numbered row by row:
+	//   1 2 3
+	//   4 5 6
+	img := image.NewGray(image.Rect(0, 0, 3, 2))
+	for i := range img.Pix {
+		img.Pix[i] = uint8(i + 1)
+	}
+
+	tests := []struct {
+		orientation int
+		want        [][]uint8
+	}{
+		{1, [][]uint8{{1, 2, 3}, {4, 5, 6}}},
+		{2, [][]uint8{{3, 2, 1}, {6, 5, 4}}},
+		{3, [][]uint8{{6, 5, 4}, {3, 2, 1}}},
+		{4, [][]uint8{{4, 5, 6}, {1, 2, 3}}},
+		{5, [][]uint8{{1, 4}, {2, 5}, {3, 6}}},
+		{6, [][]uint8{{4, 1}, {5, 2}, {6, 3}}},
+		{7, [][]uint8{{6, 3}, {5, 2}, {4, 1}}},
+		{8, [][]uint8{{3, 6}, {2, 5}, {1, 4}}},
+	}
+
+	for _, tt := range tests {
+		got := Orient(img, tt.orientation)
+		size := got.Bounds().Size()
+		if size != image.Pt(len(tt.want[0]), len(tt.want)) {
+			t.Errorf("Orient(%d) size = %v, want %dx%d", tt.orientation, size, len(tt.want[0]), len(tt.want))
+			continue
+		}
+		for y, row := range tt.want {
+			for x, want := range row {
+				if v := color.GrayModel.Convert(got.At(x, y)).(color.Gray).Y; v != want {
+					t.Errorf("Orient(%d) at (%d,%d) = %d, want %d", tt.orientation, x, y, v, want)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package preprocess
+
+import (
+	"fmt"
+	"image"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Preprocessor transforms an image before OCR
+type Preprocessor interface {
+	Apply(img image.Image) image.Image
+}
+
+// Func adapts an ordinary function to the Preprocessor interface
+type Func func(img image.Image) image.Image
+
+// Apply calls f(img)
+func (f Func) Apply(img image.Image) image.Image {
+	return f(img)
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Preprocessor{
+		"grayscale": Func(func(img image.Image) image.Image { return toGray(img) }),
+		"normalize": Func(func(img image.Image) image.Image { return Normalize(img) }),
+		"binarize":  Func(func(img image.Image) image.Image { return Binarize(img) }),
+		"deskew":    Func(func(img image.Image) image.Image { return Deskew(img) }),
+	}
+)
+
+// Register makes a preprocessor available to Parse under name, replacing
+// any existing step with the same name
+func Register(name string, p Preprocessor) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[name] = p
+}
+
+// Names returns the sorted names of the registered steps
+func Names() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Pipeline applies preprocessors in order
+type Pipeline []Preprocessor
+
+// Apply runs every step on the output of the previous one
+func (p Pipeline) Apply(img image.Image) image.Image {
+	for _, step := range p {
+		img = step.Apply(img)
+	}
+	return img
+}
+
+// Parse builds a pipeline from a comma-separated list of step names such
+// as "grayscale,deskew,binarize". An empty spec yields an empty pipeline.
+func Parse(spec string) (Pipeline, error) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	var pipeline Pipeline
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		step, ok := registry[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown preprocess step %q", name)
+		}
+		pipeline = append(pipeline, step)
+	}
+	return pipeline, nil
+}
@@ -0,0 +1,87 @@
+package preprocess
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// recorder is a step that appends its name to a shared log
+type recorder struct {
+	name string
+	log  *[]string
+}
+
+func (r recorder) Apply(img image.Image) image.Image {
+	*r.log = append(*r.log, r.name)
+	return img
+}
+
+func TestParseAppliesStepsInOrder(t *testing.T) {
+	var log []string
+	Register("test-a", recorder{"a", &log})
+	Register("test-b", recorder{"b", &log})
+
+	pipeline, err := Parse("test-b, test-a,test-b")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pipeline.Apply(image.NewGray(image.Rect(0, 0, 1, 1)))
+
+	want := []string{"b", "a", "b"}
+	if len(log) != len(want) {
+		t.Fatalf("applied %v, want %v", log, want)
+	}
+	for i := range want {
+		if log[i] != want[i] {
+			t.Fatalf("applied %v, want %v", log, want)
+		}
+	}
+}
+
+func TestParseRejectsUnknownSteps(t *testing.T) {
+	if _, err := Parse("grayscale,sharpen"); err == nil {
+		t.Fatal("expected an error for an unknown step")
+	}
+}
+
+func TestParseEmpty(t *testing.T) {
+	pipeline, err := Parse("")
+	if err != nil || len(pipeline) != 0 {
+		t.Fatalf("Parse(\"\") = %v, %v; want empty pipeline", pipeline, err)
+	}
+}
+
+func TestBinarize(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 4, 1))
+	for x, v := range []uint8{30, 40, 200, 210} {
+		img.SetGray(x, 0, color.Gray{Y: v})
+	}
+
+	out := Binarize(img)
+
+	want := []uint8{0, 0, 255, 255}
+	for i, v := range out.Pix {
+		if v != want[i] {
+			t.Fatalf("pixels = %v, want %v", out.Pix, want)
+		}
+	}
+}
+
+func TestSkewAngle(t *testing.T) {
+	// Dark lines sloping down by 3 degrees on a white page
+	img := image.NewGray(image.Rect(0, 0, 400, 200))
+	for i := range img.Pix {
+		img.Pix[i] = 255
+	}
+	for _, start := range []int{40, 90, 140} {
+		for x := 20; x < 380; x++ {
+			y := start + int(float64(x)*0.0524)
+			img.SetGray(x, y, color.Gray{})
+		}
+	}
+
+	if angle := skewAngle(img); angle < 2.5 || angle > 3.5 {
+		t.Errorf("skew angle = %.1f, want about 3", angle)
+	}
+}
@@ -0,0 +1,29 @@
+package preprocess
+
+import (
+	"image"
+
+	"golang.org/x/image/draw"
+)
+
+// Thumbnail downscales img so its longer side is at most maxDim, keeping
+// the aspect ratio. Images that already fit are returned unchanged.
+func Thumbnail(img image.Image, maxDim int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if maxDim <= 0 || (width <= maxDim && height <= maxDim) {
+		return img
+	}
+
+	if width >= height {
+		height = max(1, height*maxDim/width)
+		width = maxDim
+	} else {
+		width = max(1, width*maxDim/height)
+		height = maxDim
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, bounds, draw.Src, nil)
+	return dst
+}
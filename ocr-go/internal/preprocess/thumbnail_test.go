@@ -0,0 +1,26 @@
+package preprocess
+
+import (
+	"image"
+	"testing"
+)
+
+func TestThumbnail(t *testing.T) {
+	tests := []struct {
+		width, height int
+		want          image.Point
+	}{
+		{1000, 500, image.Pt(200, 100)},
+		{300, 1200, image.Pt(50, 200)},
+		{5000, 10, image.Pt(200, 1)},
+		{120, 80, image.Pt(120, 80)},
+	}
+
+	for _, tt := range tests {
+		img := image.NewGray(image.Rect(0, 0, tt.width, tt.height))
+
+		if got := Thumbnail(img, 200).Bounds().Size(); got != tt.want {
+			t.Errorf("Thumbnail(%dx%d) = %v, want %v", tt.width, tt.height, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+// Package preprocess prepares images before they are passed to the OCR engine
+package preprocess
+
+import (
+	"image"
+
+	"golang.org/x/image/draw"
+)
+
+// Upscale limits
+const (
+	// MinDimension is the shorter side below which images are upscaled
+	MinDimension = 1000
+
+	// MaxScale caps the upscale factor to bound memory use
+	MaxScale = 4
+)
+
+// Upscale enlarges img by the smallest integer factor that brings its
+// shorter side to at least MinDimension, up to MaxScale and to the factor
+// that keeps the enlarged image within maxPixels, so long thin images are
+// not blown up past the pixel limit. It returns the image and the factor
+// applied; images that are large enough, or too large to enlarge, are
+// returned unchanged with a factor of 1.
+func Upscale(img image.Image, maxPixels int64) (image.Image, int) {
+	bounds := img.Bounds()
+	short := bounds.Dx()
+	if bounds.Dy() < short {
+		short = bounds.Dy()
+	}
+	if short <= 0 || short >= MinDimension {
+		return img, 1
+	}
+
+	scale := (MinDimension + short - 1) / short
+	if scale > MaxScale {
+		scale = MaxScale
+	}
+	area := int64(bounds.Dx()) * int64(bounds.Dy())
+	for scale > 1 && area*int64(scale*scale) > maxPixels {
+		scale--
+	}
+	if scale == 1 {
+		return img, 1
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, bounds.Dx()*scale, bounds.Dy()*scale))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, bounds, draw.Src, nil)
+	return dst, scale
+}
@@ -0,0 +1,30 @@
+package preprocess
+
+import (
+	"image"
+	"testing"
+)
+
+func TestUpscale(t *testing.T) {
+	tests := []struct {
+		name          string
+		width, height int
+		maxPixels     int64
+		scale         int
+	}{
+		{"small", 300, 400, 50_000_000, 4},
+		{"to min dimension", 600, 800, 50_000_000, 2},
+		{"large enough", 1000, 1200, 50_000_000, 1},
+		{"clamped to pixel limit", 250, 2000, 5_000_000, 3},
+		{"at pixel limit", 250, 20000, 5_000_000, 1},
+	}
+	for _, tt := range tests {
+		img, scale := Upscale(image.NewGray(image.Rect(0, 0, tt.width, tt.height)), tt.maxPixels)
+		if scale != tt.scale {
+			t.Errorf("%s: scale = %d, want %d", tt.name, scale, tt.scale)
+		}
+		if size := img.Bounds().Size(); size != image.Pt(tt.width*scale, tt.height*scale) {
+			t.Errorf("%s: size = %v, want %dx scaled", tt.name, size, scale)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLocalConfinesPaths(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "outputs")
+	store, err := NewLocal(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	if err := store.Put(ctx, "../escaped.json", strings.NewReader("{}")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "escaped.json")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("file written outside the storage directory (stat err = %v)", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "escaped.json")); err != nil {
+		t.Errorf("file not written inside the storage directory: %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(root, "secret.txt"), []byte("secret"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := store.Get(ctx, "../secret.txt"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get outside the directory: err = %v, want ErrNotFound", err)
+	}
+	if err := store.Delete(ctx, "../secret.txt"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Delete outside the directory: err = %v, want ErrNotFound", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "secret.txt")); err != nil {
+		t.Errorf("file outside the directory was removed: %v", err)
+	}
+}
+
+func TestLocalRoundTrip(t *testing.T) {
+	store, err := NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	if err := store.Put(ctx, "ocr_1.json", strings.NewReader(`{"id":"1"}`)); err != nil {
+		t.Fatal(err)
+	}
+	rc, obj, err := store.Get(ctx, "ocr_1.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(rc)
+	rc.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"id":"1"}` || obj.Name != "ocr_1.json" || obj.Size != int64(len(data)) {
+		t.Errorf("got %q as %+v", data, obj)
+	}
+
+	if err := store.Delete(ctx, "ocr_1.json"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := store.Get(ctx, "ocr_1.json"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get after Delete: err = %v, want ErrNotFound", err)
+	}
+	if err := store.Delete(ctx, "ocr_1.json"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("second Delete: err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestLocalListSkipsHidden(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewLocal(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	for _, name := range []string{"ocr_1.json", ".dedup_abc.json", "batch_2.zip"} {
+		if err := store.Put(ctx, name, strings.NewReader("x")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, ".tmp-123"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "nested"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	objects, err := store.List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, obj := range objects {
+		names = append(names, obj.Name)
+	}
+	if got, want := strings.Join(names, ","), "batch_2.zip,ocr_1.json"; got != want {
+		t.Errorf("listed %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,127 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// S3Config configures an S3-compatible storage backend
+type S3Config struct {
+	Endpoint  string
+	Region    string
+	Bucket    string
+	Prefix    string
+	AccessKey string
+	SecretKey string
+	UseSSL    bool
+}
+
+// S3 stores objects in an S3-compatible bucket
+type S3 struct {
+	client *minio.Client
+	bucket string
+	prefix string
+}
+
+// NewS3 creates an S3 storage and verifies the bucket exists
+func NewS3(ctx context.Context, cfg S3Config) (*S3, error) {
+	client, err := minio.New(cfg.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(cfg.AccessKey, cfg.SecretKey, ""),
+		Secure: cfg.UseSSL,
+		Region: cfg.Region,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	exists, err := client.BucketExists(ctx, cfg.Bucket)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, minio.ErrorResponse{Code: "NoSuchBucket", BucketName: cfg.Bucket, Message: "bucket does not exist"}
+	}
+
+	prefix := strings.Trim(cfg.Prefix, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+
+	return &S3{client: client, bucket: cfg.Bucket, prefix: prefix}, nil
+}
+
+// Put uploads r as the named object
+func (s *S3) Put(ctx context.Context, name string, r io.Reader) error {
+	_, err := s.client.PutObject(ctx, s.bucket, s.key(name), r, -1,
+		minio.PutObjectOptions{ContentType: ContentType(name)})
+	return err
+}
+
+// Get opens the named object for reading
+func (s *S3) Get(ctx context.Context, name string) (io.ReadCloser, Object, error) {
+	obj, err := s.client.GetObject(ctx, s.bucket, s.key(name), minio.GetObjectOptions{})
+	if err != nil {
+		return nil, Object{}, s.mapError(err)
+	}
+
+	info, err := obj.Stat()
+	if err != nil {
+		obj.Close()
+		return nil, Object{}, s.mapError(err)
+	}
+
+	return obj, Object{
+		Name:    path.Base(info.Key),
+		Size:    info.Size,
+		ModTime: info.LastModified,
+	}, nil
+}
+
+// List returns all objects under the configured prefix
+func (s *S3) List(ctx context.Context) ([]Object, error) {
+	var objects []Object
+	for info := range s.client.ListObjects(ctx, s.bucket, minio.ListObjectsOptions{Prefix: s.prefix}) {
+		if info.Err != nil {
+			return nil, info.Err
+		}
+		// Nested prefixes, such as the uploads under the result prefix,
+		// are listed as keys ending in a slash
+		name := strings.TrimPrefix(info.Key, s.prefix)
+		if strings.HasPrefix(name, ".") || strings.HasSuffix(name, "/") {
+			continue
+		}
+		objects = append(objects, Object{
+			Name:    name,
+			Size:    info.Size,
+			ModTime: info.LastModified,
+		})
+	}
+
+	return objects, nil
+}
+
+// Delete removes the named object
+func (s *S3) Delete(ctx context.Context, name string) error {
+	return s.mapError(s.client.RemoveObject(ctx, s.bucket, s.key(name), minio.RemoveObjectOptions{}))
+}
+
+// key returns the bucket key for name
+func (s *S3) key(name string) string {
+	return s.prefix + path.Base(name)
+}
+
+// mapError converts S3 "not found" errors to ErrNotFound
+func (s *S3) mapError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+		return ErrNotFound
+	}
+	return err
+}
@@ -0,0 +1,32 @@
+package storage
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/minio/minio-go/v7"
+)
+
+func TestS3MapError(t *testing.T) {
+	s := &S3{bucket: "results"}
+
+	if err := s.mapError(nil); err != nil {
+		t.Errorf("nil error mapped to %v", err)
+	}
+	missing := minio.ErrorResponse{Code: "NoSuchKey", Key: "ocr_1.json", StatusCode: 404}
+	if err := s.mapError(missing); !errors.Is(err, ErrNotFound) {
+		t.Errorf("NoSuchKey mapped to %v, want ErrNotFound", err)
+	}
+
+	denied := minio.ErrorResponse{Code: "AccessDenied", StatusCode: 403}
+	if err := s.mapError(denied); errors.Is(err, ErrNotFound) || minio.ToErrorResponse(err).Code != "AccessDenied" {
+		t.Errorf("AccessDenied mapped to %v, want it unchanged", err)
+	}
+}
+
+func TestS3Key(t *testing.T) {
+	s := &S3{prefix: "ocr/uploads/"}
+	if got := s.key("../../ocr_1.json"); got != "ocr/uploads/ocr_1.json" {
+		t.Errorf("key = %q, want it confined to the prefix", got)
+	}
+}
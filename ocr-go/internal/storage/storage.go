@@ -0,0 +1,52 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"path/filepath"
+	"time"
+)
+
+// ErrNotFound is returned when an object does not exist
+var ErrNotFound = errors.New("object not found")
+
+// Object describes a stored result file
+type Object struct {
+	Name    string
+	Size    int64
+	ModTime time.Time
+}
+
+// Storage persists result files such as OCR JSON and annotated images
+type Storage interface {
+	// Put stores the contents of r under name, replacing any existing object
+	Put(ctx context.Context, name string, r io.Reader) error
+
+	// Get opens the named object for reading
+	Get(ctx context.Context, name string) (io.ReadCloser, Object, error)
+
+	// List returns all stored objects except hidden ones, whose names start
+	// with a dot
+	List(ctx context.Context) ([]Object, error)
+
+	// Delete removes the named object
+	Delete(ctx context.Context, name string) error
+}
+
+// ContentType returns the MIME type for an object name based on its extension
+func ContentType(name string) string {
+	switch filepath.Ext(name) {
+	case ".json":
+		return "application/json"
+	case ".png":
+		return "image/png"
+	case ".zip":
+		return "application/zip"
+	}
+	if t := mime.TypeByExtension(filepath.Ext(name)); t != "" {
+		return t
+	}
+	return "application/octet-stream"
+}
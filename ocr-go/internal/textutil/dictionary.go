@@ -0,0 +1,110 @@
+package textutil
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Dictionary is a word list used to correct misspelled words
+type Dictionary struct {
+	words    map[string]struct{}
+	byLength map[int][]string
+}
+
+// NewDictionary creates a dictionary from words, compared case-insensitively
+func NewDictionary(words []string) *Dictionary {
+	d := &Dictionary{
+		words:    make(map[string]struct{}, len(words)),
+		byLength: make(map[int][]string),
+	}
+	for _, word := range words {
+		word = strings.ToLower(strings.TrimSpace(word))
+		if word == "" {
+			continue
+		}
+		if _, ok := d.words[word]; ok {
+			continue
+		}
+		d.words[word] = struct{}{}
+		n := utf8.RuneCountInString(word)
+		d.byLength[n] = append(d.byLength[n], word)
+	}
+	return d
+}
+
+// LoadDictionary reads a word list with one word per line; blank lines and
+// lines starting with # are ignored
+func LoadDictionary(r io.Reader) (*Dictionary, error) {
+	var words []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		words = append(words, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return NewDictionary(words), nil
+}
+
+// Len returns the number of words
+func (d *Dictionary) Len() int {
+	return len(d.words)
+}
+
+// Contains reports whether word is in the dictionary
+func (d *Dictionary) Contains(word string) bool {
+	_, ok := d.words[strings.ToLower(word)]
+	return ok
+}
+
+// Nearest returns the dictionary word closest to word within maxDistance
+// edits. Ties are broken by the order the words were added.
+func (d *Dictionary) Nearest(word string, maxDistance int) (string, bool) {
+	word = strings.ToLower(word)
+	n := utf8.RuneCountInString(word)
+
+	best, bestDistance := "", maxDistance+1
+	for length := n - maxDistance; length <= n+maxDistance; length++ {
+		for _, candidate := range d.byLength[length] {
+			if distance := Levenshtein(word, candidate); distance < bestDistance {
+				best, bestDistance = candidate, distance
+			}
+		}
+	}
+	return best, best != ""
+}
+
+// Correct replaces token with its nearest dictionary word, keeping
+// surrounding punctuation and the capitalization of the first letter.
+// Known words, tokens containing digits and tokens without a close match
+// are returned unchanged.
+func (d *Dictionary) Correct(token string, maxDistance int) string {
+	start := strings.IndexFunc(token, unicode.IsLetter)
+	end := strings.LastIndexFunc(token, unicode.IsLetter)
+	if start < 0 || strings.IndexFunc(token, unicode.IsDigit) >= 0 {
+		return token
+	}
+	_, size := utf8.DecodeRuneInString(token[end:])
+	prefix, word, suffix := token[:start], token[start:end+size], token[end+size:]
+
+	if d.Contains(word) {
+		return token
+	}
+	corrected, ok := d.Nearest(word, maxDistance)
+	if !ok {
+		return token
+	}
+
+	if first, _ := utf8.DecodeRuneInString(word); unicode.IsUpper(first) {
+		r, size := utf8.DecodeRuneInString(corrected)
+		corrected = string(unicode.ToUpper(r)) + corrected[size:]
+	}
+	return prefix + corrected + suffix
+}
@@ -0,0 +1,32 @@
+package textutil
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDictionaryCorrect(t *testing.T) {
+	dict, err := LoadDictionary(strings.NewReader("# common words\nreceive\nthe\ninvoice\n\ntotal\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		token string
+		want  string
+	}{
+		{"recieve", "receive"},
+		{"Recieve,", "Receive,"},
+		{"(invoce)", "(invoice)"},
+		{"the", "the"},
+		{"T0tal", "T0tal"},
+		{"xyzzy", "xyzzy"},
+		{"...", "..."},
+	}
+
+	for _, tt := range tests {
+		if got := dict.Correct(tt.token, 2); got != tt.want {
+			t.Errorf("Correct(%q) = %q, want %q", tt.token, got, tt.want)
+		}
+	}
+}
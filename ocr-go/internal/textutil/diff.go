@@ -0,0 +1,121 @@
+package textutil
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// EditOp is the kind of a word-level edit
+type EditOp string
+
+// Edit operations, from the reference to the hypothesis
+const (
+	OpEqual      EditOp = "equal"
+	OpSubstitute EditOp = "substitute"
+	OpDelete     EditOp = "delete"
+	OpInsert     EditOp = "insert"
+)
+
+// Edit is one step of a word alignment. Indexes are word positions, -1 when
+// the word is absent on that side.
+type Edit struct {
+	Op         EditOp
+	RefIndex   int
+	HypIndex   int
+	Reference  string
+	Hypothesis string
+}
+
+// AlignWords returns a minimal sequence of edits turning the reference
+// words into the hypothesis words
+func AlignWords(ref, hyp []string) []Edit {
+	// dist[i][j] is the edit distance between ref[:i] and hyp[:j]
+	cols := len(hyp) + 1
+	dist := make([]int32, (len(ref)+1)*cols)
+	for i := 0; i <= len(ref); i++ {
+		dist[i*cols] = int32(i)
+	}
+	for j := 0; j <= len(hyp); j++ {
+		dist[j] = int32(j)
+	}
+	for i := 1; i <= len(ref); i++ {
+		for j := 1; j <= len(hyp); j++ {
+			cost := int32(1)
+			if ref[i-1] == hyp[j-1] {
+				cost = 0
+			}
+			dist[i*cols+j] = min(
+				dist[(i-1)*cols+j-1]+cost,
+				dist[(i-1)*cols+j]+1,
+				dist[i*cols+j-1]+1,
+			)
+		}
+	}
+
+	// Walk back from the end. Among equally short alignments, deletions
+	// and insertions are preferred over substitutions so that a dropped
+	// word does not shift every following word into a substitution.
+	var edits []Edit
+	i, j := len(ref), len(hyp)
+	for i > 0 || j > 0 {
+		switch {
+		case i > 0 && j > 0 && ref[i-1] == hyp[j-1] && dist[i*cols+j] == dist[(i-1)*cols+j-1]:
+			edits = append(edits, Edit{OpEqual, i - 1, j - 1, ref[i-1], hyp[j-1]})
+			i, j = i-1, j-1
+		case i > 0 && dist[i*cols+j] == dist[(i-1)*cols+j]+1:
+			edits = append(edits, Edit{OpDelete, i - 1, -1, ref[i-1], ""})
+			i--
+		case j > 0 && dist[i*cols+j] == dist[i*cols+j-1]+1:
+			edits = append(edits, Edit{OpInsert, -1, j - 1, "", hyp[j-1]})
+			j--
+		default:
+			edits = append(edits, Edit{OpSubstitute, i - 1, j - 1, ref[i-1], hyp[j-1]})
+			i, j = i-1, j-1
+		}
+	}
+
+	for l, r := 0, len(edits)-1; l < r; l, r = l+1, r-1 {
+		edits[l], edits[r] = edits[r], edits[l]
+	}
+	return edits
+}
+
+// CER returns the character error rate of hyp against ref: the character
+// edit distance divided by the reference length. Runs of whitespace count
+// as a single space.
+func CER(ref, hyp string) float64 {
+	ref = strings.Join(strings.Fields(ref), " ")
+	hyp = strings.Join(strings.Fields(hyp), " ")
+
+	n := utf8.RuneCountInString(ref)
+	if n == 0 {
+		return errorRateEmpty(hyp != "")
+	}
+	return float64(Levenshtein(ref, hyp)) / float64(n)
+}
+
+// WER returns the word error rate of hyp against ref: substitutions,
+// deletions and insertions divided by the number of reference words
+func WER(ref, hyp string) float64 {
+	refWords, hypWords := strings.Fields(ref), strings.Fields(hyp)
+	if len(refWords) == 0 {
+		return errorRateEmpty(len(hypWords) > 0)
+	}
+
+	errors := 0
+	for _, edit := range AlignWords(refWords, hypWords) {
+		if edit.Op != OpEqual {
+			errors++
+		}
+	}
+	return float64(errors) / float64(len(refWords))
+}
+
+// errorRateEmpty is the error rate against an empty reference: zero if the
+// hypothesis is empty too, otherwise total
+func errorRateEmpty(hypothesis bool) float64 {
+	if hypothesis {
+		return 1
+	}
+	return 0
+}
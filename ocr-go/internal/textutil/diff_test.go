@@ -0,0 +1,49 @@
+package textutil
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestAlignWords(t *testing.T) {
+	ref := strings.Fields("the quick brown fox")
+	hyp := strings.Fields("the qulck fox jumps")
+
+	var ops []EditOp
+	for _, edit := range AlignWords(ref, hyp) {
+		ops = append(ops, edit.Op)
+	}
+
+	want := []EditOp{OpEqual, OpSubstitute, OpDelete, OpEqual, OpInsert}
+	if len(ops) != len(want) {
+		t.Fatalf("ops = %v, want %v", ops, want)
+	}
+	for i := range want {
+		if ops[i] != want[i] {
+			t.Fatalf("ops = %v, want %v", ops, want)
+		}
+	}
+}
+
+func TestErrorRates(t *testing.T) {
+	tests := []struct {
+		ref, hyp string
+		cer, wer float64
+	}{
+		{"hello world", "hello world", 0, 0},
+		{"hello world", "hallo  world", 1.0 / 11, 0.5},
+		{"hello world", "", 1, 1},
+		{"", "", 0, 0},
+		{"", "noise", 1, 1},
+	}
+
+	for _, tt := range tests {
+		if got := CER(tt.ref, tt.hyp); math.Abs(got-tt.cer) > 1e-9 {
+			t.Errorf("CER(%q, %q) = %v, want %v", tt.ref, tt.hyp, got, tt.cer)
+		}
+		if got := WER(tt.ref, tt.hyp); math.Abs(got-tt.wer) > 1e-9 {
+			t.Errorf("WER(%q, %q) = %v, want %v", tt.ref, tt.hyp, got, tt.wer)
+		}
+	}
+}
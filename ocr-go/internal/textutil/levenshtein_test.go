@@ -0,0 +1,32 @@
+package textutil
+
+import "testing"
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want int
+	}{
+		{"equal", "invoice", "invoice", 0},
+		{"both empty", "", "", 0},
+		{"empty a", "", "abc", 3},
+		{"empty b", "abc", "", 3},
+		{"substitution", "invoice", "inv0ice", 1},
+		{"insertion", "total", "totals", 1},
+		{"deletion", "amount", "amont", 1},
+		{"transposition", "form", "from", 2},
+		{"classic", "kitten", "sitting", 3},
+		{"case sensitive", "Total", "total", 1},
+		{"runes not bytes", "año", "ano", 1},
+		{"symmetric", "sitting", "kitten", 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Levenshtein(tt.a, tt.b); got != tt.want {
+				t.Errorf("Levenshtein(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
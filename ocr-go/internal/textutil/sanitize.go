@@ -0,0 +1,24 @@
+package textutil
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Sanitize removes control characters other than newline and tab, such as
+// form feeds and NULs, and applies Unicode NFC normalization so composed
+// and decomposed accents compare equal
+func Sanitize(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if r == '\n' || r == '\t' {
+			return r
+		}
+		if unicode.IsControl(r) || r == unicode.ReplacementChar {
+			return -1
+		}
+		return r
+	}, s)
+	return norm.NFC.String(s)
+}
@@ -0,0 +1,25 @@
+package textutil
+
+import "testing"
+
+func TestSanitize(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"form feed", "page one\fpage two", "page onepage two"},
+		{"nul", "abc\x00def", "abcdef"},
+		{"keeps newline and tab", "a\tb\nc", "a\tb\nc"},
+		{"decomposed accent", "Jose\u0301", "Jos\u00e9"},
+		{"form feed and decomposed accent", "Cafe\u0301\f", "Caf\u00e9"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Sanitize(tt.in); got != tt.want {
+				t.Errorf("Sanitize(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}